@@ -1,38 +1,121 @@
 package cmd
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/user"
 	"path/filepath"
+	"runtime"
 	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/fileutil"
 	"github.com/baggiiiie/configlock/internal/locker"
-	"github.com/baggiiiie/configlock/internal/service"
-	kardianos "github.com/kardianos/service"
+	"github.com/baggiiiie/configlock/internal/pathutil"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 var addCmd = &cobra.Command{
 	Use:   "add <path>",
 	Short: "Add a file or directory to the lock list",
 	Long: `Add a file or directory to the lock list. If a directory is specified,
-all files in the directory (excluding .git/ and .jj/) will be added recursively.`,
-	Args: cobra.ExactArgs(1),
+all files in the directory (excluding .git/ and .jj/) will be added recursively.
+
+With --recursive=false a directory's own files are locked but its
+subdirectories are left alone, e.g. to lock the rc files at the top of
+~/.config without every application's folder. The directory itself is
+locked too, so no new entries can be created in it during lock hours.
+
+With --from-file, paths are read one per line from a file (or stdin with '-').
+Blank lines and lines starting with # are ignored. All paths are validated
+first and added in a single config change, so nothing is added if any path
+is invalid.
+
+With --temp the path is locked only for today, then the daemon removes it
+from the lock list at midnight, e.g. to lock a one-off project's config
+during a crunch. --temp=3h or --temp=18:00 sets another end.
+
+With --grace N (or grace_minutes in the config) a new path isn't locked
+until N minutes later, to finish the edit in progress when deciding to lock
+it. It can be removed without the typing challenge until then.
+
+Sensitive paths, such as ~/.ssh, launch agents, crontabs and sudoers, need
+an extra confirmation (or --yes), since the programs using them care about
+their exact permissions or need to change them. Where the read-only
+fallback is used, they only lose their write permission instead of being
+made readable by everyone.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if addFromFile != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
 	RunE: runAdd,
 }
 
+var (
+	addQuiet     bool
+	addNoRestart bool
+	addFromFile  string
+	addRecursive bool
+	addYes       bool
+	addTemp      string
+	addGrace     int
+)
+
 func init() {
 	rootCmd.AddCommand(addCmd)
+	addCmd.Flags().BoolVarP(&addQuiet, "quiet", "q", false, "Only print warnings and errors")
+	addCmd.Flags().BoolVar(&addNoRestart, "no-daemon-restart", false, "Don't restart the daemon (run 'configlock reload' afterwards)")
+	addCmd.Flags().StringVar(&addFromFile, "from-file", "", "Read paths to add from a file, one per line ('-' for stdin)")
+	addCmd.Flags().BoolVarP(&addYes, "yes", "y", false, "Add sensitive paths such as ~/.ssh without asking")
+	addCmd.Flags().StringVar(&addTemp, "temp", "", "Keep the path locked only until midnight, for a duration (e.g. 3h) or until a time of day (e.g. 18:00)")
+	addCmd.Flags().Lookup("temp").NoOptDefVal = "today"
+	addCmd.Flags().IntVar(&addGrace, "grace", 0, "Leave the path unlocked for this many minutes first (default: grace_minutes from the config)")
+	addCmd.Flags().BoolVar(&addRecursive, "recursive", true, "Lock files in subdirectories too (--recursive=false locks only a directory's own files)")
+}
+
+// readPathList reads paths one per line from a file, or stdin for "-".
+// Blank lines and # comments are skipped and a leading ~ is expanded.
+func readPathList(source string) ([]string, error) {
+	var reader io.Reader = os.Stdin
+	if source != "-" {
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open path list: %w", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	var paths []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		paths = append(paths, pathutil.ExpandHome(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read path list: %w", err)
+	}
+	return paths, nil
 }
 
 // resolveAndValidatePath resolves the given path to an absolute path,
 // handles symlinks (both working and broken), and validates the final path exists.
 // Returns the resolved absolute path or an error.
-func resolveAndValidatePath(path string) (string, error) {
+func resolveAndValidatePath(out io.Writer, path string) (string, error) {
 	// Resolve to absolute path
-	absPath, err := filepath.Abs(path)
+	absPath, err := pathutil.Abs(path)
 	if err != nil {
 		return "", fmt.Errorf("failed to resolve path: %w", err)
 	}
@@ -58,7 +141,7 @@ func resolveAndValidatePath(path string) (string, error) {
 			return "", fmt.Errorf("symlink %s -> %s is broken (target does not exist)", absPath, target)
 		}
 
-		fmt.Printf("Resolved symlink %s -> %s\n", absPath, realPath)
+		fmt.Fprintf(out, "Resolved symlink %s -> %s\n", absPath, realPath)
 		return realPath, nil
 	}
 
@@ -66,14 +149,173 @@ func resolveAndValidatePath(path string) (string, error) {
 	return absPath, nil
 }
 
-func runAdd(cmd *cobra.Command, args []string) error {
-	path := args[0]
+// warnExternalHardlinks warns about files that can also be reached through
+// hard links outside the added path. The immutable flag belongs to the inode,
+// so those links are locked too, but edits through them while unlocked are
+// not seen by the daemon's watchers.
+func warnExternalHardlinks(path string) {
+	links, err := fileutil.ExternalHardlinks(path)
+	if err != nil || len(links) == 0 {
+		return
+	}
 
-	resolvedPath, err := resolveAndValidatePath(path)
+	fmt.Printf("Warning: %d file(s) have hard links outside %s:\n", len(links), path)
+	for _, link := range links {
+		fmt.Printf("  - %s (%d link(s), %d outside)\n", link.Path, link.Links, link.External)
+	}
+	fmt.Println("  Locking applies to every link, but changes made through the other links are not detected.")
+}
+
+// parseTempUntil returns when a path added with --temp is removed from the
+// lock list: midnight for "today", after a duration such as "3h", or at a
+// time of day such as "18:00"
+func parseTempUntil(value string, now time.Time) (time.Time, error) {
+	if value == "today" {
+		year, month, day := now.Date()
+		return time.Date(year, month, day+1, 0, 0, 0, 0, now.Location()), nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("invalid --temp duration: %s", value)
+		}
+		return now.Add(d), nil
+	}
+	until, err := config.ParseUntil(value, now)
 	if err != nil {
-		return err
+		return time.Time{}, fmt.Errorf("invalid --temp value %q (%v): use today, a duration such as 3h, or a time such as 18:00", value, err)
+	}
+	return until, nil
+}
+
+// confirmSensitive lists the sensitive paths that locking paths would lock and
+// asks once whether to go ahead. Without a terminal to ask on, --yes is needed.
+func confirmSensitive(paths []string) error {
+	var found []fileutil.Sensitive
+	for _, path := range paths {
+		for _, s := range fileutil.SensitivePaths(path) {
+			if !slices.Contains(found, s) {
+				found = append(found, s)
+			}
+		}
+	}
+	if len(found) == 0 || addYes {
+		return nil
+	}
+
+	fmt.Println("Warning: locking these paths needs care:")
+	for _, s := range found {
+		fmt.Printf("  - %s: %s\n", s.Path, s.Reason)
+	}
+	fmt.Println("  Their modes are kept as they are: where the read-only fallback is used, they only lose write permission.")
+	if addFromFile == "-" || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return errors.New("sensitive paths need confirmation, run again with --yes to add them")
+	}
+	fmt.Print("Lock them anyway? (y/N): ")
+	response, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	response = strings.TrimSpace(strings.ToLower(response))
+	if response != "y" && response != "yes" {
+		return errors.New("nothing was added")
+	}
+	return nil
+}
+
+// warnLockAccess explains up front when path can't be locked by the user
+// running configlock, and so won't be enforced by their daemon: paths on a
+// read-only filesystem, and files owned by other users
+func warnLockAccess(cfg *config.Config, path string) {
+	if locker.ReadOnlyFilesystem(path) {
+		fmt.Printf("Warning: %s is on a read-only filesystem.\n", path)
+		fmt.Println("  It can't be changed while mounted read-only, but it can't be locked either:")
+		fmt.Println("  the daemon reports it as failing until the filesystem is mounted read-write.")
+		return
+	}
+
+	uid := os.Geteuid()
+	strategy := locker.StrategyFor(path)
+	// Root can lock anything, and bind mounts don't depend on file owners
+	if uid <= 0 || strategy == locker.StrategyBindRO {
+		return
+	}
+	owners, err := fileutil.ForeignOwners(path, uid)
+	if err != nil || len(owners) == 0 {
+		return
+	}
+
+	fmt.Printf("Warning: %s contains files owned by other users:\n", path)
+	for _, owner := range owners {
+		fmt.Printf("  - %s: %d file(s), e.g. %s\n", userName(owner.UID), owner.Files, owner.Example)
+	}
+	if strategy == locker.StrategyImmutable && cfg.Elevation == locker.ElevationSudo {
+		fmt.Println("  Only root can lock them. Lock tools run through sudo, so the daemon enforces them")
+		fmt.Println("  as long as it can use sudo without a password.")
+		return
+	}
+	fmt.Println("  Only their owner or root can lock them, so neither this command nor the daemon,")
+	switch {
+	case strategy == locker.StrategyImmutable:
+		fmt.Println("  which runs as you, can. Set elevation to sudo to lock them as root, or add them")
+		fmt.Println("  from the owner's account.")
+	case runtime.GOOS == "linux":
+		fmt.Println("  which runs as you, can. Set their lock method to bind-ro in lock_methods to lock")
+		fmt.Println("  them as root, or add them from the owner's account.")
+	default:
+		fmt.Println("  which runs as you, can. Add them from the owner's account instead.")
+	}
+}
+
+// userName returns the login name of uid, or the number if it is unknown
+func userName(uid uint32) string {
+	id := strconv.FormatUint(uint64(uid), 10)
+	if u, err := user.LookupId(id); err == nil {
+		return u.Username
+	}
+	return "uid " + id
+}
+
+func runAdd(cmd *cobra.Command, args []string) error {
+	out := outputWriter(addQuiet)
+
+	inputs := args
+	if addFromFile != "" {
+		var err error
+		if inputs, err = readPathList(addFromFile); err != nil {
+			return err
+		}
+		if len(inputs) == 0 {
+			return fmt.Errorf("no paths found in %s", addFromFile)
+		}
+	}
+
+	// Validate every path before changing anything
+	var resolvedPaths []string
+	var invalid []string
+	for _, input := range inputs {
+		resolvedPath, err := resolveAndValidatePath(out, input)
+		if err != nil {
+			invalid = append(invalid, err.Error())
+			continue
+		}
+		if !slices.Contains(resolvedPaths, resolvedPath) {
+			resolvedPaths = append(resolvedPaths, resolvedPath)
+		}
+	}
+	if len(invalid) == 1 && len(inputs) == 1 {
+		return errors.New(invalid[0])
+	}
+	if len(invalid) > 0 {
+		for _, msg := range invalid {
+			fmt.Printf("  - %s\n", msg)
+		}
+		return fmt.Errorf("%d invalid path(s), nothing was added", len(invalid))
+	}
+
+	var tempUntil time.Time
+	if addTemp != "" {
+		var err error
+		if tempUntil, err = parseTempUntil(addTemp, time.Now()); err != nil {
+			return err
+		}
 	}
-	info, _ := os.Stat(resolvedPath)
 
 	// Load config
 	cfg, err := config.Load()
@@ -81,56 +323,132 @@ func runAdd(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	// Check if path is already in lock list
-	if slices.Contains(cfg.LockedPaths, resolvedPath) {
-		fmt.Printf("Path is already in lock list: %s\n", resolvedPath)
+	// Skip paths already in lock list
+	var newPaths []string
+	for _, resolvedPath := range resolvedPaths {
+		if slices.Contains(cfg.LockedPaths, resolvedPath) {
+			fmt.Fprintf(out, "Path is already in lock list: %s\n", resolvedPath)
+			continue
+		}
+		newPaths = append(newPaths, resolvedPath)
+	}
+	if len(newPaths) == 0 {
 		return nil
 	}
+	if err := confirmSensitive(newPaths); err != nil {
+		return err
+	}
 
-	// Add path to config (just the directory or file path, not individual files)
-	cfg.AddPath(resolvedPath)
+	graceMinutes := cfg.GraceMinutes
+	if cmd.Flags().Changed("grace") {
+		graceMinutes = addGrace
+	}
+	if graceMinutes < 0 {
+		return fmt.Errorf("invalid grace period: %d minutes", graceMinutes)
+	}
+	graceUntil := time.Now().Add(time.Duration(graceMinutes) * time.Minute)
 
-	// Save config
-	if err := cfg.Save(); err != nil {
+	// Add paths to config (just the directory or file path, not individual files)
+	cfg, err = config.Update(func(latest *config.Config) error {
+		latest.CleanExpiredGrace(time.Now())
+		for _, resolvedPath := range newPaths {
+			latest.AddPath(resolvedPath)
+			if info, err := os.Stat(resolvedPath); err == nil && info.IsDir() && !addRecursive {
+				latest.SetShallow(resolvedPath, true)
+			}
+			if !tempUntil.IsZero() {
+				latest.SetTempPath(resolvedPath, tempUntil)
+			}
+			if graceMinutes > 0 {
+				latest.SetGrace(resolvedPath, graceUntil)
+			}
+		}
+		return nil
+	})
+	if err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 
-	if info.IsDir() {
-		fmt.Printf("✓ Added directory to lock list: %s\n", resolvedPath)
-	} else {
-		fmt.Printf("✓ Added file to lock list: %s\n", resolvedPath)
+	for _, resolvedPath := range newPaths {
+		if info, err := os.Stat(resolvedPath); err == nil && info.IsDir() && !addRecursive {
+			fmt.Fprintf(out, "✓ Added directory to lock list (top-level files only): %s\n", resolvedPath)
+		} else if err == nil && info.IsDir() {
+			fmt.Fprintf(out, "✓ Added directory to lock list: %s\n", resolvedPath)
+		} else {
+			fmt.Fprintf(out, "✓ Added file to lock list: %s\n", resolvedPath)
+		}
+		warnExternalHardlinks(resolvedPath)
+		warnLockAccess(cfg, resolvedPath)
+	}
+	if !tempUntil.IsZero() {
+		fmt.Fprintf(out, "  Locked temporarily: removed from the lock list at %s\n", tempUntil.Format("Mon 15:04"))
+	}
+	if graceMinutes > 0 {
+		fmt.Fprintf(out, "  Grace period: not locked before %s, to let you finish your edit\n", graceUntil.Format("15:04"))
 	}
 
-	// Apply locks immediately if within lock hours
-	if cfg.IsWithinWorkHours() {
-		fmt.Println("Applying locks (within lock hours)...")
-		if err := locker.Lock(resolvedPath); err != nil {
-			fmt.Printf("Warning: failed to lock %s: %v\n", resolvedPath, err)
-		} else {
-			fmt.Println("✓ Locks applied")
-		}
-	} else {
-		fmt.Println("Note: Outside lock hours. Locks will be applied during lock hours.")
-	}
-
-	// Restart daemon if running to pick up new path
-	svc, err := service.New()
-	if err == nil {
-		status, err := svc.Status()
-		if err == nil && status == kardianos.StatusRunning {
-			fmt.Println("\nRestarting daemon to apply configuration changes...")
-			if err := svc.Restart(); err != nil {
-				// Restart might not be supported, try stop+start
-				if err := svc.Stop(); err == nil {
-					if err := svc.Start(); err != nil {
-						fmt.Printf("Warning: failed to restart daemon: %v\n", err)
-						return nil
-					}
-				}
+	// Apply locks immediately to paths whose schedule is active, in a single pass
+	var activePaths []string
+	now := time.Now()
+	for _, resolvedPath := range newPaths {
+		if cfg.IsPathActive(resolvedPath, now) {
+			activePaths = append(activePaths, resolvedPath)
+		}
+	}
+	if len(activePaths) > 0 {
+		confirmElevation(cfg, activePaths[0])
+		fmt.Fprintln(out, "Applying locks (within lock hours)...")
+		var progress locker.ProgressFunc
+		if !addQuiet {
+			progress = newProgressPrinter("Locking")
+		}
+
+		combined := &locker.Report{}
+		lockedAny := false
+		for _, resolvedPath := range activePaths {
+			report, err := locker.LockWithProgress(cmd.Context(), resolvedPath, progress)
+			if err != nil {
+				fmt.Printf("Warning: failed to lock %s: %v\n", resolvedPath, err)
+				continue
 			}
-			fmt.Println("✓ Daemon restarted")
+			lockedAny = true
+			combined.Total += report.Total
+			combined.Locked += report.Locked
+			combined.Skipped += report.Skipped
+			combined.Excluded += report.Excluded
+			combined.Failed = append(combined.Failed, report.Failed...)
+			combined.NewlyExcluded = append(combined.NewlyExcluded, report.NewlyExcluded...)
+			combined.Aborted = combined.Aborted || report.Aborted
 		}
+		if lockedAny {
+			printLockReport(out, combined)
+			saveExcluded(combined.NewlyExcluded)
+		}
+	} else if graceMinutes == 0 || !cfg.IsWithinWorkHours(now) {
+		fmt.Fprintln(out, "Note: Outside lock hours. Locks will be applied during lock hours.")
+	}
+
+	// Restart daemon if running to pick up configuration changes
+	if !addNoRestart {
+		restartDaemonIfRunning(out)
 	}
 
 	return nil
 }
+
+// saveExcluded adds files excluded after failing to lock to excluded_files
+func saveExcluded(failures []locker.LockError) {
+	if len(failures) == 0 {
+		return
+	}
+	files := make([]string, len(failures))
+	for i, failure := range failures {
+		files[i] = failure.Path
+	}
+	if _, err := config.Update(func(cfg *config.Config) error {
+		cfg.AddExcludedFiles(files)
+		return nil
+	}); err != nil {
+		fmt.Printf("Warning: failed to save excluded files: %v\n", err)
+	}
+}
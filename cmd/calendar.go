@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/calendar"
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/daemon"
+	"github.com/spf13/cobra"
+)
+
+var calendarDays int
+
+var calendarCmd = &cobra.Command{
+	Use:   "calendar",
+	Short: "Show the lock schedule or use calendar focus events as lock windows",
+	Long: `Without a subcommand, show the coming days as a grid with the locked
+half hours shaded, covering lock hours and windows, named schedules and
+calendar focus events, so you can check the configuration at a glance.
+
+The login and sync subcommands read focus events from a Google or Microsoft
+calendar (read-only) and lock during them, in addition to the configured lock
+hours. Configure the "calendar" section in config.json with the provider and
+the OAuth client ID of your own app registration, then run 'configlock
+calendar login'. Events whose title or category contains the keyword (default
+"Focus") are treated as lock windows. The daemon refreshes them periodically.`,
+	Args: cobra.NoArgs,
+	RunE: runCalendar,
+}
+
+var calendarLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Authorize read-only access to your calendar",
+	Args:  cobra.NoArgs,
+	RunE:  runCalendarLogin,
+}
+
+var calendarSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Fetch focus events now and show upcoming ones",
+	Args:  cobra.NoArgs,
+	RunE:  runCalendarSync,
+}
+
+func init() {
+	calendarCmd.Flags().IntVar(&calendarDays, "days", 7, "number of days to show (1-30)")
+	rootCmd.AddCommand(calendarCmd)
+	calendarCmd.AddCommand(calendarLoginCmd)
+	calendarCmd.AddCommand(calendarSyncCmd)
+}
+
+// slotsPerHour is the number of grid cells per hour
+const slotsPerHour = 2
+
+func runCalendar(cmd *cobra.Command, args []string) error {
+	if calendarDays < 1 || calendarDays > 30 {
+		return fmt.Errorf("--days must be between 1 and 30")
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	now := time.Now()
+	fmt.Printf("Lock schedule for the next %d day(s) (█ locked, ▒ partly locked, · unlocked)\n\n", calendarDays)
+
+	// Hour labels every 3 hours, aligned with the cells
+	header := strings.Repeat(" ", 13)
+	for hour := 0; hour < 24; hour += 3 {
+		header += fmt.Sprintf("%-*d", 3*slotsPerHour, hour)
+	}
+	fmt.Println(strings.TrimRight(header, " "))
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for i := range calendarDays {
+		day := today.AddDate(0, 0, i)
+		row, locked := calendarRow(cfg, day)
+		marker := " "
+		if i == 0 {
+			marker = "*"
+		}
+		fmt.Printf("%s%s  %s  %s\n", marker, day.Format("Mon Jan 02"), row, formatDuration(locked))
+	}
+	fmt.Println()
+	fmt.Println("* today; times are local")
+	return nil
+}
+
+// calendarRow renders one day as grid cells and returns how long it is
+// locked. Lock state is sampled every minute, so partly locked cells show
+// windows that don't start or end on a cell boundary.
+func calendarRow(cfg *config.Config, day time.Time) (string, time.Duration) {
+	var row strings.Builder
+	var locked time.Duration
+	end := day.AddDate(0, 0, 1)
+	slot := time.Hour / slotsPerHour
+	for start := day; start.Before(end); start = start.Add(slot) {
+		minutes, total := 0, 0
+		for t := start; t.Before(start.Add(slot)) && t.Before(end); t = t.Add(time.Minute) {
+			total++
+			if cfg.IsWithinWorkHours(t) {
+				minutes++
+			}
+		}
+		locked += time.Duration(minutes) * time.Minute
+		switch {
+		case minutes == 0:
+			row.WriteString("·")
+		case minutes == total:
+			row.WriteString("█")
+		default:
+			row.WriteString("▒")
+		}
+	}
+	return row.String(), locked
+}
+
+// loadCalendarSettings loads the config and returns its calendar settings
+func loadCalendarSettings() (*config.CalendarSettings, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg.Calendar == nil {
+		return nil, fmt.Errorf("no calendar configured: add a \"calendar\" section with provider and client_id to %s", config.GetConfigPath())
+	}
+	return cfg.Calendar, nil
+}
+
+func runCalendarLogin(cmd *cobra.Command, args []string) error {
+	settings, err := loadCalendarSettings()
+	if err != nil {
+		return err
+	}
+
+	code, err := calendar.StartLogin(settings)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("To authorize configlock, open %s and enter the code: %s\n", code.URL(), code.UserCode)
+	fmt.Println("Waiting for authorization...")
+
+	if err := calendar.FinishLogin(settings, code); err != nil {
+		return err
+	}
+	fmt.Println("✓ Calendar access authorized")
+
+	return runCalendarSync(cmd, args)
+}
+
+func runCalendarSync(cmd *cobra.Command, args []string) error {
+	settings, err := loadCalendarSettings()
+	if err != nil {
+		return err
+	}
+
+	blocks, err := calendar.Sync(settings)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Found %d focus event(s) in the next 7 days\n", len(blocks))
+	for _, block := range blocks {
+		fmt.Printf("  %s - %s  %s\n", block.Start.Local().Format("Mon Jan 2 15:04"), block.End.Local().Format("15:04"), block.Title)
+	}
+
+	// Let a running daemon pick up the new focus blocks right away
+	if err := daemon.SignalReload(); err == nil {
+		fmt.Println("✓ Daemon reloading focus events")
+	}
+	return nil
+}
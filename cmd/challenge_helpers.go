@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/challenge"
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/stats"
+)
+
+// recordChallenges records every challenge attempt of the running command
+// in the stats store, so 'configlock report' shows how often unlocking was
+// attempted and abandoned
+func recordChallenges(command string) {
+	challenge.SetRecorder(func(a challenge.Attempt) {
+		err := stats.Record(stats.Event{
+			Name:    stats.EventChallenge,
+			Time:    time.Now(),
+			Command: command,
+			Kind:    a.Kind,
+			Outcome: a.Outcome,
+			Retries: a.Retries,
+			Seconds: int(a.Duration.Seconds()),
+		})
+		if err != nil {
+			fmt.Printf("⚠ Failed to record challenge: %v\n", err)
+		}
+	})
+}
+
+// requireChallenge runs the typing challenge, scaled during lock hours to the
+// lock time that remains: a short line just before the locks lift, several
+// paragraphs at the start of the day
+func requireChallenge(cfg *config.Config) error {
+	now := time.Now()
+	if !cfg.IsWithinWorkHours(now) {
+		return challenge.Require("challenge failed")
+	}
+	return challenge.RequireScaled("challenge failed", cfg.LockRemaining(now), cfg.ChallengeCurve)
+}
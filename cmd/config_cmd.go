@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the configlock configuration file",
+}
+
+var configUndoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Restore the previous version of config.json",
+	Long: `Restore the previous version of config.json from the backups kept on
+every change (config_backups, default 10). Running it repeatedly steps further
+back. During lock hours this requires completing the typing challenge, and
+a version that locks less than the current one (fewer paths, shorter lock
+hours, lower strictness, no stop passphrase, more trusted processes) can't
+be restored.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigUndo,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check config.json for mistakes",
+	Long: `Check that config.json parses and is valid, and warn about entries that
+likely don't do what was meant: locked paths inside other locked paths, paths
+that look like globs (locked paths are not expanded), path_schedules and
+lock_methods entries that match no locked path, and temporary unlocks whose
+--only patterns match nothing or cover a whole locked path.
+
+The daemon logs the same warnings when it starts.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigValidate,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configUndoCmd)
+	configCmd.AddCommand(configValidateCmd)
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	warnings := cfg.Lint()
+	for _, warning := range warnings {
+		fmt.Printf("⚠ %s\n", warning)
+	}
+	if len(warnings) > 0 {
+		fmt.Printf("\n%s is valid, with %d warning(s)\n", config.GetConfigPath(), len(warnings))
+		return nil
+	}
+	fmt.Printf("✓ %s is valid\n", config.GetConfigPath())
+	return nil
+}
+
+func runConfigUndo(cmd *cobra.Command, args []string) error {
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	backups, err := config.ListBackups()
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		fmt.Println("No previous config version available.")
+		return nil
+	}
+
+	// During lock hours the previous version may not lock less than the
+	// current one, and restoring it requires the typing challenge
+	if err := cfg.CheckEscapeHatch(config.HatchEditTime); err != nil {
+		return err
+	}
+	if now := time.Now(); cfg.IsWithinWorkHours(now) {
+		previous, err := config.LoadBackup(backups[0])
+		if err != nil {
+			return err
+		}
+		if narrowing := cfg.NarrowedBy(previous, now); !narrowing.Empty() {
+			return fmt.Errorf("the previous config version %s, undo it outside lock hours", narrowing)
+		}
+		if err := requireChallenge(cfg); err != nil {
+			return err
+		}
+	}
+
+	restored, err := config.Undo()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Restored previous config version (%d older version(s) remaining)\n", len(backups)-1)
+	fmt.Printf("Lock hours: %s - %s on days: %s\n", restored.StartTime, restored.EndTime, config.FormatDays(restored.LockDays))
+	fmt.Printf("Locked paths: %d\n", len(restored.LockedPaths))
+
+	// Restart daemon if running to pick up configuration changes
+	restartDaemonIfRunning(os.Stdout)
+
+	return nil
+}
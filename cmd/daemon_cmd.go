@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/baggiiiie/configlock/internal/daemon"
+	"github.com/baggiiiie/configlock/internal/logger"
 	"github.com/spf13/cobra"
 )
 
@@ -21,7 +22,7 @@ func init() {
 
 func runDaemon(cmd *cobra.Command, args []string) error {
 	// Create and start daemon
-	d, err := daemon.New()
+	d, err := daemon.New(GetVersion(), logger.GetLogger())
 	if err != nil {
 		return fmt.Errorf("failed to create daemon: %w", err)
 	}
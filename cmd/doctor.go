@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/locker"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that locking works for every locked path",
+	Long: `Lock and unlock a scratch file on the filesystem of every locked path and
+report which lock method is used, whether it silently falls back to read-only
+permissions, and known platform limitations such as Windows drives under WSL.`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	platform := runtime.GOOS
+	if locker.IsWSL() {
+		platform += " (WSL)"
+	}
+	fmt.Printf("Platform: %s\n\n", platform)
+
+	problems := 0
+	for _, path := range cfg.LockedPaths {
+		if _, err := os.Stat(path); err != nil {
+			fmt.Printf("⚠ %s: does not exist\n", path)
+			problems++
+			continue
+		}
+
+		result, err := locker.Probe(path)
+		switch {
+		case err != nil:
+			fmt.Printf("⚠ %s: %s on %s does not work: %v\n", path, result.Backend, result.FSType, err)
+			problems++
+		case result.Fallback():
+			fmt.Printf("⚠ %s: %s falls back to %s on %s (needs root, see the elevation setting)\n", path, result.Backend, result.Effective, result.FSType)
+			problems++
+		default:
+			fmt.Printf("✓ %s: %s on %s\n", path, result.Backend, result.FSType)
+		}
+
+		if locker.IsWindowsDrive(path) {
+			fmt.Println("  ⚠ This is a Windows drive mounted into WSL. Linux immutable flags can't be set")
+			fmt.Println("    here, so it is only made read-only, and Windows programs ignore that.")
+			fmt.Println("    Keep locked files inside the WSL filesystem (e.g. under ~) instead.")
+			problems++
+		}
+	}
+
+	fmt.Println()
+	if problems > 0 {
+		fmt.Printf("⚠ Found %d problem(s)\n", problems)
+	} else {
+		fmt.Println("✓ Locking works for all paths")
+	}
+	return nil
+}
@@ -6,9 +6,13 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/baggiiiie/configlock/internal/challenge"
 	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/logger"
 	"github.com/baggiiiie/configlock/internal/service"
+	"github.com/baggiiiie/configlock/internal/stats"
 	kardianos "github.com/kardianos/service"
 	"github.com/spf13/cobra"
 )
@@ -19,7 +23,11 @@ var editTimeCmd = &cobra.Command{
 	Long: `Edit the lock hours configuration for ConfigLock.
 
 This allows you to change the existing time settings. If the daemon is running, it will be
-automatically restarted to apply the changes immediately.`,
+automatically restarted to apply the changes immediately.
+
+During lock hours, new settings that end the current lock period earlier
+require the typing challenge. Every change is recorded in the log and the
+stats shown by 'configlock report'.`,
 	RunE: runEditTime,
 }
 
@@ -27,6 +35,25 @@ func init() {
 	rootCmd.AddCommand(editTimeCmd)
 }
 
+// describeLockHours summarizes the settings 'edit time' changes
+func describeLockHours(cfg *config.Config) string {
+	return fmt.Sprintf("%s - %s %s, temp unlocks %d min", cfg.StartTime, cfg.EndTime, config.FormatDays(cfg.LockDays), cfg.TempDuration)
+}
+
+// recordLockHoursChange logs a change of the lock hours and records it in
+// the stats store, so weakened settings show up in 'configlock report'
+func recordLockHoursChange(oldHours, newHours string) {
+	logger.GetLogger().Infof("Lock hours changed: %s -> %s", oldHours, newHours)
+	err := stats.Record(stats.Event{
+		Name:   stats.EventLockHoursChange,
+		Time:   time.Now(),
+		Reason: fmt.Sprintf("%s -> %s", oldHours, newHours),
+	})
+	if err != nil {
+		fmt.Printf("⚠ Failed to record the change: %v\n", err)
+	}
+}
+
 func runEditTime(cmd *cobra.Command, args []string) error {
 	// Load existing config
 	cfg, err := config.Load()
@@ -34,10 +61,24 @@ func runEditTime(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	if err := cfg.CheckEscapeHatch(config.HatchEditTime); err != nil {
+		return err
+	}
+
+	// What the current lock period has left, to tell whether the new
+	// settings shorten it
+	now := time.Now()
+	remaining := cfg.LockRemaining(now)
+	oldHours := describeLockHours(cfg)
+
 	// Show current configuration
 	fmt.Println("Current lock hours configuration:")
 	fmt.Printf("  Time range: %s - %s\n", cfg.StartTime, cfg.EndTime)
 	fmt.Printf("  Lock days: %s\n", config.FormatDays(cfg.LockDays))
+	if len(cfg.Windows) > 0 {
+		fmt.Printf("  Windows: %s\n", strings.Join(cfg.Windows, "; "))
+		fmt.Println("⚠ Lock windows are set in the config and take precedence over the time range and days below.")
+	}
 	fmt.Println()
 
 	// Prompt for new configuration
@@ -45,7 +86,7 @@ func runEditTime(cmd *cobra.Command, args []string) error {
 
 	fmt.Println("\nNew lock hours configuration:")
 	fmt.Println("  - Time range: Enter a time range like 0800-1700 or 8-17.")
-	fmt.Println("  - Day range: Enter a day range like 1-5 (Mon-Fri) or comma-separated days like 1,2,3,4,5.")
+	fmt.Println("  - Day range: Enter a day range like 1-5 or mon-fri, or comma-separated days like 1,3,5 or sat,sun.")
 
 	// Get time range with retry
 	fmt.Print("\nEnter lock time range (press Enter to keep current): ")
@@ -94,12 +135,30 @@ func runEditTime(cmd *cobra.Command, args []string) error {
 		fmt.Printf("✓ Updated temporary unlock duration to %d minutes\n", duration)
 	}
 
-	// Save updated config
-	if err := cfg.Save(); err != nil {
+	if shortened := remaining - cfg.LockRemaining(now); shortened > 0 {
+		fmt.Printf("\n⚠ The new lock hours end the current lock period %s early.\n", formatDuration(shortened))
+		// Scaled to the lock time the current settings have left
+		if err := challenge.RequireScaled("challenge failed", remaining, cfg.ChallengeCurve); err != nil {
+			return err
+		}
+	}
+
+	// Apply the new settings to the config on disk, so changes the daemon
+	// made while the prompts were open aren't overwritten
+	saved, err := config.Update(func(latest *config.Config) error {
+		latest.StartTime, latest.EndTime = cfg.StartTime, cfg.EndTime
+		latest.LockDays = cfg.LockDays
+		latest.TempDuration = cfg.TempDuration
+		return nil
+	})
+	if err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 
 	fmt.Println("\n✓ Configuration updated successfully!")
+	if newHours := describeLockHours(saved); newHours != oldHours {
+		recordLockHoursChange(oldHours, newHours)
+	}
 
 	// Automatically restart daemon if it's running
 	svc, err := service.New()
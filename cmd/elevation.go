@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/locker"
+	"golang.org/x/term"
+)
+
+// confirmElevation asks once, on an interactive terminal, whether lock tools
+// should run through sudo when setting immutable flags on path needs root.
+// The answer is saved in the config so neither the CLI nor the daemon
+// silently falls back to chmod afterwards.
+func confirmElevation(cfg *config.Config, path string) {
+	if cfg.Elevation != "" || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return
+	}
+	if !locker.NeedsElevation(path) {
+		return
+	}
+
+	fmt.Printf("⚠ Setting immutable flags on %s requires root.\n", path)
+	fmt.Println("Without it, locked files are only made read-only, which you can undo yourself.")
+	fmt.Print("Run lock operations through sudo? (y/N): ")
+	response, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	response = strings.TrimSpace(strings.ToLower(response))
+
+	elevation := locker.ElevationNone
+	if response == "y" || response == "yes" {
+		// Cache credentials now so the locks below don't prompt per file
+		sudo := exec.Command("sudo", "-v")
+		sudo.Stdin, sudo.Stdout, sudo.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := sudo.Run(); err != nil {
+			fmt.Printf("Warning: sudo failed, falling back to read-only permissions: %v\n", err)
+		} else {
+			elevation = locker.ElevationSudo
+		}
+	}
+
+	// Saving reloads the config, which hands the choice to the locker
+	if _, err := config.Update(func(latest *config.Config) error {
+		latest.Elevation = elevation
+		return nil
+	}); err != nil {
+		fmt.Printf("Warning: failed to save elevation choice: %v\n", err)
+		return
+	}
+	cfg.Elevation = elevation
+	if elevation == locker.ElevationSudo {
+		fmt.Println("✓ Lock operations will use sudo. For the daemon, allow chattr/chflags without a password or set SUDO_ASKPASS.")
+	}
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/locker"
+	"github.com/baggiiiie/configlock/internal/pathutil"
+	"github.com/spf13/cobra"
+)
+
+var findCmd = &cobra.Command{
+	Use:   "find <pattern>",
+	Short: "Find which locked paths cover matching files",
+	Long: `Search the locked paths and the files inside locked directories for a
+pattern and show each matching file with its lock state and the locked path
+that covers it.
+
+The pattern is a glob matched against file names (e.g. '*.toml'), or else a
+case-insensitive substring of the path. An existing path is checked directly,
+which also tells whether a file inside a locked directory was skipped by the
+size, binary or symlink settings.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runFind,
+}
+
+func init() {
+	rootCmd.AddCommand(findCmd)
+}
+
+// findMatch is a file covered by a locked path
+type findMatch struct {
+	file       string
+	lockedPath string
+	state      string
+}
+
+func runFind(cmd *cobra.Command, args []string) error {
+	pattern := args[0]
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if absPath, err := filepath.Abs(pattern); err == nil {
+		if _, err := os.Stat(absPath); err == nil {
+			return findPath(cmd, cfg, absPath)
+		}
+	}
+
+	var matches []findMatch
+	for _, lockedPath := range cfg.LockedPaths {
+		files, err := locker.CoveredFiles(cmd.Context(), lockedPath)
+		if err != nil {
+			fmt.Printf("⚠ %s: %v\n", lockedPath, err)
+			continue
+		}
+		root, err := filepath.EvalSymlinks(lockedPath)
+		if err != nil {
+			root = lockedPath
+		}
+		for _, file := range files {
+			// Report files under the configured path, not the resolved one
+			if rel, err := filepath.Rel(root, file); err == nil && !strings.HasPrefix(rel, "..") {
+				file = filepath.Join(lockedPath, rel)
+			}
+			if matchesPattern(pattern, file) {
+				matches = append(matches, findMatch{file: file, lockedPath: lockedPath, state: fileState(cfg, lockedPath, file)})
+			}
+		}
+	}
+
+	if len(matches) == 0 {
+		fmt.Printf("No locked files match %q.\n", pattern)
+		return nil
+	}
+	for _, m := range matches {
+		printFindMatch(m)
+	}
+	return nil
+}
+
+// matchesPattern reports whether file matches a glob on its name, or
+// contains pattern case-insensitively
+func matchesPattern(pattern, file string) bool {
+	if strings.ContainsAny(pattern, "*?[") {
+		ok, _ := filepath.Match(pattern, filepath.Base(file))
+		return ok
+	}
+	return strings.Contains(strings.ToLower(file), strings.ToLower(pattern))
+}
+
+// findPath reports whether an existing path is covered by a locked path
+func findPath(cmd *cobra.Command, cfg *config.Config, absPath string) error {
+	for _, lockedPath := range cfg.LockedPaths {
+		if !pathutil.Within(absPath, lockedPath) {
+			continue
+		}
+		if absPath == lockedPath {
+			printFindMatch(findMatch{file: absPath, lockedPath: lockedPath, state: fileState(cfg, lockedPath, absPath)})
+			return nil
+		}
+
+		files, err := locker.CoveredFiles(cmd.Context(), lockedPath)
+		if err != nil {
+			return err
+		}
+		realPath, err := filepath.EvalSymlinks(absPath)
+		if err != nil {
+			realPath = absPath
+		}
+		if info, err := os.Stat(absPath); err == nil && info.IsDir() {
+			count := 0
+			for _, file := range files {
+				if strings.HasPrefix(file, realPath+string(filepath.Separator)) {
+					count++
+				}
+			}
+			fmt.Printf("%s is inside %s, which covers %d file(s) below it.\n", absPath, lockedPath, count)
+			fmt.Println("Use 'configlock list --tree' to see their lock state.")
+			return nil
+		}
+		if !slices.Contains(files, realPath) {
+			fmt.Printf("%s is inside %s but not covered: it is skipped by the size, binary or symlink settings, or ignored (.git, .jj).\n", absPath, lockedPath)
+			return nil
+		}
+		printFindMatch(findMatch{file: absPath, lockedPath: lockedPath, state: fileState(cfg, lockedPath, absPath)})
+		return nil
+	}
+
+	fmt.Printf("%s is not covered by any locked path.\n", absPath)
+	return nil
+}
+
+// printFindMatch prints a matching file, its state and what covers it
+func printFindMatch(m findMatch) {
+	if m.file == m.lockedPath {
+		fmt.Printf("%s  %s\n", m.file, m.state)
+		return
+	}
+	fmt.Printf("%s  %s  (via %s)\n", m.file, m.state, m.lockedPath)
+}
@@ -6,27 +6,131 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/baggiiiie/configlock/internal/challenge"
 	"github.com/baggiiiie/configlock/internal/config"
 	"github.com/baggiiiie/configlock/internal/locker"
+	"github.com/baggiiiie/configlock/internal/logger"
 	"github.com/baggiiiie/configlock/internal/service"
+	kardianos "github.com/kardianos/service"
 	"github.com/spf13/cobra"
 )
 
+var (
+	initStart        string
+	initEnd          string
+	initDays         string
+	initTempDuration int
+	initYes          bool
+	initRepair       bool
+)
+
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize configlock and install the daemon",
 	Long: `Initialize configlock by creating the configuration file,
-prompting for lock hours, and installing the daemon as a system service.`,
+prompting for lock hours, and installing the daemon as a system service.
+
+Every prompt can be answered with a flag for use in provisioning scripts:
+
+  configlock init --start 08:00 --end 17:00 --days 1-5 --temp-duration 5 --yes
+
+With --yes, values not given as flags use their defaults and an existing
+config is overwritten without asking. Re-initializing keeps the locked paths,
+strictness, stop passphrase, lock windows and schedules of the existing
+config. During lock hours it still requires the typing challenge, and
+settings that end the current lock period earlier are refused.
+
+With --repair, nothing is prompted and the schedule and locked paths are left
+alone: missing pieces are re-created instead (service unit, log and data
+directories, config keys missing or invalid in the config file). This is handy
+after OS upgrades that wipe launchd/systemd files.`,
 	RunE: runInit,
 }
 
 func init() {
 	rootCmd.AddCommand(initCmd)
+	initCmd.Flags().StringVar(&initStart, "start", "", "Lock start time (e.g. 08:00 or 0800)")
+	initCmd.Flags().StringVar(&initEnd, "end", "", "Lock end time (e.g. 17:00 or 1700)")
+	initCmd.Flags().StringVar(&initDays, "days", "", "Lock days (e.g. 1-5, mon-fri or sat,sun)")
+	initCmd.Flags().IntVar(&initTempDuration, "temp-duration", 0, "Temporary unlock duration in minutes")
+	initCmd.Flags().BoolVarP(&initYes, "yes", "y", false, "Don't prompt; use defaults for values not given as flags")
+	initCmd.Flags().BoolVar(&initRepair, "repair", false, "Re-create missing pieces without changing the schedule or locked paths")
+}
+
+// runInitRepair re-creates whatever is missing from an existing installation
+func runInitRepair() error {
+	fmt.Println("Repairing ConfigLock installation...")
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Config keys
+	if repaired := cfg.FillDefaults(); len(repaired) > 0 {
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Printf("✓ Repaired config keys: %s\n", strings.Join(repaired, ", "))
+	} else {
+		fmt.Println("✓ Config is complete")
+	}
+
+	// Log and data directories
+	if logPath := logger.GetLogger().GetLogPath(); logPath != "" {
+		fmt.Printf("✓ Log file: %s\n", logPath)
+	} else {
+		fmt.Println("Warning: log file could not be set up")
+	}
+	if err := os.MkdirAll(config.GetDataDir(), 0o755); err != nil {
+		fmt.Printf("Warning: failed to create data directory: %v\n", err)
+	} else {
+		fmt.Printf("✓ Data directory: %s\n", config.GetDataDir())
+	}
+
+	// Service unit
+	svc, err := service.New()
+	if err != nil {
+		return fmt.Errorf("failed to create service: %w", err)
+	}
+
+	status, err := svc.Status()
+	if err != nil {
+		fmt.Println("Service is not installed, installing...")
+		if err := svc.Install(); err != nil {
+			return fmt.Errorf("failed to install service: %w", err)
+		}
+		fmt.Println("✓ Daemon installed")
+	} else {
+		fmt.Println("✓ Service is installed")
+	}
+
+	if status != kardianos.StatusRunning {
+		if err := svc.Start(); err != nil {
+			return fmt.Errorf("failed to start service: %w", err)
+		}
+		fmt.Println("✓ Daemon started")
+	} else {
+		fmt.Println("✓ Daemon is running")
+	}
+
+	fmt.Println("\nRepair complete.")
+	return nil
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
+	if initRepair {
+		return runInitRepair()
+	}
+
+	if (initStart == "") != (initEnd == "") {
+		return fmt.Errorf("--start and --end must be given together")
+	}
+	if cmd.Flags().Changed("temp-duration") && initTempDuration <= 0 {
+		return fmt.Errorf("--temp-duration must be positive")
+	}
+
 	fmt.Println("Initializing ConfigLock...")
 
 	// Create config directory
@@ -37,26 +141,32 @@ func runInit(cmd *cobra.Command, args []string) error {
 
 	// Check if config already exists
 	configPath := config.GetConfigPath()
-	var existingLockedPaths []string
+	var existingCfg *config.Config
 	if _, err := os.Stat(configPath); err == nil {
-		// Config exists - check if it's locked
-		isLocked, err := locker.IsLocked(configPath)
+		existingCfg, err = config.Load()
 		if err != nil {
-			fmt.Printf("Warning: failed to check if config is locked: %v\n", err)
+			fmt.Printf("Warning: failed to load existing config: %v\n", err)
 		}
 
-		if isLocked {
-			// Config is locked - require typing challenge to prevent bypass
-			fmt.Println("\n⚠️  Config file is currently locked.")
+		if existingCfg != nil && existingCfg.IsWithinWorkHours(time.Now()) {
+			// Re-initializing changes the schedule, so it is gated like edit time
+			if err := existingCfg.CheckEscapeHatch(config.HatchEditTime); err != nil {
+				return err
+			}
+
+			// Within lock hours - require typing challenge to prevent bypass
+			fmt.Println("\n⚠️  Lock hours are in effect.")
 			fmt.Println("Re-initializing will modify the configuration.")
 			fmt.Println("You must complete the typing challenge to proceed.")
 			fmt.Println()
 
-			if err := challenge.Require("typing challenge failed"); err != nil {
+			if err := requireChallenge(existingCfg); err != nil {
 				return err
 			}
+		} else if initYes {
+			fmt.Println("Config file already exists, overwriting (--yes).")
 		} else {
-			// Config exists but not locked - just ask for confirmation
+			// Outside lock hours - just ask for confirmation
 			fmt.Print("Config file already exists. Overwrite? (y/N): ")
 			reader := bufio.NewReader(os.Stdin)
 			response, _ := reader.ReadString('\n')
@@ -67,27 +177,48 @@ func runInit(cmd *cobra.Command, args []string) error {
 			}
 		}
 
-		// Load existing config to preserve locked paths
-		existingCfg, err := config.Load()
-		if err == nil {
-			existingLockedPaths = existingCfg.LockedPaths
-			fmt.Printf("Preserving %d existing locked path(s)\n", len(existingLockedPaths))
+		if existingCfg != nil {
+			fmt.Printf("Preserving %d existing locked path(s)\n", len(existingCfg.LockedPaths))
 		}
 	}
 
-	// Prompt for lock hours configuration
-	reader := bufio.NewReader(os.Stdin)
-
-	fmt.Println("\nLock hours configuration:")
-	fmt.Println("  - Time range: Enter a time range like 0800-1700 or 8-17.")
-	fmt.Println("  - Day range: Enter a day range like 1-5 (Mon-Fri) or comma-separated days like 1,2,3,4,5.")
-
 	var cfg *config.Config
 	var startTime, endTime string
 	var lockDays []int
+	var err error
+
+	// Values given as flags (or defaulted by --yes) skip their prompt
+	if initStart != "" {
+		if startTime, endTime, err = config.NormalizeTimeRange(initStart + "-" + initEnd); err != nil {
+			return err
+		}
+	} else if initYes {
+		startTime, endTime = config.DefaultStartTime, config.DefaultEndTime
+	}
+	if initDays != "" {
+		if lockDays, err = config.ParseDays(initDays); err != nil {
+			return err
+		}
+	} else if initYes {
+		lockDays = config.DefaultLockDays
+	}
+	tempDuration := config.DefaultTempDuration
+	if cmd.Flags().Changed("temp-duration") {
+		tempDuration = initTempDuration
+	}
+	promptDuration := !initYes && !cmd.Flags().Changed("temp-duration")
+
+	// Prompt for lock hours configuration
+	reader := bufio.NewReader(os.Stdin)
+
+	if startTime == "" || lockDays == nil {
+		fmt.Println("\nLock hours configuration:")
+		fmt.Println("  - Time range: Enter a time range like 0800-1700 or 8-17.")
+		fmt.Println("  - Day range: Enter a day range like 1-5 or mon-fri, or comma-separated days like 1,3,5 or sat,sun.")
+	}
 
 	// Get time range with retry
-	for {
+	for startTime == "" {
 		fmt.Print("\nEnter lock time range (default 08:00-17:00): ")
 		input, _ := reader.ReadString('\n')
 		input = strings.TrimSpace(input)
@@ -106,13 +237,13 @@ func runInit(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get day range with retry
-	for {
-		fmt.Print("Enter lock days (default 1-5): ")
+	for lockDays == nil {
+		fmt.Print("Enter lock days (default mon-fri): ")
 		input, _ := reader.ReadString('\n')
 		input = strings.TrimSpace(input)
 
 		if input == "" {
-			input = "1-5"
+			input = "mon-fri"
 		}
 
 		var err error
@@ -125,16 +256,17 @@ func runInit(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get temp duration
-	fmt.Print("\nTemporary unlock duration in minutes (default 5): ")
-	durationStr, _ := reader.ReadString('\n')
-	durationStr = strings.TrimSpace(durationStr)
-	tempDuration := 5
-	if durationStr != "" {
-		duration, err := strconv.Atoi(durationStr)
-		if err != nil {
-			return fmt.Errorf("invalid duration: %w", err)
+	if promptDuration {
+		fmt.Print("\nTemporary unlock duration in minutes (default 5): ")
+		durationStr, _ := reader.ReadString('\n')
+		durationStr = strings.TrimSpace(durationStr)
+		if durationStr != "" {
+			duration, err := strconv.Atoi(durationStr)
+			if err != nil {
+				return fmt.Errorf("invalid duration: %w", err)
+			}
+			tempDuration = duration
 		}
-		tempDuration = duration
 	}
 
 	cfg = config.CreateDefault(startTime, endTime, lockDays, tempDuration)
@@ -143,15 +275,34 @@ func runInit(cmd *cobra.Command, args []string) error {
 	// Add config file itself to locked paths
 	cfg.AddPath(configPath)
 
-	// Restore existing locked paths (if re-initializing)
-	if len(existingLockedPaths) > 0 {
-		fmt.Println("Restoring existing locked paths...")
-		for _, path := range existingLockedPaths {
+	// Restore existing locked paths and the settings init doesn't ask for
+	// (if re-initializing)
+	if existingCfg != nil {
+		if len(existingCfg.LockedPaths) > 0 {
+			fmt.Println("Restoring existing locked paths...")
+		}
+		for _, path := range existingCfg.LockedPaths {
 			// Skip the config path since we already added it
 			if path != configPath {
 				cfg.AddPath(path)
 			}
 		}
+		cfg.Strictness = existingCfg.Strictness
+		cfg.StopPassphraseHash = existingCfg.StopPassphraseHash
+		cfg.Windows = existingCfg.Windows
+		cfg.Schedules = existingCfg.Schedules
+		cfg.PathSchedules = existingCfg.PathSchedules
+		if len(cfg.Windows) > 0 {
+			fmt.Println("⚠ Lock windows are set in the existing config and take precedence over the time range and days.")
+		}
+
+		// During lock hours the new settings may not lock less, as edit time
+		// requires for shortening the current lock period
+		if now := time.Now(); existingCfg.IsWithinWorkHours(now) {
+			if narrowing := existingCfg.NarrowedBy(cfg, now); !narrowing.Empty() {
+				return fmt.Errorf("re-initializing with these settings %s, run it outside lock hours", narrowing)
+			}
+		}
 	}
 
 	// Save config
@@ -162,7 +313,8 @@ func runInit(cmd *cobra.Command, args []string) error {
 	fmt.Printf("✓ Config created at %s\n", configPath)
 
 	// Apply lock to config file immediately if within lock hours
-	if cfg.IsWithinWorkHours() {
+	if cfg.IsPathActive(configPath, time.Now()) {
+		confirmElevation(cfg, configPath)
 		fmt.Println("Applying lock to config file (within lock hours)...")
 		if err := locker.Lock(configPath); err != nil {
 			fmt.Printf("Warning: failed to lock config file %s: %v\n", configPath, err)
@@ -1,21 +1,38 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/locker"
+	"github.com/baggiiiie/configlock/internal/pathutil"
 	"github.com/spf13/cobra"
 )
 
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all locked paths",
-	Long:  `Display all files and directories that are currently in the lock list.`,
-	RunE:  runList,
+	Long: `Display all files and directories that are currently in the lock list,
+and whether they are locked. For a directory, a sample of its files is read.
+
+With --tree, directories are expanded into their subdirectories with the
+number of files each covers and how many of them are currently locked.`,
+	RunE: runList,
 }
 
+var listTree bool
+
 func init() {
 	rootCmd.AddCommand(listCmd)
+	listCmd.Flags().BoolVar(&listTree, "tree", false, "Expand directories into a tree with file counts and lock state")
 }
 
 func runList(cmd *cobra.Command, args []string) error {
@@ -38,9 +55,205 @@ func runList(cmd *cobra.Command, args []string) error {
 		status := ""
 		if cfg.IsTemporarilyExcluded(path) {
 			status = " [temporarily unlocked]"
+		} else if only := cfg.TempExcludeScope(path); len(only) > 0 && slices.Contains(cfg.ActiveExcludes(), path) {
+			status = fmt.Sprintf(" [temporarily unlocked: %s]", strings.Join(only, ", "))
+		}
+		if !cfg.IsTemporarilyExcluded(path) {
+			status = lockLabel(cmd.Context(), cfg, path) + status
+		}
+		if cfg.IsShallow(path) {
+			status = " [top-level files only]" + status
+		}
+		if until, ok := cfg.InGrace(path, time.Now()); ok {
+			status = fmt.Sprintf(" [grace period, locked from %s]", until.Format("15:04")) + status
+		}
+		if until, ok := cfg.TempPathExpiry(path); ok {
+			status = fmt.Sprintf(" [temporary, until %s]", until.Format("Mon 15:04")) + status
 		}
 		fmt.Printf("%4d. %s%s\n", i+1, path, status)
+		if listTree {
+			printTree(cmd.Context(), cfg, path)
+		}
 	}
 
 	return nil
 }
+
+// Lock states of a file covered by a locked path
+const (
+	stateLocked   = "locked"
+	stateExcluded = "temporarily unlocked"
+	stateUnlocked = "unlocked"
+	stateUnknown  = "unknown"
+)
+
+// lockLabel describes how much of a locked path carries a lock, reading a
+// sample of the files of a directory
+func lockLabel(ctx context.Context, cfg *config.Config, path string) string {
+	status, err := locker.CheckLockedExcept(ctx, path, locker.CheckSampled, cfg.TempExcludeScope(path))
+	if err != nil {
+		return ""
+	}
+	var label string
+	switch {
+	case status.AllLocked():
+		label = "locked"
+	case !status.AnyLocked():
+		label = "not locked"
+	case status.Checked < status.Total:
+		label = fmt.Sprintf("partially locked: %d of %d sampled files", status.Locked, status.Checked)
+	default:
+		label = fmt.Sprintf("partially locked: %d of %d files", status.Locked, status.Checked)
+	}
+	if status.Excluded > 0 {
+		label += fmt.Sprintf(", %d excluded", status.Excluded)
+	}
+	return " [" + label + "]"
+}
+
+// Tree drawing for --tree
+const (
+	treeIndent       = "      " // aligns with the path after "%4d. "
+	treeBranch       = "├── "
+	treeLastBranch   = "└── "
+	treeContinue     = "│   "
+	treeLastContinue = "    "
+)
+
+// fileState returns the lock state of a file covered by lockedPath
+func fileState(cfg *config.Config, lockedPath, file string) string {
+	if cfg.IsTemporarilyExcluded(lockedPath) || cfg.IsTemporarilyExcluded(file) {
+		return stateExcluded
+	}
+	locked, err := locker.IsLocked(file)
+	if err != nil {
+		return stateUnknown
+	}
+	if locked {
+		return stateLocked
+	}
+	return stateUnlocked
+}
+
+// treeNode counts the files below one directory of a locked path
+type treeNode struct {
+	files, locked, excluded int
+	children                map[string]*treeNode
+}
+
+// add counts a file in the given state
+func (n *treeNode) add(state string) {
+	n.files++
+	switch state {
+	case stateLocked:
+		n.locked++
+	case stateExcluded:
+		n.excluded++
+	}
+}
+
+// child returns the node of a subdirectory, creating it if needed
+func (n *treeNode) child(name string) *treeNode {
+	if n.children == nil {
+		n.children = make(map[string]*treeNode)
+	}
+	if n.children[name] == nil {
+		n.children[name] = &treeNode{}
+	}
+	return n.children[name]
+}
+
+// summary describes the files below the node
+func (n *treeNode) summary() string {
+	if n.files == 0 {
+		return "no files"
+	}
+	var locked string
+	switch {
+	case n.locked == n.files:
+		locked = "all locked"
+	case n.locked == 0 && n.excluded == 0:
+		locked = "none locked"
+	default:
+		locked = fmt.Sprintf("%d locked", n.locked)
+	}
+	if n.excluded > 0 {
+		locked += fmt.Sprintf(", %d temporarily unlocked", n.excluded)
+	}
+	return fmt.Sprintf("%d file(s), %s", n.files, locked)
+}
+
+// printTree prints what a locked path covers below its list entry: the state
+// of a file, or the subdirectories of a directory with file counts
+func printTree(ctx context.Context, cfg *config.Config, path string) {
+	files, err := locker.CoveredFiles(ctx, path)
+	if err != nil {
+		fmt.Printf("%s⚠ %v\n", treeIndent, err)
+		return
+	}
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		fmt.Printf("%s%s\n", treeIndent, fileState(cfg, path, files[0]))
+		return
+	}
+
+	root, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		root = path
+	}
+	tree := &treeNode{}
+	for _, file := range files {
+		rel, err := filepath.Rel(root, file)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			// Symlink targets outside the directory count only at the top
+			tree.add(fileState(cfg, path, file))
+			continue
+		}
+		state := fileState(cfg, path, filepath.Join(path, rel))
+		tree.add(state)
+		node := tree
+		if dir := filepath.Dir(rel); dir != "." {
+			for _, name := range strings.Split(dir, string(filepath.Separator)) {
+				node = node.child(name)
+				node.add(state)
+			}
+		}
+	}
+
+	fmt.Printf("%s%s\n", treeIndent, tree.summary())
+	printTreeChildren(tree, treeIndent)
+}
+
+// printTreeChildren prints the subdirectories of node, sorted by name
+func printTreeChildren(node *treeNode, prefix string) {
+	names := slices.Sorted(maps.Keys(node.children))
+	for i, name := range names {
+		branch, next := treeBranch, treeContinue
+		if i == len(names)-1 {
+			branch, next = treeLastBranch, treeLastContinue
+		}
+		child := node.children[name]
+		fmt.Printf("%s%s%s/  %s\n", prefix, branch, name, child.summary())
+		printTreeChildren(child, prefix+next)
+	}
+}
+
+// resolveLockedPath turns a path argument into an absolute path. A number that
+// isn't itself a locked path selects the locked path at that position in
+// 'configlock list'.
+func resolveLockedPath(cfg *config.Config, arg string) (string, error) {
+	absPath, err := pathutil.Abs(arg)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path: %w", err)
+	}
+	if slices.Contains(cfg.LockedPaths, absPath) {
+		return absPath, nil
+	}
+
+	if index, err := strconv.Atoi(arg); err == nil {
+		if index < 1 || index > len(cfg.LockedPaths) {
+			return "", fmt.Errorf("no locked path number %d ('configlock list' shows %d)", index, len(cfg.LockedPaths))
+		}
+		return cfg.LockedPaths[index-1], nil
+	}
+	return absPath, nil
+}
@@ -32,6 +32,9 @@ func runLogs(cmd *cobra.Command, args []string) error {
 	if logPath == "" {
 		return fmt.Errorf("log file path not available")
 	}
+	if reason := log.FallbackReason(); reason != "" {
+		fmt.Printf("Warning: the log file can't be written (%s).\nconfiglock logs to stderr (and syslog where available) instead; for the daemon, see its service log (journalctl --user -u configlock, or the unified log on macOS).\n\n", reason)
+	}
 
 	// Check if log file exists
 	if _, err := os.Stat(logPath); os.IsNotExist(err) {
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/baggiiiie/configlock/internal/service"
+	"github.com/spf13/cobra"
+)
+
+var migrateServiceDryRun bool
+
+var migrateServiceCmd = &cobra.Command{
+	Use:   "migrate-service",
+	Short: "Replace older or hand-written daemon services with the managed one",
+	Long: `Find service definitions that run the configlock daemon besides the one
+configlock manages, and replace them with it:
+
+  - systemd units or launchd plists written by hand or under another name
+  - the managed unit or plist when it runs another configlock executable or
+    was written by an older version
+
+Each one found is stopped and moved to the 'migrated' directory next to the
+config, then the managed service is installed and started. The config itself
+is not touched. System-wide definitions need root; the commands that remove
+them are printed instead.
+
+Windows services and scheduled tasks are not searched: 'configlock start'
+already replaces one with the other.`,
+	Args: cobra.NoArgs,
+	RunE: runMigrateService,
+}
+
+func init() {
+	rootCmd.AddCommand(migrateServiceCmd)
+	migrateServiceCmd.Flags().BoolVar(&migrateServiceDryRun, "dry-run", false, "Only list the services that would be migrated")
+}
+
+func runMigrateService(cmd *cobra.Command, args []string) error {
+	svc, err := service.New()
+	if err != nil {
+		return fmt.Errorf("failed to create service: %w", err)
+	}
+
+	found, err := svc.FindInstallations()
+	if err != nil {
+		return fmt.Errorf("failed to search for services: %w", err)
+	}
+	if len(found) == 0 {
+		fmt.Println("✓ No other configlock services found")
+		return nil
+	}
+
+	fmt.Printf("Found %d service(s) to migrate:\n", len(found))
+	for _, inst := range found {
+		fmt.Printf("  - %s (%s)\n", inst.Name, inst.Path)
+		fmt.Printf("    %s: %s\n", inst.Reason, strings.Join(inst.Exec, " "))
+	}
+	if migrateServiceDryRun {
+		return nil
+	}
+	fmt.Println()
+
+	var manual []service.Installation
+	for _, inst := range found {
+		if inst.System {
+			manual = append(manual, inst)
+			continue
+		}
+		saved, err := service.RemoveInstallation(inst)
+		if err != nil {
+			fmt.Printf("Warning: failed to remove %s: %v\n", inst.Path, err)
+			continue
+		}
+		fmt.Printf("✓ Removed %s (saved to %s)\n", inst.Name, saved)
+	}
+
+	fmt.Println("\nInstalling configlock service...")
+	if err := svc.Install(); err != nil {
+		return fmt.Errorf("failed to install service: %w", err)
+	}
+	if err := svc.Start(); err != nil {
+		return fmt.Errorf("failed to start service: %w", err)
+	}
+	fmt.Println("✓ Daemon started")
+
+	if len(manual) > 0 {
+		fmt.Println("\n⚠ These services are system-wide and still run the daemon. Remove them as root:")
+		for _, inst := range manual {
+			fmt.Printf("  %s\n", service.RemovalCommand(inst))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var panicCancel bool
+
+var panicCmd = &cobra.Command{
+	Use:   "panic",
+	Short: "Request an emergency full unlock after a long delay",
+	Long: `Request an emergency full unlock of all locked paths.
+
+Instead of a typing challenge, the unlock only happens after a long delay
+(panic_delay in hours, default 24) has passed since the request. The daemon
+records the request and, once it becomes due, unlocks all paths and stops
+itself exactly like 'configlock stop'.
+
+Running the command again shows the time remaining on a pending request.
+Use --cancel to withdraw a pending request.`,
+	RunE: runPanic,
+}
+
+func init() {
+	rootCmd.AddCommand(panicCmd)
+	panicCmd.Flags().BoolVar(&panicCancel, "cancel", false, "Cancel a pending emergency unlock request")
+}
+
+func runPanic(cmd *cobra.Command, args []string) error {
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	executesAt, pending := cfg.PanicExecutesAt()
+
+	if panicCancel {
+		if !pending {
+			fmt.Println("No emergency unlock request is pending.")
+			return nil
+		}
+		if _, err := config.Update(func(latest *config.Config) error {
+			latest.CancelPanic()
+			return nil
+		}); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Println("✓ Emergency unlock request cancelled")
+		restartDaemonIfRunning(os.Stdout)
+		return nil
+	}
+
+	if pending {
+		fmt.Printf("Emergency unlock already requested at %s\n", cfg.PanicRequestedAt)
+		if remaining := time.Until(executesAt); remaining > 0 {
+			fmt.Printf("It will execute in %s (at %s)\n", formatDuration(remaining), executesAt.Format("2006-01-02 15:04"))
+		} else {
+			fmt.Println("It is due and will execute on the daemon's next check.")
+		}
+		fmt.Println("Use 'configlock panic --cancel' to withdraw the request.")
+		return nil
+	}
+
+	cfg, err = config.Update(func(latest *config.Config) error {
+		latest.RequestPanic()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	executesAt, _ = cfg.PanicExecutesAt()
+	fmt.Println("✓ Emergency unlock requested")
+	fmt.Printf("All paths will be unlocked and the daemon stopped in %s (at %s).\n",
+		formatDuration(cfg.PanicDelayDuration()), executesAt.Format("2006-01-02 15:04"))
+	fmt.Println("Use 'configlock panic --cancel' to withdraw the request.")
+
+	// Restart daemon so it picks up the request
+	restartDaemonIfRunning(os.Stdout)
+
+	return nil
+}
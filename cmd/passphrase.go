@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/baggiiiie/configlock/internal/challenge"
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var passphraseCmd = &cobra.Command{
+	Use:   "passphrase",
+	Short: "Manage the partner-held passphrase for stop",
+	Long: `Manage a passphrase chosen by someone else (e.g. an accountability partner).
+
+When set, 'configlock stop' during lock hours requires this passphrase instead
+of the typing challenge. Only a bcrypt hash is stored in the config. Changing or
+clearing an existing passphrase requires entering it first.`,
+}
+
+var passphraseSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Set or replace the passphrase",
+	Args:  cobra.NoArgs,
+	RunE:  runPassphraseSet,
+}
+
+var passphraseClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove the passphrase and fall back to the typing challenge",
+	Args:  cobra.NoArgs,
+	RunE:  runPassphraseClear,
+}
+
+func init() {
+	rootCmd.AddCommand(passphraseCmd)
+	passphraseCmd.AddCommand(passphraseSetCmd)
+	passphraseCmd.AddCommand(passphraseClearCmd)
+}
+
+func runPassphraseSet(cmd *cobra.Command, args []string) error {
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg.StopPassphraseHash != "" {
+		fmt.Println("A passphrase is already set. Enter it to replace it.")
+		if err := challenge.RequirePassphrase(cfg.StopPassphraseHash); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("Ask your accountability partner to enter the new passphrase.")
+	passphrase, err := challenge.ReadPassphrase("New passphrase: ")
+	if err != nil {
+		return err
+	}
+	if passphrase == "" {
+		return fmt.Errorf("passphrase cannot be empty")
+	}
+
+	confirm, err := challenge.ReadPassphrase("Confirm passphrase: ")
+	if err != nil {
+		return err
+	}
+	if passphrase != confirm {
+		return fmt.Errorf("passphrases do not match")
+	}
+
+	hash, err := challenge.HashPassphrase(passphrase)
+	if err != nil {
+		return err
+	}
+
+	if _, err := config.Update(func(latest *config.Config) error {
+		latest.StopPassphraseHash = hash
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	fmt.Println("✓ Passphrase set. 'configlock stop' during lock hours now requires it.")
+	return nil
+}
+
+func runPassphraseClear(cmd *cobra.Command, args []string) error {
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg.StopPassphraseHash == "" {
+		fmt.Println("No passphrase is set.")
+		return nil
+	}
+
+	if err := challenge.RequirePassphrase(cfg.StopPassphraseHash); err != nil {
+		return err
+	}
+
+	if _, err := config.Update(func(latest *config.Config) error {
+		latest.StopPassphraseHash = ""
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	fmt.Println("✓ Passphrase cleared. 'configlock stop' uses the typing challenge again.")
+	return nil
+}
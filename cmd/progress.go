@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/baggiiiie/configlock/internal/locker"
+)
+
+// outputWriter returns where informational output goes: stdout, or nowhere when quiet
+func outputWriter(quiet bool) io.Writer {
+	if quiet {
+		return io.Discard
+	}
+	return os.Stdout
+}
+
+// progressThreshold is the number of files above which progress is displayed
+const progressThreshold = 100
+
+// newProgressPrinter returns a locker.ProgressFunc that prints an in-place
+// counter for large directory operations and stays silent for small ones
+func newProgressPrinter(label string) locker.ProgressFunc {
+	return func(done, total int) {
+		// The total is unknown while the directory is still being walked
+		if total == 0 {
+			if done%progressThreshold == 0 {
+				fmt.Printf("\r  %s: %d files", label, done)
+			}
+			return
+		}
+		if total < progressThreshold {
+			return
+		}
+
+		// Update roughly once per percent to avoid flooding the terminal
+		step := max(total/100, 1)
+		if done%step != 0 && done != total {
+			return
+		}
+
+		fmt.Printf("\r  %s: %d/%d files (%d%%)", label, done, total, done*100/total)
+		if done == total {
+			fmt.Println()
+		}
+	}
+}
+
+// printLockReport prints the summary of a lock operation to out, listing
+// failed files with reasons on stdout regardless of out
+func printLockReport(out io.Writer, report *locker.Report) {
+	switch {
+	case len(report.Failed) == 0 && report.Total > 1:
+		fmt.Fprintf(out, "✓ Locks applied (%d locked, %d skipped)\n", report.Locked, report.Skipped)
+	case len(report.Failed) == 0:
+		fmt.Fprintln(out, "✓ Locks applied")
+	case report.Aborted:
+		fmt.Println("⚠ Locking aborted at a failure (lock_failure_policy: abort), the files locked before it were unlocked again:")
+	default:
+		fmt.Printf("⚠ Locked %d/%d file(s), %d skipped, %d failed:\n",
+			report.Locked, report.Total, report.Skipped, len(report.Failed))
+	}
+	for _, failure := range report.Failed {
+		fmt.Printf("  - %s: %v\n", failure.Path, failure.Err)
+	}
+	printExcluded(report)
+}
+
+// printExcluded prints the files of a lock operation left alone because
+// they are excluded, and those excluded from now on after failing
+func printExcluded(report *locker.Report) {
+	if report.Excluded > 0 {
+		fmt.Printf("  %d file(s) left unlocked as excluded_files\n", report.Excluded)
+	}
+	if len(report.NewlyExcluded) == 0 {
+		return
+	}
+	fmt.Printf("⚠ Excluded %d file(s) that failed to lock (lock_failure_policy: exclude):\n", len(report.NewlyExcluded))
+	for _, failure := range report.NewlyExcluded {
+		fmt.Printf("  - %s: %v\n", failure.Path, failure.Err)
+	}
+}
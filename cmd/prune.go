@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var pruneYes bool
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove locked paths that no longer exist",
+	Long: `Find locked paths that no longer exist and remove them from the lock list,
+asking for each one unless --yes is given.
+
+Removing a path that is within its lock hours needs the typing challenge,
+since a path moved away and pruned could be moved back unlocked. Set
+prune_missing_after_days to have the daemon remove missing paths itself.`,
+	Args: cobra.NoArgs,
+	RunE: runPrune,
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+	pruneCmd.Flags().BoolVarP(&pruneYes, "yes", "y", false, "Remove every missing path without asking")
+}
+
+func runPrune(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	missing := cfg.MissingPaths()
+	if len(missing) == 0 {
+		fmt.Println("✓ All locked paths exist")
+		return nil
+	}
+
+	if err := cfg.CheckEscapeHatch(config.HatchRemove); err != nil {
+		return err
+	}
+
+	fmt.Printf("%d locked path(s) no longer exist:\n", len(missing))
+	var prune []string
+	reader := bufio.NewReader(os.Stdin)
+	for _, path := range missing {
+		if pruneYes {
+			fmt.Printf("  %s\n", path)
+			prune = append(prune, path)
+			continue
+		}
+		fmt.Printf("  Remove %s? (y/N): ", path)
+		response, _ := reader.ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+		if response == "y" || response == "yes" {
+			prune = append(prune, path)
+		}
+	}
+	if len(prune) == 0 {
+		fmt.Println("Nothing removed")
+		return nil
+	}
+
+	// One challenge covers every path within its lock hours
+	now := time.Now()
+	for _, path := range prune {
+		if cfg.IsPathActive(path, now) {
+			if err := requireChallenge(cfg); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	if _, err := config.Update(func(latest *config.Config) error {
+		for _, path := range prune {
+			latest.RemovePath(path)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	fmt.Printf("✓ Removed %d path(s) from the lock list\n", len(prune))
+
+	restartDaemonIfRunning(os.Stdout)
+	return nil
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/baggiiiie/configlock/internal/daemon"
+	"github.com/spf13/cobra"
+)
+
+var reloadCmd = &cobra.Command{
+	Use:   "reload",
+	Short: "Make the daemon reload its configuration",
+	Long: `Make the running daemon reload its configuration without restarting it.
+
+Use this after batch changes made with 'add --no-daemon-restart' or
+'rm --no-daemon-restart'. Falls back to restarting the daemon if it cannot be
+reached over its control socket.`,
+	Args: cobra.NoArgs,
+	RunE: runReload,
+}
+
+func init() {
+	rootCmd.AddCommand(reloadCmd)
+}
+
+func runReload(cmd *cobra.Command, args []string) error {
+	if err := daemon.SignalReload(); err != nil {
+		fmt.Printf("Could not reach the daemon directly (%v)\n", err)
+		restartDaemonIfRunning(os.Stdout)
+		return nil
+	}
+
+	fmt.Println("✓ Daemon reloading configuration")
+	return nil
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/ipc"
+	"github.com/baggiiiie/configlock/internal/locker"
+	"github.com/spf13/cobra"
+)
+
+var relockCmd = &cobra.Command{
+	Use:   "relock <path|index>",
+	Short: "Lock a path again right away",
+	Long: `Re-apply the lock to a locked path immediately, ending its temporary unlock
+if it has one. Use it when you're done editing early. Outside the path's lock
+hours only the temporary unlock is ended.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRelock,
+}
+
+func init() {
+	rootCmd.AddCommand(relockCmd)
+}
+
+func runRelock(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	absPath, err := resolveLockedPath(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	if err := cfg.RequireLockedPath(absPath); err != nil {
+		return err
+	}
+
+	var result ipc.Relock
+	err = ipc.Send(ipc.Request{Command: ipc.CommandRelock, Path: absPath}, &result)
+	if errors.Is(err, ipc.ErrNotRunning) {
+		result, err = relockWithoutDaemon(absPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	if result.Locked {
+		fmt.Printf("✓ Re-locked: %s\n", absPath)
+	} else {
+		fmt.Printf("✓ Temporary unlock ended, not locking outside lock hours: %s\n", absPath)
+	}
+	return nil
+}
+
+// relockWithoutDaemon removes the exclusion and locks the path directly when
+// the daemon can't be reached
+func relockWithoutDaemon(absPath string) (ipc.Relock, error) {
+	cfg, err := config.Update(func(latest *config.Config) error {
+		latest.RemoveTempExclude(absPath)
+		return nil
+	})
+	if err != nil {
+		return ipc.Relock{}, fmt.Errorf("failed to save config: %w", err)
+	}
+	if !cfg.IsPathActive(absPath, time.Now()) {
+		return ipc.Relock{Path: absPath}, nil
+	}
+	if err := locker.Lock(absPath); err != nil {
+		return ipc.Relock{}, fmt.Errorf("failed to lock %s: %w", absPath, err)
+	}
+	return ipc.Relock{Path: absPath, Locked: true}, nil
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/report"
+	"github.com/baggiiiie/configlock/internal/stats"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Summarize blocked changes, temp unlocks and challenges",
+	Long: `Summarize the events recorded by the daemon: lock periods, time spent
+locked, changes to locked paths that were blocked, and temp unlocks, per day
+and per path, along with every typing challenge and passphrase check and
+whether it was passed or given up on.
+
+The report is printed as text, markdown or HTML, written to a file with
+--output, or emailed to report_email through smtp_url with --email. Run it
+from cron, e.g. every Monday morning, to get it without asking.
+
+With --format json or csv, every recorded event of the period is exported
+instead, e.g. for a spreadsheet; without a period the whole history is.`,
+	Args: cobra.NoArgs,
+	RunE: runReport,
+}
+
+var (
+	reportWeek   bool
+	reportFrom   string
+	reportTo     string
+	reportFormat string
+	reportOutput string
+	reportEmail  bool
+)
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.Flags().BoolVar(&reportWeek, "week", false, "Report on today and the 6 days before it")
+	reportCmd.Flags().StringVar(&reportFrom, "from", "", "Report from this day on (YYYY-MM-DD)")
+	reportCmd.Flags().StringVar(&reportTo, "to", "", "Report up to and including this day (YYYY-MM-DD, default today)")
+	reportCmd.Flags().StringVar(&reportFormat, "format", report.FormatText, "Output format: "+strings.Join(append(report.Formats, report.ExportFormats...), ", "))
+	reportCmd.Flags().StringVarP(&reportOutput, "output", "o", "", "Write the report to a file instead of stdout")
+	reportCmd.Flags().BoolVar(&reportEmail, "email", false, "Email the report to report_email")
+	reportCmd.MarkFlagsMutuallyExclusive("week", "from")
+	reportCmd.MarkFlagsMutuallyExclusive("week", "to")
+	// --out is accepted for --output
+	reportCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "out" {
+			name = "output"
+		}
+		return pflag.NormalizedName(name)
+	})
+}
+
+// reportPeriod returns the period given by --week or --from and --to. An
+// export without a period covers the whole history.
+func reportPeriod(now time.Time, export bool) (from, to time.Time, err error) {
+	if reportWeek {
+		// Today and the 6 days before it
+		year, month, day := now.AddDate(0, 0, -6).Date()
+		return time.Date(year, month, day, 0, 0, 0, 0, now.Location()), now, nil
+	}
+	if reportFrom == "" {
+		if !export {
+			return from, to, fmt.Errorf("no period given, use --week or --from")
+		}
+		if reportTo != "" {
+			return from, to, fmt.Errorf("--to needs --from")
+		}
+		return time.Time{}, now, nil
+	}
+
+	from, err = time.ParseInLocation(time.DateOnly, reportFrom, now.Location())
+	if err != nil {
+		return from, to, fmt.Errorf("invalid --from %q, expected YYYY-MM-DD", reportFrom)
+	}
+	to = now
+	if reportTo != "" {
+		last, err := time.ParseInLocation(time.DateOnly, reportTo, now.Location())
+		if err != nil {
+			return from, to, fmt.Errorf("invalid --to %q, expected YYYY-MM-DD", reportTo)
+		}
+		to = last.AddDate(0, 0, 1)
+	}
+	if !from.Before(to) {
+		return from, to, fmt.Errorf("--from must not be after --to")
+	}
+	return from, to, nil
+}
+
+func runReport(cmd *cobra.Command, args []string) error {
+	export := report.IsExport(reportFormat)
+	now := time.Now()
+	from, to, err := reportPeriod(now, export)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if reportEmail && (cfg.ReportEmail == "" || cfg.SMTPURL == "") {
+		return fmt.Errorf("--email needs report_email and smtp_url in %s", config.GetConfigPath())
+	}
+	if reportEmail && export {
+		return fmt.Errorf("--email sends %s reports, not %s exports", strings.Join(report.Formats, ", "), reportFormat)
+	}
+
+	events, err := stats.Load(from, to)
+	if err != nil {
+		return err
+	}
+
+	var body string
+	var r *report.Report
+	if export {
+		body, err = report.Export(events, reportFormat)
+	} else {
+		r = report.Build(events, from, to, now)
+		body, err = r.Render(reportFormat)
+	}
+	if err != nil {
+		return err
+	}
+
+	if reportOutput != "" {
+		if err := os.WriteFile(reportOutput, []byte(body), 0o644); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		fmt.Printf("✓ Report written to %s\n", reportOutput)
+	}
+
+	if reportEmail {
+		if err := report.Mail(cfg.SMTPURL, cfg.ReportEmail, r.Title(), body, reportFormat == report.FormatHTML); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Report sent to %s\n", cfg.ReportEmail)
+	}
+
+	if reportOutput == "" && !reportEmail {
+		fmt.Print(body)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/daemon"
+	"github.com/baggiiiie/configlock/internal/locker"
+	"github.com/baggiiiie/configlock/internal/logger"
+	"github.com/baggiiiie/configlock/internal/service"
+	"github.com/spf13/cobra"
+)
+
+var (
+	resetPurge bool
+	resetYes   bool
+)
+
+var resetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Unlock everything and uninstall configlock",
+	Long: `Tear configlock down completely, undoing 'configlock init':
+
+  - Stop and uninstall the daemon service
+  - Unlock every locked path, including the config file
+
+With --purge the config, its backups, the log, stats, snapshot index and
+caches are deleted as well. Filesystem snapshots themselves are kept; remove
+them with 'configlock snapshot prune' before resetting if you don't want them.
+
+During lock hours this requires the typing challenge (or the stop passphrase)
+and is refused when the strictness level disables stop.`,
+	Args: cobra.NoArgs,
+	RunE: runReset,
+}
+
+func init() {
+	rootCmd.AddCommand(resetCmd)
+	resetCmd.Flags().BoolVar(&resetPurge, "purge", false, "Also delete config, backups, logs, stats and caches")
+	resetCmd.Flags().BoolVarP(&resetYes, "yes", "y", false, "Don't ask for confirmation outside lock hours")
+}
+
+func runReset(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg != nil {
+		if err := cfg.CheckEscapeHatch(config.HatchStop); err != nil {
+			return err
+		}
+
+		fmt.Printf("This will unlock %d path(s) and uninstall the configlock daemon.\n", len(cfg.LockedPaths))
+		if resetPurge {
+			fmt.Println("Config, backups, logs, stats and caches will be deleted.")
+		}
+		fmt.Println()
+
+		if cfg.IsWithinWorkHours(time.Now()) {
+			if err := requireStopAuthorization(cfg); err != nil {
+				return err
+			}
+			fmt.Println()
+		} else if !resetYes && !confirmReset() {
+			fmt.Println("Reset cancelled.")
+			return nil
+		}
+
+		takeSnapshots(cfg, cfg.LockedPaths, "reset")
+	}
+
+	// The daemon goes first so it can't re-lock anything behind our back
+	fmt.Println("Uninstalling daemon...")
+	svc, err := service.New()
+	if err != nil {
+		fmt.Printf("Warning: failed to create service: %v\n", err)
+	} else if err := svc.Uninstall(); err != nil {
+		fmt.Println("Daemon is not installed.")
+	} else {
+		fmt.Println("✓ Daemon stopped and uninstalled")
+	}
+
+	failed := 0
+	if cfg != nil {
+		fmt.Println("\nUnlocking all paths...")
+		for _, path := range cfg.LockedPaths {
+			if err := locker.Unlock(path); err != nil {
+				fmt.Printf("  ⚠ %s: %v\n", path, err)
+				failed++
+				continue
+			}
+			fmt.Printf("  ✓ %s\n", path)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("\n⚠ %d path(s) could not be unlocked. You may need to manually unlock them.\n", failed)
+		if resetPurge {
+			// Keep the config so the locked paths can still be found
+			fmt.Println("Nothing was deleted; run 'configlock reset --purge' again once they are unlocked.")
+		}
+		return fmt.Errorf("reset incomplete")
+	}
+
+	if resetPurge {
+		// A daemon that was killed may have left the logs append-only
+		if err := daemon.ReleaseLogs(); err != nil {
+			fmt.Printf("Warning: failed to clear append-only flag: %v\n", err)
+		}
+		fmt.Println("\nDeleting configlock files...")
+		for _, dir := range []string{config.GetConfigDir(), config.GetDataDir(), config.GetCacheDir()} {
+			removeResetPath(dir)
+		}
+		// The log lives outside the data directory on macOS
+		if logPath := logger.GetLogger().GetLogPath(); logPath != "" {
+			removeResetPath(logPath)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("ConfigLock has been reset.")
+	if !resetPurge {
+		fmt.Printf("Your config is kept at %s; run 'configlock init' to set it up again.\n", config.GetConfigPath())
+	}
+	return nil
+}
+
+// confirmReset asks the user to confirm the reset
+func confirmReset() bool {
+	fmt.Print("Continue? (y/N): ")
+	response, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "y" || response == "yes"
+}
+
+// removeResetPath deletes a configlock file or directory if it exists
+func removeResetPath(path string) {
+	if _, err := os.Lstat(path); err != nil {
+		return
+	}
+	if err := os.RemoveAll(path); err != nil {
+		fmt.Printf("  ⚠ %s: %v\n", path, err)
+		return
+	}
+	fmt.Printf("  ✓ Deleted %s\n", path)
+}
@@ -3,38 +3,37 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"path/filepath"
-	"slices"
+	"time"
 
-	"github.com/baggiiiie/configlock/internal/challenge"
 	"github.com/baggiiiie/configlock/internal/config"
 	"github.com/baggiiiie/configlock/internal/locker"
-	"github.com/baggiiiie/configlock/internal/service"
-	kardianos "github.com/kardianos/service"
 	"github.com/spf13/cobra"
 )
 
 var rmCmd = &cobra.Command{
-	Use:   "rm <path>",
+	Use:   "rm <path|index>",
 	Short: "Remove a file or directory from the lock list",
 	Long: `Remove a file or directory from the lock list. This requires
-completing a typing challenge to prevent impulsive actions.`,
+completing a typing challenge to prevent impulsive actions.
+
+The path can also be given as its number in 'configlock list'.`,
 	Args: cobra.ExactArgs(1),
 	RunE: runRm,
 }
 
+var (
+	rmQuiet     bool
+	rmNoRestart bool
+)
+
 func init() {
 	rootCmd.AddCommand(rmCmd)
+	rmCmd.Flags().BoolVarP(&rmQuiet, "quiet", "q", false, "Only print warnings and errors")
+	rmCmd.Flags().BoolVar(&rmNoRestart, "no-daemon-restart", false, "Don't restart the daemon (run 'configlock reload' afterwards)")
 }
 
 func runRm(cmd *cobra.Command, args []string) error {
-	path := args[0]
-
-	// Resolve to absolute path
-	absPath, err := filepath.Abs(path)
-	if err != nil {
-		return fmt.Errorf("failed to resolve path: %w", err)
-	}
+	out := outputWriter(rmQuiet)
 
 	// Load config
 	cfg, err := config.Load()
@@ -42,61 +41,53 @@ func runRm(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	// Check if path exists in config
-	found := slices.Contains(cfg.LockedPaths, absPath)
+	absPath, err := resolveLockedPath(cfg, args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.RequireLockedPath(absPath); err != nil {
+		return err
+	}
 
-	if !found {
-		return fmt.Errorf("path not found in lock list: %s", absPath)
+	if err := cfg.CheckEscapeHatch(config.HatchRemove); err != nil {
+		return err
 	}
 
 	// Run typing challenge only during lock hours
-	if cfg.IsWithinWorkHours() {
-		if err := challenge.Require("challenge failed"); err != nil {
+	if cfg.IsPathActive(absPath, time.Now()) {
+		if err := requireChallenge(cfg); err != nil {
 			return err
 		}
 	}
 
 	// Remove path from config
-	cfg.RemovePath(absPath)
-
-	// Save config
-	if err := cfg.Save(); err != nil {
+	if _, err := config.Update(func(latest *config.Config) error {
+		latest.RemovePath(absPath)
+		return nil
+	}); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 
 	// Check if it's a file or directory for display purposes
 	info, err := os.Stat(absPath)
 	if err == nil && info.IsDir() {
-		fmt.Printf("✓ Removed directory from lock list: %s\n", absPath)
+		fmt.Fprintf(out, "✓ Removed directory from lock list: %s\n", absPath)
 	} else {
-		fmt.Printf("✓ Removed file from lock list: %s\n", absPath)
+		fmt.Fprintf(out, "✓ Removed file from lock list: %s\n", absPath)
 	}
 
 	// Unlock the path immediately (locker will handle directories recursively)
-	fmt.Println("Unlocking path...")
+	fmt.Fprintln(out, "Unlocking path...")
 	if err := locker.Unlock(absPath); err != nil {
 		fmt.Printf("Warning: failed to unlock %s: %v\n", absPath, err)
 	} else {
-		fmt.Println("✓ Path unlocked")
+		fmt.Fprintln(out, "✓ Path unlocked")
 	}
 
 	// Restart daemon if running to pick up configuration changes
-	svc, err := service.New()
-	if err == nil {
-		status, err := svc.Status()
-		if err == nil && status == kardianos.StatusRunning {
-			fmt.Println("\nRestarting daemon to apply configuration changes...")
-			if err := svc.Restart(); err != nil {
-				// Restart might not be supported, try stop+start
-				if err := svc.Stop(); err == nil {
-					if err := svc.Start(); err != nil {
-						fmt.Printf("Warning: failed to restart daemon: %v\n", err)
-						return nil
-					}
-				}
-			}
-			fmt.Println("✓ Daemon restarted")
-		}
+	if !rmNoRestart {
+		restartDaemonIfRunning(out)
 	}
 
 	return nil
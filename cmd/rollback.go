@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/locker"
+	"github.com/baggiiiie/configlock/internal/pathutil"
+	"github.com/baggiiiie/configlock/internal/snapshot"
+	"github.com/spf13/cobra"
+)
+
+var rollbackID string
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback [path]",
+	Short: "Restore a path from a snapshot",
+	Long: `Restore a locked path from a snapshot.
+
+When snapshot_before_unlock is enabled, temp-unlock and stop take a btrfs, zfs
+or APFS snapshot before unlocking, so regretted edits can be rolled back.
+Snapshots created with 'configlock snapshot create' are plain copies in the
+backup store and work on every platform.
+
+Without arguments, lists the recorded snapshots. With a path, restores it from
+the newest snapshot covering it, or from the snapshot given with --id.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runRollback,
+}
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+	rollbackCmd.Flags().StringVar(&rollbackID, "id", "", "Snapshot ID to restore from (default: newest covering the path)")
+}
+
+// takeSnapshots snapshots each path before it is unlocked, if enabled in the config.
+// Failures are reported but never block the unlock.
+func takeSnapshots(cfg *config.Config, paths []string, reason string) {
+	if !cfg.SnapshotBeforeUnlock {
+		return
+	}
+
+	for _, path := range paths {
+		record, err := snapshot.Take(path, reason)
+		if err != nil {
+			fmt.Printf("Warning: failed to snapshot %s: %v\n", path, err)
+			continue
+		}
+		fmt.Printf("✓ Snapshot %s (%s) taken of %s\n", record.ID, record.Kind, path)
+	}
+}
+
+func runRollback(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return listSnapshots()
+	}
+
+	absPath, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+	if realPath, err := filepath.EvalSymlinks(absPath); err == nil {
+		absPath = realPath
+	}
+
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	record, err := snapshot.Find(absPath, rollbackID)
+	if err != nil {
+		return err
+	}
+	if err := requireRestoreAllowed(cfg, []string{absPath}); err != nil {
+		return err
+	}
+
+	return restoreFromSnapshot(cfg, record, absPath)
+}
+
+// requireRestoreAllowed gates restoring paths from a snapshot like removing
+// them from the lock list: a snapshot can hold any content, so while one of
+// paths is locked the escape hatch must be open and the typing challenge
+// passed
+func requireRestoreAllowed(cfg *config.Config, paths []string) error {
+	now := time.Now()
+	locked := slices.ContainsFunc(paths, func(path string) bool {
+		covered := slices.ContainsFunc(cfg.LockedPaths, func(lockedPath string) bool {
+			return pathutil.Within(path, lockedPath) || pathutil.Within(lockedPath, path)
+		})
+		return covered && cfg.IsPathActive(path, now) && !cfg.IsTemporarilyExcluded(path)
+	})
+	if !locked {
+		return nil
+	}
+	if err := cfg.CheckEscapeHatch(config.HatchRemove); err != nil {
+		return err
+	}
+	return requireChallenge(cfg)
+}
+
+// restoreFromSnapshot unlocks path, restores it from the snapshot and re-locks
+// it if it should currently be locked
+func restoreFromSnapshot(cfg *config.Config, record *snapshot.Record, path string) error {
+	if err := snapshot.CheckLocation(record); err != nil {
+		return err
+	}
+	fmt.Printf("Restoring %s from snapshot %s (%s, taken %s)...\n",
+		path, record.ID, record.Kind, record.Created.Format("2006-01-02 15:04"))
+
+	if err := locker.Unlock(path); err != nil {
+		fmt.Printf("Warning: failed to unlock %s: %v\n", path, err)
+	}
+
+	restoreErr := snapshot.Restore(record, path)
+
+	// Re-lock if the path should currently be locked
+	if cfg.IsPathActive(path, time.Now()) && !cfg.IsTemporarilyExcluded(path) {
+		if err := locker.Lock(path); err != nil {
+			fmt.Printf("Warning: failed to re-lock %s: %v\n", path, err)
+		}
+	}
+
+	if restoreErr != nil {
+		return fmt.Errorf("failed to restore %s: %w", path, restoreErr)
+	}
+
+	fmt.Printf("✓ Restored %s\n", path)
+	return nil
+}
+
+// listSnapshots prints all recorded snapshots
+func listSnapshots() error {
+	records, err := snapshot.List()
+	if err != nil {
+		return err
+	}
+
+	if len(records) == 0 {
+		fmt.Println("No snapshots recorded.")
+		fmt.Println("Use 'configlock snapshot create' or set snapshot_before_unlock in the config.")
+		return nil
+	}
+
+	fmt.Printf("Snapshots (%d):\n\n", len(records))
+	for _, r := range records {
+		fmt.Printf("  %s  %-5s  %s  %s (%s)\n", r.ID, r.Kind, r.Created.Format("2006-01-02 15:04"), r.Path, r.Reason)
+	}
+	return nil
+}
@@ -18,9 +18,13 @@ var rootCmd = &cobra.Command{
 config files or directories during lock hours using system-level immutable flags.`,
 	SilenceUsage:  true,
 	SilenceErrors: true,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		recordChallenges(cmd.CommandPath())
+	},
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
-		// Skip upgrade check for daemon (runs in background) and help/version
-		if cmd.Name() == "daemon" || cmd.Name() == "help" {
+		// Skip upgrade check for daemon (runs in background) and help/version,
+		// and for status, which shows it in its own output
+		if cmd.Name() == "daemon" || cmd.Name() == "help" || cmd.Name() == "status" {
 			return
 		}
 		upgrade.CheckForUpgrade(GetVersion())
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/ipc"
+	"github.com/spf13/cobra"
+)
+
+var serveAddr string
+
+// serveRefreshSeconds is how often the HTML page reloads itself
+const serveRefreshSeconds = 30
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a read-only status page on localhost",
+	Long: `Serve the current lock status over HTTP for dashboards and pinned
+browser tabs:
+
+  /             HTML page that refreshes itself every 30 seconds
+  /status.json  the same status as JSON (for Übersicht, Glance, etc.)
+
+The server is read-only and only listens on a loopback address.`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:7171", "Loopback address to listen on")
+}
+
+// serveStatus is the status served as JSON
+type serveStatus struct {
+	Locked       bool              `json:"locked"` // within lock hours
+	Reason       string            `json:"reason"`
+	NextChange   time.Time         `json:"next_change,omitzero"`
+	Strictness   string            `json:"strictness"`
+	Daemon       bool              `json:"daemon_running"`
+	LastEnforced time.Time         `json:"last_enforced,omitzero"`
+	FilesLocked  int               `json:"files_locked"`
+	FilesTotal   int               `json:"files_total"`
+	Problems     map[string]string `json:"problems,omitempty"` // locked path -> last lock error
+	LockedPaths  []string          `json:"locked_paths"`
+	TempUnlocks  []serveTempUnlock `json:"temp_unlocks"`
+	Generated    time.Time         `json:"generated"`
+}
+
+// serveTempUnlock is an active temporary unlock
+type serveTempUnlock struct {
+	Path      string    `json:"path"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Only      []string  `json:"only,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	host, _, err := net.SplitHostPort(serveAddr)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %w", serveAddr, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("%s is not a loopback address; the status server only listens locally", host)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", serveHTML)
+	mux.HandleFunc("GET /status.json", serveJSON)
+
+	listener, err := net.Listen("tcp", serveAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", serveAddr, err)
+	}
+	fmt.Printf("✓ Serving status on http://%s (Ctrl+C to stop)\n", listener.Addr())
+
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	return server.Serve(listener)
+}
+
+// currentStatus gathers the status from the config and, if it runs, the daemon
+func currentStatus() (*serveStatus, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	now := time.Now()
+	status := &serveStatus{
+		Locked:      cfg.IsWithinWorkHours(now),
+		Reason:      cfg.LockReason(now),
+		Strictness:  cfg.GetStrictness(),
+		LockedPaths: cfg.LockedPaths,
+		TempUnlocks: []serveTempUnlock{},
+		Generated:   now,
+	}
+	if next, ok := cfg.NextTransition(now); ok {
+		status.NextChange = next
+	}
+
+	for _, path := range cfg.ActiveExcludes() {
+		expiry, _ := time.Parse(time.RFC3339, cfg.TempExcludes[path])
+		status.TempUnlocks = append(status.TempUnlocks, serveTempUnlock{
+			Path:      path,
+			ExpiresAt: expiry,
+			Only:      cfg.TempExcludeScope(path),
+			Reason:    cfg.TempExcludeReason(path),
+		})
+	}
+	slices.SortFunc(status.TempUnlocks, func(a, b serveTempUnlock) int {
+		return a.ExpiresAt.Compare(b.ExpiresAt)
+	})
+
+	var daemonStatus ipc.Status
+	if err := ipc.Query(ipc.CommandStatus, &daemonStatus); err == nil {
+		status.Daemon = true
+		status.LastEnforced = daemonStatus.LastEnforced
+		for path, health := range daemonStatus.Health {
+			status.FilesLocked += health.Locked
+			status.FilesTotal += health.Locked + health.Failed
+			if health.Error != "" {
+				if status.Problems == nil {
+					status.Problems = make(map[string]string)
+				}
+				status.Problems[path] = health.Error
+			}
+		}
+	}
+	return status, nil
+}
+
+func serveJSON(w http.ResponseWriter, r *http.Request) {
+	status, err := currentStatus()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	// Dashboards such as Übersicht fetch from another origin
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(status)
+}
+
+func serveHTML(w http.ResponseWriter, r *http.Request) {
+	status, err := currentStatus()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, statusHTML(status))
+}
+
+// statusHTML renders the status page
+func statusHTML(s *serveStatus) string {
+	state := "Unlocked"
+	if s.Locked {
+		state = "Locked"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><meta http-equiv=\"refresh\" content=\"%d\"><title>ConfigLock: %s</title></head>\n<body>\n", serveRefreshSeconds, state)
+	fmt.Fprintf(&b, "<h1>%s</h1>\n<ul>\n", state)
+	fmt.Fprintf(&b, "<li><strong>Reason:</strong> %s</li>\n", html.EscapeString(s.Reason))
+	if !s.NextChange.IsZero() {
+		fmt.Fprintf(&b, "<li><strong>Next change:</strong> %s (in %s)</li>\n", s.NextChange.Format("Mon 15:04"), formatDuration(s.NextChange.Sub(s.Generated)))
+	}
+	fmt.Fprintf(&b, "<li><strong>Strictness:</strong> %s</li>\n", html.EscapeString(s.Strictness))
+	switch {
+	case !s.Daemon:
+		b.WriteString("<li><strong>Daemon:</strong> not running</li>\n")
+	case !s.LastEnforced.IsZero():
+		fmt.Fprintf(&b, "<li><strong>Enforcement:</strong> last sweep %s ago, %d/%d file(s) locked</li>\n",
+			formatDuration(s.Generated.Sub(s.LastEnforced)), s.FilesLocked, s.FilesTotal)
+	default:
+		b.WriteString("<li><strong>Daemon:</strong> running</li>\n")
+	}
+	b.WriteString("</ul>\n")
+
+	if len(s.Problems) > 0 {
+		b.WriteString("<h2>Problems</h2>\n<ul>\n")
+		for _, path := range slices.Sorted(maps.Keys(s.Problems)) {
+			fmt.Fprintf(&b, "<li><code>%s</code>: %s</li>\n", html.EscapeString(path), html.EscapeString(s.Problems[path]))
+		}
+		b.WriteString("</ul>\n")
+	}
+
+	if len(s.TempUnlocks) > 0 {
+		b.WriteString("<h2>Temporary unlocks</h2>\n<ul>\n")
+		for _, unlock := range s.TempUnlocks {
+			fmt.Fprintf(&b, "<li><code>%s</code>", html.EscapeString(unlock.Path))
+			if len(unlock.Only) > 0 {
+				fmt.Fprintf(&b, " only %s", html.EscapeString(strings.Join(unlock.Only, ", ")))
+			}
+			fmt.Fprintf(&b, ", re-locks in %s", formatDuration(max(unlock.ExpiresAt.Sub(s.Generated), 0)))
+			if unlock.Reason != "" {
+				fmt.Fprintf(&b, ": %s", html.EscapeString(unlock.Reason))
+			}
+			b.WriteString("</li>\n")
+		}
+		b.WriteString("</ul>\n")
+	}
+
+	fmt.Fprintf(&b, "<h2>Locked paths (%d)</h2>\n<ul>\n", len(s.LockedPaths))
+	for _, path := range s.LockedPaths {
+		fmt.Fprintf(&b, "<li><code>%s</code></li>\n", html.EscapeString(path))
+	}
+	b.WriteString("</ul>\n</body>\n</html>\n")
+	return b.String()
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/baggiiiie/configlock/internal/service"
+	kardianos "github.com/kardianos/service"
+)
+
+// restartDaemonIfRunning restarts the daemon so it picks up configuration changes.
+// Does nothing if the daemon is not installed or not running.
+// Progress messages go to out, warnings always go to stdout.
+func restartDaemonIfRunning(out io.Writer) {
+	svc, err := service.New()
+	if err != nil {
+		return
+	}
+
+	status, err := svc.Status()
+	if err != nil || status != kardianos.StatusRunning {
+		return
+	}
+
+	fmt.Fprintln(out, "\nRestarting daemon to apply configuration changes...")
+	if err := svc.Restart(); err != nil {
+		// Restart might not be supported, try stop+start
+		if err := svc.Stop(); err == nil {
+			if err := svc.Start(); err != nil {
+				fmt.Printf("Warning: failed to restart daemon: %v\n", err)
+				return
+			}
+		}
+	}
+	fmt.Fprintln(out, "✓ Daemon restarted")
+}
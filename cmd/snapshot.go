@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/snapshot"
+	"github.com/spf13/cobra"
+)
+
+var snapshotKeep int
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Capture, list, restore and prune snapshots of locked paths",
+	Long: `Manage snapshots of locked paths in the backup store.
+
+Snapshots created here are plain copies of the managed files, so they work on
+every platform and filesystem. Filesystem snapshots taken before unlocking
+(snapshot_before_unlock) are listed and pruned here as well.`,
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create [path...]",
+	Short: "Capture locked paths into the backup store (default: all locked paths)",
+	RunE:  runSnapshotCreate,
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recorded snapshots",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listSnapshots()
+	},
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <id>",
+	Short: "Restore every path captured in a snapshot",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSnapshotRestore,
+}
+
+var snapshotPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete old snapshots, keeping the newest ones",
+	Args:  cobra.NoArgs,
+	RunE:  runSnapshotPrune,
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotRestoreCmd)
+	snapshotCmd.AddCommand(snapshotPruneCmd)
+	snapshotPruneCmd.Flags().IntVar(&snapshotKeep, "keep", 10, "Number of newest snapshots to keep")
+}
+
+func runSnapshotCreate(cmd *cobra.Command, args []string) error {
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	paths := cfg.LockedPaths
+	if len(args) > 0 {
+		paths = nil
+		for _, arg := range args {
+			absPath, err := filepath.Abs(arg)
+			if err != nil {
+				return fmt.Errorf("failed to resolve path: %w", err)
+			}
+			paths = append(paths, absPath)
+		}
+	}
+
+	if len(paths) == 0 {
+		fmt.Println("No paths to snapshot.")
+		return nil
+	}
+
+	records, err := snapshot.Capture(paths, "manual")
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Snapshot %s created with %d path(s):\n", records[0].ID, len(records))
+	for _, r := range records {
+		fmt.Printf("  - %s\n", r.Path)
+	}
+	return nil
+}
+
+func runSnapshotRestore(cmd *cobra.Command, args []string) error {
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	records, err := snapshot.Get(args[0])
+	if err != nil {
+		return err
+	}
+	paths := make([]string, len(records))
+	for i, record := range records {
+		if err := snapshot.CheckLocation(&record); err != nil {
+			return err
+		}
+		paths[i] = record.Path
+	}
+	if err := requireRestoreAllowed(cfg, paths); err != nil {
+		return err
+	}
+
+	var lastErr error
+	for i := range records {
+		if err := restoreFromSnapshot(cfg, &records[i], records[i].Path); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+func runSnapshotPrune(cmd *cobra.Command, args []string) error {
+	if snapshotKeep < 0 {
+		return fmt.Errorf("--keep must not be negative")
+	}
+
+	removed, err := snapshot.Prune(snapshotKeep)
+	for _, id := range removed {
+		fmt.Printf("✓ Removed snapshot %s\n", id)
+	}
+	if err != nil {
+		return err
+	}
+	if len(removed) == 0 {
+		fmt.Println("Nothing to prune.")
+	}
+	return nil
+}
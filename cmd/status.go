@@ -2,10 +2,14 @@ package cmd
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/ipc"
 	"github.com/baggiiiie/configlock/internal/service"
+	"github.com/baggiiiie/configlock/internal/upgrade"
 	kardianos "github.com/kardianos/service"
 	"github.com/spf13/cobra"
 )
@@ -13,7 +17,7 @@ import (
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show current status and configuration",
-	Long:  `Display the current lock status, lock hours, and active temporary unlocks.`,
+	Long:  `Display the current lock status and why it is locked or unlocked, the lock hours or windows, and active temporary unlocks.`,
 	RunE:  runStatus,
 }
 
@@ -28,9 +32,35 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	fmt.Printf("Lock Hours: %s - %s (Days: %s)\n", cfg.StartTime, cfg.EndTime, config.FormatDays(cfg.LockDays))
+	if len(cfg.Windows) > 0 {
+		fmt.Printf("Lock Windows: %s\n", strings.Join(cfg.Windows, "; "))
+	} else {
+		days := config.FormatDays(cfg.LockDays)
+		if days == "" {
+			days = "none"
+		}
+		fmt.Printf("Lock Hours: %s - %s (Days: %s)\n", cfg.StartTime, cfg.EndTime, days)
+	}
+	if cfg.Calendar != nil {
+		fmt.Printf("Calendar: %s focus events (keyword %q)\n", cfg.Calendar.Provider, cfg.Calendar.GetKeyword())
+	}
+
+	now := time.Now()
+	var activeSchedule string
+	for _, name := range cfg.ScheduleNames()[1:] {
+		state := "inactive"
+		if cfg.IsScheduleActive(name, now) {
+			state = "active"
+			if activeSchedule == "" {
+				activeSchedule = name
+			}
+		}
+		fmt.Printf("Schedule %s: %s (%s)\n", name, strings.Join(cfg.Schedules[name], "; "), state)
+	}
+
+	fmt.Printf("Strictness: %s\n", cfg.GetStrictness())
 
-	withinWorkHours := cfg.IsWithinWorkHours()
+	withinWorkHours := cfg.IsWithinWorkHours(now)
 
 	// Check daemon status
 	svc, err := service.New()
@@ -39,6 +69,9 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	}
 	status, _ := svc.Status()
 	daemonRunning := status == kardianos.StatusRunning
+	if svc != nil && svc.Homebrew() {
+		fmt.Println("Service: Homebrew ('brew services' and configlock start/stop manage the same service)")
+	}
 
 	if daemonRunning {
 		if withinWorkHours {
@@ -54,6 +87,28 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Explain the current state, which a named schedule may override
+	reason := cfg.LockReason(now)
+	if !cfg.IsScheduleActive(config.DefaultSchedule, now) && activeSchedule != "" {
+		reason = fmt.Sprintf("schedule %s is active (default: %s)", activeSchedule, reason)
+	}
+	fmt.Printf("Reason: %s\n", reason)
+	if next, ok := cfg.NextTransition(now); ok {
+		change := "locks"
+		if withinWorkHours {
+			change = "unlocks"
+		}
+		fmt.Printf("Next change: %s at %s (in %s)\n", change, next.Format("Mon 15:04"), formatDuration(next.Sub(now)))
+	}
+
+	if daemonRunning {
+		printEnforcementHealth(now)
+	}
+
+	if executesAt, pending := cfg.PanicExecutesAt(); pending {
+		fmt.Printf("Emergency unlock: pending, executes in %s\n", formatDuration(max(time.Until(executesAt), 0)))
+	}
+
 	fmt.Println()
 
 	// Locked paths
@@ -67,14 +122,73 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		fmt.Printf("Active Temporary Unlocks: %d\n", len(cfg.TempExcludes))
 		for path, expiryStr := range cfg.TempExcludes {
 			if expiry, err := time.Parse(time.RFC3339, expiryStr); err == nil {
-				fmt.Printf("  - %s (expires in %s)\n", path, formatDuration(time.Until(expiry)))
+				scope := ""
+				if only := cfg.TempExcludeScope(path); len(only) > 0 {
+					scope = fmt.Sprintf(" only %s", strings.Join(only, ", "))
+				}
+				fmt.Printf("  - %s%s (expires in %s)\n", path, scope, formatDuration(time.Until(expiry)))
+				if reason := cfg.TempExcludeReason(path); reason != "" {
+					fmt.Printf("    Reason: %s\n", reason)
+				}
 			}
 		}
 	}
 
+	if latest, ok := upgrade.Available(GetVersion()); ok {
+		fmt.Println()
+		fmt.Printf("Update: configlock %s is available (current: %s)\n", latest, GetVersion())
+		fmt.Printf("- %s\n", upgrade.Instructions)
+	}
+
 	return nil
 }
 
+// printEnforcementHealth shows what the daemon's last sweep actually
+// achieved, as reported over its control socket
+func printEnforcementHealth(now time.Time) {
+	var status ipc.Status
+	if err := ipc.Query(ipc.CommandStatus, &status); err != nil {
+		fmt.Printf("Enforcement: ⚠ Unable to query daemon (%v)\n", err)
+		return
+	}
+	if status.LastEnforced.IsZero() {
+		return
+	}
+
+	locked, total, excluded := 0, 0, 0
+	var failing, busy []string
+	for path, health := range status.Health {
+		locked += health.Locked
+		total += health.Locked + health.Failed + len(health.Busy)
+		excluded += health.Excluded
+		busy = append(busy, health.Busy...)
+		if health.Error != "" {
+			failing = append(failing, path)
+		}
+	}
+	fmt.Printf("Enforcement: last sweep %s ago, %d/%d file(s) locked",
+		formatDuration(now.Sub(status.LastEnforced)), locked, total)
+	if excluded > 0 {
+		fmt.Printf(" (%d excluded)", excluded)
+	}
+	if status.SweepEvery > 0 {
+		fmt.Printf(", sweeping every %s", formatDuration(status.SweepEvery))
+	}
+	fmt.Println()
+
+	sort.Strings(failing)
+	for _, path := range failing {
+		fmt.Printf("  ⚠ %s: %s\n", path, status.Health[path].Error)
+	}
+	sort.Strings(busy)
+	for _, file := range busy {
+		fmt.Printf("  ⚠ %s: open for writing, locked once it is closed\n", file)
+	}
+	for _, path := range status.SweepOnly {
+		fmt.Printf("  ⚠ %s: not watched (watch limit reached), changes are caught by the sweep only; see the log\n", path)
+	}
+}
+
 // formatDuration formats a duration in a human-readable way
 func formatDuration(d time.Duration) string {
 	if d < time.Minute {
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/baggiiiie/configlock/internal/challenge"
 	"github.com/baggiiiie/configlock/internal/config"
@@ -29,6 +30,15 @@ func init() {
 	rootCmd.AddCommand(stopCmd)
 }
 
+// requireStopAuthorization gates stopping configlock: during lock hours a
+// configured partner-held passphrase replaces the typing challenge
+func requireStopAuthorization(cfg *config.Config) error {
+	if cfg.StopPassphraseHash != "" && cfg.IsWithinWorkHours(time.Now()) {
+		return challenge.RequirePassphrase(cfg.StopPassphraseHash)
+	}
+	return requireChallenge(cfg)
+}
+
 func runStop(cmd *cobra.Command, args []string) error {
 	// Load config
 	cfg, err := config.Load()
@@ -36,6 +46,10 @@ func runStop(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	if err := cfg.CheckEscapeHatch(config.HatchStop); err != nil {
+		return err
+	}
+
 	if len(cfg.LockedPaths) == 0 {
 		fmt.Println("No paths are currently locked.")
 		fmt.Println("\nChecking daemon status...")
@@ -63,13 +77,14 @@ func runStop(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Println()
 
-	// Run typing challenge
-	if err := challenge.Require("challenge failed"); err != nil {
+	if err := requireStopAuthorization(cfg); err != nil {
 		return err
 	}
 
 	fmt.Println()
 
+	takeSnapshots(cfg, cfg.LockedPaths, "stop")
+
 	// Stop the daemon first
 	fmt.Println("Stopping daemon...")
 	svc, err := service.New()
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var strictnessCmd = &cobra.Command{
+	Use:   "strictness [level]",
+	Short: "Show or change the strictness level",
+	Long: `Show or change the strictness level, which controls which escape hatches
+exist during lock hours:
+
+  easy     temp-unlock does not require the typing challenge
+  normal   every escape hatch requires the typing challenge (default)
+  hard     'stop' is disabled during lock hours
+  nuclear  'stop', 'rm' and 'edit time' are disabled during lock hours
+
+The strictness level can only be changed outside lock hours.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runStrictness,
+}
+
+func init() {
+	rootCmd.AddCommand(strictnessCmd)
+}
+
+func runStrictness(cmd *cobra.Command, args []string) error {
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if len(args) == 0 {
+		fmt.Printf("Strictness: %s\n", cfg.GetStrictness())
+		return nil
+	}
+
+	level := args[0]
+	if err := config.ValidateStrictness(level); err != nil {
+		return err
+	}
+
+	if cfg.IsWithinWorkHours(time.Now()) {
+		return fmt.Errorf("strictness can only be changed outside lock hours")
+	}
+
+	if _, err := config.Update(func(latest *config.Config) error {
+		latest.Strictness = level
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	fmt.Printf("✓ Strictness set to: %s\n", level)
+	return nil
+}
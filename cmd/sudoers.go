@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/daemon"
+	"github.com/baggiiiie/configlock/internal/locker"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sudoersUser   string
+	sudoersOutput string
+)
+
+var sudoersCmd = &cobra.Command{
+	Use:   "sudoers",
+	Short: "Set up sudo for lock operations that need root",
+}
+
+var sudoersGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Print sudoers rules allowing only the lock operations configlock needs",
+	Long: `Print a sudoers snippet that lets the daemon run exactly the lock tool
+invocations it needs without a password: chattr +i/-i (chflags schg/noschg on
+macOS) on each file of the locked paths, and chattr +a/-a on its logs. Nothing
+else is allowed through sudo, so files such as those in /etc can be locked by
+a daemon running as you without giving it broad sudo access.
+
+The rules name each file, so regenerate them after adding locked paths or
+files. Install them with:
+
+  configlock sudoers generate -o configlock.sudoers
+  sudo visudo -cf configlock.sudoers
+  sudo install -m 0440 -o root configlock.sudoers /etc/sudoers.d/configlock
+
+and set "elevation": "sudo" in config.json if lock tools don't run through
+sudo yet.`,
+	Args: cobra.NoArgs,
+	RunE: runSudoersGenerate,
+}
+
+func init() {
+	rootCmd.AddCommand(sudoersCmd)
+	sudoersCmd.AddCommand(sudoersGenerateCmd)
+	sudoersGenerateCmd.Flags().StringVar(&sudoersUser, "user", "", "User the rules are for (default: the current user)")
+	sudoersGenerateCmd.Flags().StringVarP(&sudoersOutput, "output", "o", "", "Write the rules to a file instead of stdout")
+}
+
+func runSudoersGenerate(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	name := sudoersUser
+	if name == "" {
+		current, err := user.Current()
+		if err != nil {
+			return fmt.Errorf("failed to get current user: %w", err)
+		}
+		name = current.Username
+	}
+
+	// Every file a lock sweep passes to the lock tool, directories included
+	var files []string
+	for _, path := range cfg.LockedPaths {
+		covered, err := locker.CoveredFiles(context.Background(), path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", path, err)
+			continue
+		}
+		files = append(files, covered...)
+		// Directories are locked themselves after their contents
+		if realPath, err := filepath.EvalSymlinks(path); err == nil {
+			if info, err := os.Stat(realPath); err == nil && info.IsDir() {
+				files = append(files, realPath)
+			}
+		}
+	}
+	slices.Sort(files)
+	files = slices.Compact(files)
+
+	rules, skipped, err := locker.SudoersRules(name, files, daemon.AppendOnlyFiles())
+	if err != nil {
+		return fmt.Errorf("failed to generate sudoers rules: %w", err)
+	}
+	for _, file := range skipped {
+		fmt.Fprintf(os.Stderr, "Warning: %s contains whitespace, which sudoers rules can't match; lock it with the chmod fallback or rename it\n", file)
+	}
+
+	if sudoersOutput == "" {
+		fmt.Print(rules)
+	} else {
+		if err := os.WriteFile(sudoersOutput, []byte(rules), 0o440); err != nil {
+			return fmt.Errorf("failed to write %s: %w", sudoersOutput, err)
+		}
+		fmt.Printf("✓ Wrote sudoers rules for %d file(s) to %s\n", len(files)-len(skipped), sudoersOutput)
+		fmt.Printf("Check and install them with:\n  sudo visudo -cf %s\n  sudo install -m 0440 -o root %s /etc/sudoers.d/configlock\n", sudoersOutput, sudoersOutput)
+	}
+	if cfg.Elevation != locker.ElevationSudo {
+		fmt.Fprintln(os.Stderr, "Note: lock tools don't run through sudo yet; set \"elevation\": \"sudo\" in config.json to use the rules.")
+	}
+	return nil
+}
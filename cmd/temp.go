@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/ipc"
+	"github.com/baggiiiie/configlock/internal/locker"
+	"github.com/spf13/cobra"
+)
+
+var tempCmd = &cobra.Command{
+	Use:   "temp",
+	Short: "Manage temporary unlocks",
+}
+
+var tempListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List active temporary unlocks with their remaining time",
+	Args:  cobra.NoArgs,
+	RunE:  runTempList,
+}
+
+var tempCancelCmd = &cobra.Command{
+	Use:   "cancel <path|index>",
+	Short: "End a temporary unlock early and re-lock the path",
+	Long: `End a temporary unlock before it expires and re-lock the path right away,
+for when you're done editing early. Use --all to end every temporary unlock.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if tempCancelAll {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	RunE: runTempCancel,
+}
+
+var tempCancelAll bool
+
+func init() {
+	rootCmd.AddCommand(tempCmd)
+	tempCmd.AddCommand(tempListCmd)
+	tempCmd.AddCommand(tempCancelCmd)
+	tempCancelCmd.Flags().BoolVar(&tempCancelAll, "all", false, "End every temporary unlock")
+}
+
+func runTempList(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	paths := cfg.ActiveExcludes()
+	if len(paths) == 0 {
+		fmt.Println("No active temporary unlocks.")
+		return nil
+	}
+	slices.Sort(paths)
+
+	fmt.Printf("Temporary Unlocks (%d):\n\n", len(paths))
+	for _, path := range paths {
+		expiry, err := time.Parse(time.RFC3339, cfg.TempExcludes[path])
+		if err != nil {
+			continue
+		}
+		fmt.Printf("  %s\n", path)
+		fmt.Printf("    Re-locks at %s (in %s)\n", expiry.Local().Format("15:04"), formatDuration(time.Until(expiry)))
+		if only := cfg.TempExcludeScope(path); len(only) > 0 {
+			fmt.Printf("    Only: %s\n", strings.Join(only, ", "))
+		}
+		if reason := cfg.TempExcludeReason(path); reason != "" {
+			fmt.Printf("    Reason: %s\n", reason)
+		}
+	}
+	return nil
+}
+
+func runTempCancel(cmd *cobra.Command, args []string) error {
+	req := ipc.Request{Command: ipc.CommandTempCancel, All: tempCancelAll}
+	if !tempCancelAll {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		absPath, err := resolveLockedPath(cfg, args[0])
+		if err != nil {
+			return err
+		}
+		req.Path = absPath
+	}
+
+	// The daemon drops the exclusion and re-locks the path itself
+	var result ipc.TempCancel
+	err := ipc.Send(req, &result)
+	if errors.Is(err, ipc.ErrNotRunning) {
+		result.Paths, err = cancelTempUnlockWithoutDaemon(req)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(result.Paths) == 0 {
+		fmt.Println("No active temporary unlocks.")
+		return nil
+	}
+	for _, path := range result.Paths {
+		fmt.Printf("✓ Temporary unlock ended, re-locked: %s\n", path)
+	}
+	return nil
+}
+
+// cancelTempUnlockWithoutDaemon removes the exclusions and re-locks the paths
+// directly when the daemon can't be reached
+func cancelTempUnlockWithoutDaemon(req ipc.Request) ([]string, error) {
+	var paths []string
+	cfg, err := config.Update(func(latest *config.Config) error {
+		paths = latest.ActiveExcludes()
+		if !req.All {
+			if !slices.Contains(paths, req.Path) {
+				return fmt.Errorf("%s is not temporarily unlocked", req.Path)
+			}
+			paths = []string{req.Path}
+		}
+		for _, path := range paths {
+			latest.RemoveTempExclude(path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, path := range paths {
+		if !cfg.IsPathActive(path, now) {
+			continue
+		}
+		if err := locker.Lock(path); err != nil {
+			fmt.Printf("Warning: failed to lock %s: %v\n", path, err)
+		}
+	}
+	return paths, nil
+}
@@ -1,23 +1,45 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
-	"github.com/baggiiiie/configlock/internal/challenge"
 	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/ipc"
 	"github.com/baggiiiie/configlock/internal/locker"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
-var duration int
+var (
+	duration         int
+	tempUnlockUntil  string
+	tempUnlockOnly   []string
+	tempUnlockReason string
+)
 
 var tempUnlockCmd = &cobra.Command{
-	Use:   "temp-unlock <path>",
+	Use:   "temp-unlock <path|index>",
 	Short: "Temporarily unlock a file or directory",
-	Long: `Temporarily unlock a file or directory for a specified duration.
-This requires completing a typing challenge to prevent impulsive actions.`,
+	Long: `Temporarily unlock a file or directory for a specified duration, or
+until a time of day later today with --until (e.g. --until 17:00). This
+requires completing a typing challenge to prevent impulsive actions.
+
+For a locked directory, --only limits the unlock to the files matching a glob
+relative to the directory, e.g. --only 'lua/plugins/*.lua'. A pattern naming
+a subdirectory covers everything below it. Repeat --only for more patterns.
+
+The path can also be given as its number in 'configlock list'.
+
+During lock hours a reason is required, given with --reason or typed when
+asked. It is kept with the unlock and shown by 'configlock status' and in
+reports.`,
 	Args: cobra.ExactArgs(1),
 	RunE: runTempUnlock,
 }
@@ -25,34 +47,34 @@ This requires completing a typing challenge to prevent impulsive actions.`,
 func init() {
 	rootCmd.AddCommand(tempUnlockCmd)
 	tempUnlockCmd.Flags().IntVar(&duration, "duration", 0, "Duration in minutes (0 = use config default)")
+	tempUnlockCmd.Flags().StringVar(&tempUnlockUntil, "until", "", "Re-lock at this time of day (e.g. 17:00 or 1700)")
+	tempUnlockCmd.MarkFlagsMutuallyExclusive("duration", "until")
+	tempUnlockCmd.Flags().StringArrayVar(&tempUnlockOnly, "only", nil, "Only unlock files of the directory matching this glob (repeatable)")
+	tempUnlockCmd.Flags().StringVar(&tempUnlockReason, "reason", "", "Why the path needs unlocking (required during lock hours)")
 }
 
 func runTempUnlock(cmd *cobra.Command, args []string) error {
-	path := args[0]
-
-	// Resolve to absolute path
-	absPath, err := filepath.Abs(path)
-	if err != nil {
-		return fmt.Errorf("failed to resolve path: %w", err)
-	}
-
 	// Load config
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	// Check if path exists in config
-	found := false
-	for _, p := range cfg.LockedPaths {
-		if p == absPath {
-			found = true
-			break
-		}
+	absPath, err := resolveLockedPath(cfg, args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.RequireLockedPath(absPath); err != nil {
+		return err
+	}
+	if err := validateOnly(absPath, tempUnlockOnly); err != nil {
+		return err
 	}
 
-	if !found {
-		return fmt.Errorf("path not found in lock list: %s", absPath)
+	reason, err := askReason(cfg, absPath)
+	if err != nil {
+		return err
 	}
 
 	// Use config default if duration not specified
@@ -60,33 +82,137 @@ func runTempUnlock(cmd *cobra.Command, args []string) error {
 	if unlockDuration == 0 {
 		unlockDuration = cfg.TempDuration
 	}
-
-	// Run typing challenge
-	if err := challenge.Require("challenge failed"); err != nil {
-		return err
+	if time.Duration(unlockDuration)*time.Minute > config.MaxTempUnlock {
+		return fmt.Errorf("--duration can be at most %d minutes", int(config.MaxTempUnlock.Minutes()))
+	}
+	span := fmt.Sprintf("for %d minutes", unlockDuration)
+	var until time.Time
+	if tempUnlockUntil != "" {
+		if until, err = config.ParseUntil(tempUnlockUntil, time.Now()); err != nil {
+			return err
+		}
+		span = "until " + until.Format("15:04")
 	}
 
-	// Add temporary exclusion for the path
-	cfg.AddTempExclude(absPath, unlockDuration)
-
-	// Save config
-	if err := cfg.Save(); err != nil {
-		return fmt.Errorf("failed to save config: %w", err)
+	// Run typing challenge unless strictness is set to easy. The token
+	// proves to the daemon that it was passed.
+	var token string
+	if cfg.GetStrictness() != config.StrictnessEasy {
+		if err := requireChallenge(cfg); err != nil {
+			return err
+		}
+		if token, err = config.IssueChallengeToken(); err != nil {
+			return err
+		}
 	}
 
-	// Unlock the path immediately (locker will handle directories recursively)
+	takeSnapshots(cfg, []string{absPath}, "temp-unlock")
+
+	// The daemon records the exclusion, unlocks the path and re-locks it
+	// exactly when the exclusion expires
 	fmt.Println("Unlocking path...")
-	if err := locker.Unlock(absPath); err != nil {
-		fmt.Printf("Warning: failed to unlock %s: %v\n", absPath, err)
+	req := ipc.Request{
+		Command: ipc.CommandTempUnlock,
+		Path:    absPath,
+		Minutes: unlockDuration,
+		Only:    tempUnlockOnly,
+		Reason:  reason,
+		Until:   until,
+		Token:   token,
+	}
+	var result ipc.TempUnlock
+	err = ipc.Send(req, &result)
+	if errors.Is(err, ipc.ErrNotRunning) {
+		err = tempUnlockWithoutDaemon(req)
+	}
+	if err != nil {
+		return err
 	}
 
 	// Check if it's a file or directory for display purposes
 	info, err := os.Stat(absPath)
-	if err == nil && info.IsDir() {
-		fmt.Printf("✓ Temporarily unlocked directory for %d minutes: %s\n", unlockDuration, absPath)
+	if len(tempUnlockOnly) > 0 {
+		fmt.Printf("✓ Temporarily unlocked files matching %s %s in: %s\n", strings.Join(tempUnlockOnly, ", "), span, absPath)
+	} else if err == nil && info.IsDir() {
+		fmt.Printf("✓ Temporarily unlocked directory %s: %s\n", span, absPath)
 	} else {
-		fmt.Printf("✓ Temporarily unlocked file for %d minutes: %s\n", unlockDuration, absPath)
+		fmt.Printf("✓ Temporarily unlocked file %s: %s\n", span, absPath)
+	}
+	if !result.ExpiresAt.IsZero() && until.IsZero() {
+		fmt.Printf("  Re-locks at %s\n", result.ExpiresAt.Local().Format("15:04"))
+	}
+
+	return nil
+}
+
+// validateOnly checks that --only patterns are valid globs given for a directory
+func validateOnly(absPath string, patterns []string) error {
+	if len(patterns) == 0 {
+		return nil
+	}
+	if info, err := os.Stat(absPath); err != nil || !info.IsDir() {
+		return fmt.Errorf("--only can only be used with a directory")
+	}
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid --only pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// askReason returns the reason given with --reason or asks for one on the
+// terminal. During lock hours a reason of at least config.MinReasonLength
+// characters is required.
+func askReason(cfg *config.Config, absPath string) (string, error) {
+	reason := strings.TrimSpace(tempUnlockReason)
+	if !cfg.IsPathActive(absPath, time.Now()) {
+		return reason, nil
 	}
 
+	if reason == "" && term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Print("Why do you need to unlock this now? ")
+		input, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return "", fmt.Errorf("failed to read reason: %w", err)
+		}
+		reason = strings.TrimSpace(input)
+	}
+	if reason == "" {
+		return "", fmt.Errorf("a reason is required during lock hours, use --reason")
+	}
+	if len(reason) < config.MinReasonLength {
+		return "", fmt.Errorf("reason is too short, write at least %d characters", config.MinReasonLength)
+	}
+	return reason, nil
+}
+
+// tempUnlockWithoutDaemon records the exclusion and unlocks the path directly
+// when the daemon can't be reached
+func tempUnlockWithoutDaemon(req ipc.Request) error {
+	absPath, only := req.Path, req.Only
+	if _, err := config.Update(func(latest *config.Config) error {
+		if req.Until.IsZero() {
+			latest.AddTempExclude(absPath, req.Minutes)
+		} else {
+			latest.AddTempExcludeUntil(absPath, req.Until)
+		}
+		latest.SetTempExcludeScope(absPath, only)
+		latest.SetTempExcludeReason(absPath, req.Reason)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	// Locker will handle directories recursively
+	unlock := locker.Unlock
+	if len(only) > 0 {
+		unlock = func(path string) error {
+			return locker.UnlockScope(context.Background(), path, only)
+		}
+	}
+	if err := unlock(absPath); err != nil {
+		fmt.Printf("Warning: failed to unlock %s: %v\n", absPath, err)
+	}
 	return nil
 }
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/baggiiiie/configlock/internal/tray"
+	"github.com/spf13/cobra"
+)
+
+var trayCmd = &cobra.Command{
+	Use:   "tray",
+	Short: "Show lock state in the menubar / system tray",
+	Long: `Run a small menubar (macOS) or system tray (Linux, Windows) companion.
+
+It shows whether locks are enforced and the time until the next transition,
+and offers status and temp-unlock actions. Those open a terminal running the
+regular CLI, so the typing challenge still applies. Requires a running daemon.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return tray.Run()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(trayCmd)
+}
@@ -2,21 +2,111 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
 
+	"github.com/baggiiiie/configlock/internal/locker"
 	"github.com/spf13/cobra"
 )
 
+// Set at build time with -ldflags "-X github.com/baggiiiie/configlock/cmd.commit=..."
+var (
+	commit    = ""
+	buildDate = ""
+)
+
+var versionVerbose bool
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Show version information",
-	Long:  `Display the current version of ConfigLock.`,
-	Run:   runVersion,
+	Long: `Display the current version of ConfigLock.
+
+With --verbose, also show the build details and the lock backend detected for
+this machine, which are useful in bug reports.`,
+	Run: runVersion,
 }
 
 func init() {
 	rootCmd.AddCommand(versionCmd)
+	versionCmd.Flags().BoolVar(&versionVerbose, "verbose", false, "Show build details and the detected lock backend")
 }
 
 func runVersion(cmd *cobra.Command, args []string) {
 	fmt.Printf("configlock version %s\n", GetVersion())
+	if !versionVerbose {
+		return
+	}
+
+	buildCommit, date := buildVCSInfo()
+	fmt.Printf("  Commit:       %s\n", buildCommit)
+	fmt.Printf("  Build date:   %s\n", date)
+	fmt.Printf("  Go version:   %s\n", runtime.Version())
+	fmt.Printf("  Platform:     %s/%s\n", runtime.GOOS, runtime.GOARCH)
+
+	home, _ := os.UserHomeDir()
+	fsType, strategy := locker.DetectStrategy(home)
+	fmt.Printf("  Lock backend: %s (%s on %s filesystem)\n", strategy, lockTool(strategy), fsType)
+}
+
+// buildVCSInfo returns the commit and build date from ldflags, falling back
+// to the VCS information Go embeds when building from a checkout
+func buildVCSInfo() (string, string) {
+	buildCommit, date := commit, buildDate
+	if info, ok := debug.ReadBuildInfo(); ok {
+		modified := false
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if buildCommit == "" {
+					buildCommit = setting.Value
+				}
+			case "vcs.time":
+				if date == "" {
+					date = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if modified && commit == "" && buildCommit != "" {
+			buildCommit += " (modified)"
+		}
+	}
+
+	if buildCommit == "" {
+		buildCommit = "unknown"
+	}
+	if date == "" {
+		date = "unknown"
+	}
+	return buildCommit, date
+}
+
+// lockTool names the system tool behind a lock strategy on this platform
+func lockTool(strategy string) string {
+	switch strategy {
+	case locker.StrategyChmod:
+		return "chmod"
+	case locker.StrategyImmutable:
+		switch runtime.GOOS {
+		case "linux":
+			return "chattr +i"
+		case "darwin":
+			return "chflags uchg"
+		}
+	case locker.StrategyACL:
+		switch runtime.GOOS {
+		case "linux":
+			return "setfacl"
+		case "darwin":
+			return "chmod +a"
+		}
+	case locker.StrategyBindRO:
+		if runtime.GOOS == "linux" {
+			return "mount --bind -o ro"
+		}
+	}
+	return "unsupported"
 }
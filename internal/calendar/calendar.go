@@ -0,0 +1,200 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/schedule"
+)
+
+// lookahead is how far ahead focus events are fetched
+const lookahead = 7 * 24 * time.Hour
+
+// Sync fetches focus events from the configured calendar and stores them as
+// lock blocks for the config to pick up
+func Sync(settings *config.CalendarSettings) (schedule.Blocks, error) {
+	token, err := accessToken(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	from := time.Now()
+	to := from.Add(lookahead)
+
+	var blocks schedule.Blocks
+	switch settings.Provider {
+	case config.CalendarGoogle:
+		blocks, err = fetchGoogle(token, from, to, settings.GetKeyword())
+	case config.CalendarMicrosoft:
+		blocks, err = fetchMicrosoft(token, from, to, settings.GetKeyword())
+	default:
+		return nil, fmt.Errorf("unknown calendar provider: %q", settings.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := schedule.SaveBlocks(config.GetCalendarCachePath(), blocks); err != nil {
+		return nil, fmt.Errorf("failed to save focus blocks: %w", err)
+	}
+	return blocks, nil
+}
+
+// isFocus reports whether an event title or category carries the keyword
+func isFocus(keyword, title string, categories []string) bool {
+	keyword = strings.ToLower(keyword)
+	if strings.Contains(strings.ToLower(title), keyword) {
+		return true
+	}
+	return slices.ContainsFunc(categories, func(c string) bool {
+		return strings.EqualFold(c, keyword)
+	})
+}
+
+// getJSON performs an authorized GET and decodes the JSON response into v
+func getJSON(endpoint, token string, header map[string]string, v any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch calendar: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected calendar status: %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse calendar response: %w", err)
+	}
+	return nil
+}
+
+// googleEvents is the subset of the Google Calendar events list response used here
+type googleEvents struct {
+	Items []struct {
+		Summary   string `json:"summary"`
+		EventType string `json:"eventType"`
+		Start     struct {
+			DateTime time.Time `json:"dateTime"`
+		} `json:"start"`
+		End struct {
+			DateTime time.Time `json:"dateTime"`
+		} `json:"end"`
+	} `json:"items"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// fetchGoogle lists focus events from the primary Google calendar. Google's
+// own "Focus time" events count regardless of their title.
+func fetchGoogle(token string, from, to time.Time, keyword string) (schedule.Blocks, error) {
+	var blocks schedule.Blocks
+	pageToken := ""
+	for {
+		query := url.Values{
+			"timeMin":      {from.Format(time.RFC3339)},
+			"timeMax":      {to.Format(time.RFC3339)},
+			"singleEvents": {"true"},
+			"orderBy":      {"startTime"},
+		}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+
+		var events googleEvents
+		endpoint := "https://www.googleapis.com/calendar/v3/calendars/primary/events?" + query.Encode()
+		if err := getJSON(endpoint, token, nil, &events); err != nil {
+			return nil, err
+		}
+
+		for _, item := range events.Items {
+			// All-day events have no dateTime
+			if item.Start.DateTime.IsZero() || item.End.DateTime.IsZero() {
+				continue
+			}
+			if item.EventType == "focusTime" || isFocus(keyword, item.Summary, nil) {
+				blocks = append(blocks, schedule.Block{Title: item.Summary, Start: item.Start.DateTime, End: item.End.DateTime})
+			}
+		}
+
+		if events.NextPageToken == "" {
+			return blocks, nil
+		}
+		pageToken = events.NextPageToken
+	}
+}
+
+// microsoftEvents is the subset of the Microsoft Graph calendarView response used here
+type microsoftEvents struct {
+	Value []struct {
+		Subject    string   `json:"subject"`
+		Categories []string `json:"categories"`
+		IsAllDay   bool     `json:"isAllDay"`
+		Start      struct {
+			DateTime string `json:"dateTime"`
+		} `json:"start"`
+		End struct {
+			DateTime string `json:"dateTime"`
+		} `json:"end"`
+	} `json:"value"`
+	NextLink string `json:"@odata.nextLink"`
+}
+
+// graphTimeLayout is the format of Graph dateTime values, which carry no zone
+const graphTimeLayout = "2006-01-02T15:04:05.9999999"
+
+// fetchMicrosoft lists focus events from the user's Outlook calendar
+func fetchMicrosoft(token string, from, to time.Time, keyword string) (schedule.Blocks, error) {
+	query := url.Values{
+		"startDateTime": {from.UTC().Format(time.RFC3339)},
+		"endDateTime":   {to.UTC().Format(time.RFC3339)},
+		"$select":       {"subject,categories,isAllDay,start,end"},
+		"$top":          {"100"},
+	}
+	// Ask for UTC so the zone-less dateTime values can be parsed as UTC
+	header := map[string]string{"Prefer": `outlook.timezone="UTC"`}
+
+	var blocks schedule.Blocks
+	endpoint := "https://graph.microsoft.com/v1.0/me/calendarView?" + query.Encode()
+	for endpoint != "" {
+		var events microsoftEvents
+		if err := getJSON(endpoint, token, header, &events); err != nil {
+			return nil, err
+		}
+
+		for _, item := range events.Value {
+			if item.IsAllDay || !isFocus(keyword, item.Subject, item.Categories) {
+				continue
+			}
+			start, err := time.Parse(graphTimeLayout, item.Start.DateTime)
+			if err != nil {
+				continue
+			}
+			end, err := time.Parse(graphTimeLayout, item.End.DateTime)
+			if err != nil {
+				continue
+			}
+			blocks = append(blocks, schedule.Block{Title: item.Subject, Start: start, End: end})
+		}
+
+		endpoint = events.NextLink
+	}
+	return blocks, nil
+}
@@ -0,0 +1,250 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+)
+
+const requestTimeout = 15 * time.Second
+
+// endpoints holds a provider's OAuth device flow endpoints and scope
+type endpoints struct {
+	deviceURL string
+	tokenURL  string
+	scope     string
+}
+
+var providerEndpoints = map[string]endpoints{
+	config.CalendarGoogle: {
+		deviceURL: "https://oauth2.googleapis.com/device/code",
+		tokenURL:  "https://oauth2.googleapis.com/token",
+		scope:     "https://www.googleapis.com/auth/calendar.readonly",
+	},
+	config.CalendarMicrosoft: {
+		deviceURL: "https://login.microsoftonline.com/common/oauth2/v2.0/devicecode",
+		tokenURL:  "https://login.microsoftonline.com/common/oauth2/v2.0/token",
+		scope:     "offline_access Calendars.Read",
+	},
+}
+
+// Token is a stored OAuth token
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// tokenResponse is the token endpoint response, including device flow errors
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	Error        string `json:"error"`
+	Description  string `json:"error_description"`
+}
+
+// DeviceCode is the code the user enters at VerificationURL to authorize access
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURL string `json:"verification_url"` // Google
+	VerificationURI string `json:"verification_uri"` // Microsoft
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// URL returns the page where the user enters the code
+func (d *DeviceCode) URL() string {
+	if d.VerificationURL != "" {
+		return d.VerificationURL
+	}
+	return d.VerificationURI
+}
+
+// getEndpoints returns the OAuth endpoints for the configured provider
+func getEndpoints(settings *config.CalendarSettings) (endpoints, error) {
+	ep, ok := providerEndpoints[settings.Provider]
+	if !ok {
+		return endpoints{}, fmt.Errorf("unknown calendar provider: %q (must be %s or %s)",
+			settings.Provider, config.CalendarGoogle, config.CalendarMicrosoft)
+	}
+	if settings.ClientID == "" {
+		return endpoints{}, fmt.Errorf("calendar client_id is not set in config")
+	}
+	return ep, nil
+}
+
+// postForm sends a form POST and decodes the JSON response into v,
+// regardless of status code since OAuth errors are reported in the body
+func postForm(endpoint string, form url.Values, v any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse response (status %d): %w", resp.StatusCode, err)
+	}
+	return nil
+}
+
+// StartLogin requests a device code for read-only calendar access
+func StartLogin(settings *config.CalendarSettings) (*DeviceCode, error) {
+	ep, err := getEndpoints(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var code DeviceCode
+	form := url.Values{"client_id": {settings.ClientID}, "scope": {ep.scope}}
+	if err := postForm(ep.deviceURL, form, &code); err != nil {
+		return nil, fmt.Errorf("failed to request device code: %w", err)
+	}
+	if code.DeviceCode == "" {
+		return nil, fmt.Errorf("failed to request device code: no code returned (check client_id)")
+	}
+	return &code, nil
+}
+
+// FinishLogin polls until the user has authorized the device code, then
+// stores the token
+func FinishLogin(settings *config.CalendarSettings, code *DeviceCode) error {
+	ep, err := getEndpoints(settings)
+	if err != nil {
+		return err
+	}
+
+	interval := time.Duration(max(code.Interval, 5)) * time.Second
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+	form := url.Values{
+		"client_id":   {settings.ClientID},
+		"device_code": {code.DeviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+	if settings.ClientSecret != "" {
+		form.Set("client_secret", settings.ClientSecret)
+	}
+
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+
+		var resp tokenResponse
+		if err := postForm(ep.tokenURL, form, &resp); err != nil {
+			return fmt.Errorf("failed to poll for token: %w", err)
+		}
+		switch resp.Error {
+		case "":
+			return saveToken(tokenFromResponse(resp, ""))
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += 5 * time.Second
+			continue
+		default:
+			return fmt.Errorf("authorization failed: %s %s", resp.Error, resp.Description)
+		}
+	}
+	return fmt.Errorf("authorization timed out, run 'configlock calendar login' again")
+}
+
+// tokenFromResponse converts a token response, keeping the previous refresh
+// token if the provider did not issue a new one
+func tokenFromResponse(resp tokenResponse, refreshToken string) *Token {
+	if resp.RefreshToken != "" {
+		refreshToken = resp.RefreshToken
+	}
+	return &Token{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: refreshToken,
+		Expiry:       time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second),
+	}
+}
+
+// accessToken returns a valid access token, refreshing it if it has expired
+func accessToken(settings *config.CalendarSettings) (string, error) {
+	token, err := loadToken()
+	if err != nil {
+		return "", err
+	}
+	if time.Now().Add(time.Minute).Before(token.Expiry) {
+		return token.AccessToken, nil
+	}
+
+	ep, err := getEndpoints(settings)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"client_id":     {settings.ClientID},
+		"refresh_token": {token.RefreshToken},
+		"grant_type":    {"refresh_token"},
+	}
+	if settings.ClientSecret != "" {
+		form.Set("client_secret", settings.ClientSecret)
+	}
+
+	var resp tokenResponse
+	if err := postForm(ep.tokenURL, form, &resp); err != nil {
+		return "", fmt.Errorf("failed to refresh token: %w", err)
+	}
+	if resp.Error != "" {
+		return "", fmt.Errorf("failed to refresh token: %s %s", resp.Error, resp.Description)
+	}
+
+	refreshed := tokenFromResponse(resp, token.RefreshToken)
+	if err := saveToken(refreshed); err != nil {
+		return "", err
+	}
+	return refreshed.AccessToken, nil
+}
+
+// loadToken reads the stored token
+func loadToken() (*Token, error) {
+	data, err := os.ReadFile(config.GetCalendarTokenPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("not logged in, run 'configlock calendar login' first")
+		}
+		return nil, fmt.Errorf("failed to read calendar token: %w", err)
+	}
+
+	var token Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse calendar token: %w", err)
+	}
+	return &token, nil
+}
+
+// saveToken stores the token readable only by the user
+func saveToken(token *Token) error {
+	path := config.GetCalendarTokenPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal calendar token: %w", err)
+	}
+	return os.WriteFile(path, data, 0o600)
+}
@@ -0,0 +1,50 @@
+package challenge
+
+import (
+	"errors"
+	"time"
+)
+
+// Kinds of challenge
+const (
+	KindTyping     = "typing"
+	KindPassphrase = "passphrase"
+)
+
+// Outcomes of a challenge attempt
+const (
+	OutcomePassed  = "passed"
+	OutcomeFailed  = "failed"  // too many incorrect attempts
+	OutcomeAborted = "aborted" // input ended before the challenge was completed
+)
+
+// Attempt describes one run of the typing challenge or passphrase check
+type Attempt struct {
+	Kind     string
+	Outcome  string
+	Retries  int // incorrect entries
+	Duration time.Duration
+}
+
+// recorder is called after every attempt, see SetRecorder
+var recorder func(Attempt)
+
+// SetRecorder registers a function that is called after every challenge
+// attempt, e.g. to keep a history of them
+func SetRecorder(fn func(Attempt)) {
+	recorder = fn
+}
+
+// record reports an attempt that started at start and ended with err
+func record(kind string, start time.Time, retries int, err error) {
+	if recorder == nil {
+		return
+	}
+	outcome := OutcomePassed
+	if errors.Is(err, ErrChallengeFailed) {
+		outcome = OutcomeFailed
+	} else if err != nil {
+		outcome = OutcomeAborted
+	}
+	recorder(Attempt{Kind: kind, Outcome: outcome, Retries: retries, Duration: time.Since(start)})
+}
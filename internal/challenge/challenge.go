@@ -2,11 +2,15 @@ package challenge
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"math/rand"
 	"os"
 	"strings"
 	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
 )
 
 const statement = `I UNDERSTAND THIS ACTION WILL DECREASE,
@@ -16,6 +20,10 @@ AND I AM WILLING TO PROCEED.`
 
 const maxRetriesPerLine = 3
 
+// ErrChallengeFailed is returned when the typing challenge or passphrase
+// check is failed too many times
+var ErrChallengeFailed = errors.New("too many incorrect attempts")
+
 // Require runs the typing challenge and wraps any error with the given context.
 // Use this as a standardized way to require a challenge before dangerous operations.
 func Require(context string) error {
@@ -27,7 +35,15 @@ func Require(context string) error {
 
 // Run executes the typing challenge
 func Run() error {
-	lines := strings.Split(statement, "\n")
+	return runLines(strings.Split(statement, "\n"))
+}
+
+// runLines has the given lines typed one by one
+func runLines(lines []string) (err error) {
+	start := time.Now()
+	totalRetries := 0
+	defer func() { record(KindTyping, start, totalRetries, err) }()
+
 	reader := bufio.NewReader(os.Stdin)
 
 	fmt.Println("\n⚠️  WARNING: You are about to perform an action that may reduce your productivity.")
@@ -61,8 +77,9 @@ func Run() error {
 			}
 
 			retries++
+			totalRetries++
 			if retries >= maxRetriesPerLine {
-				return fmt.Errorf("too many incorrect attempts. Challenge failed")
+				return fmt.Errorf("%w. Challenge failed", ErrChallengeFailed)
 			}
 
 			fmt.Printf("✗ Incorrect. You have %d attempt(s) remaining for this line.\n", maxRetriesPerLine-retries)
@@ -86,3 +103,51 @@ func typewriterEffect(text string) {
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
+
+// HashPassphrase returns a bcrypt hash of the given passphrase for storage in the config
+func HashPassphrase(passphrase string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(passphrase), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash passphrase: %w", err)
+	}
+	return string(hash), nil
+}
+
+// ReadPassphrase prompts for a passphrase without echoing it to the terminal
+func ReadPassphrase(prompt string) (string, error) {
+	fmt.Print(prompt)
+	input, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return string(input), nil
+}
+
+// RequirePassphrase prompts for the passphrase matching the given bcrypt hash.
+// This is an alternative to the typing challenge for a passphrase held by someone else.
+func RequirePassphrase(hash string) (err error) {
+	start := time.Now()
+	retries := 0
+	defer func() { record(KindPassphrase, start, retries, err) }()
+
+	fmt.Println("\n⚠️  WARNING: This action requires the passphrase held by your accountability partner.")
+
+	for ; retries < maxRetriesPerLine; retries++ {
+		input, err := ReadPassphrase("Passphrase: ")
+		if err != nil {
+			return err
+		}
+
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(input)) == nil {
+			fmt.Println("✓ Passphrase accepted.")
+			return nil
+		}
+
+		if remaining := maxRetriesPerLine - retries - 1; remaining > 0 {
+			fmt.Printf("✗ Incorrect. You have %d attempt(s) remaining.\n", remaining)
+		}
+	}
+
+	return fmt.Errorf("%w. Passphrase check failed", ErrChallengeFailed)
+}
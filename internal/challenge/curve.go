@@ -0,0 +1,94 @@
+package challenge
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// shortStatement is typed when only a few minutes of lock time remain
+const shortStatement = "I COULD WAIT A FEW MINUTES, BUT I WON'T."
+
+// paragraphs are typed in order as more lock time remains; the first one is
+// the standard statement
+var paragraphs = []string{
+	statement,
+	`I AM CHOOSING TO TINKER INSTEAD OF WORK.
+THE CONFIG I WANT TO CHANGE WILL STILL BE HERE
+WHEN THE LOCK HOURS END,
+AND SO WILL THE IDEA IF IT IS A GOOD ONE.`,
+	`I SET THESE LOCK HOURS FOR A REASON.
+I KNEW THIS MOMENT WOULD COME,
+AND I ASKED MY FUTURE SELF
+TO WAIT UNTIL THE DAY IS DONE.`,
+	`HOURS OF LOCK TIME REMAIN TODAY.
+I HAVE READ EVERY WORD OF THIS,
+I HAVE TYPED IT ALL BY HAND,
+AND I STILL WANT TO UNLOCK.`,
+}
+
+// Step is a point on the challenge curve: with at most Minutes of lock time
+// remaining, Paragraphs paragraphs are typed (0 = a single short line)
+type Step struct {
+	Minutes    int `json:"minutes"`
+	Paragraphs int `json:"paragraphs"`
+}
+
+// DefaultCurve is used when no curve is configured. With more lock time
+// remaining than its last step, every paragraph is typed.
+var DefaultCurve = []Step{
+	{Minutes: 15, Paragraphs: 0},
+	{Minutes: 120, Paragraphs: 1},
+	{Minutes: 240, Paragraphs: 2},
+	{Minutes: 360, Paragraphs: 3},
+}
+
+// ValidateCurve checks that steps are in increasing order of minutes and ask
+// for no more paragraphs than there are
+func ValidateCurve(curve []Step) error {
+	for i, step := range curve {
+		if step.Minutes <= 0 || (i > 0 && step.Minutes <= curve[i-1].Minutes) {
+			return fmt.Errorf("challenge curve minutes must be positive and increasing")
+		}
+		if step.Paragraphs < 0 || step.Paragraphs > len(paragraphs) {
+			return fmt.Errorf("challenge curve paragraphs must be between 0 and %d", len(paragraphs))
+		}
+	}
+	return nil
+}
+
+// Paragraphs returns how many paragraphs the curve asks for with remaining
+// lock time left
+func Paragraphs(curve []Step, remaining time.Duration) int {
+	if len(curve) == 0 {
+		curve = DefaultCurve
+	}
+	for _, step := range curve {
+		if remaining <= time.Duration(step.Minutes)*time.Minute {
+			return step.Paragraphs
+		}
+	}
+	return len(paragraphs)
+}
+
+// linesFor returns the lines to type for the given number of paragraphs
+func linesFor(count int) []string {
+	if count <= 0 {
+		return []string{shortStatement}
+	}
+	var lines []string
+	for _, paragraph := range paragraphs[:min(count, len(paragraphs))] {
+		lines = append(lines, strings.Split(paragraph, "\n")...)
+	}
+	return lines
+}
+
+// RequireScaled runs a typing challenge whose length follows the curve for
+// the remaining lock time, so unlocking early in the day takes far more typing
+// than unlocking just before the locks lift
+func RequireScaled(context string, remaining time.Duration, curve []Step) error {
+	if err := runLines(linesFor(Paragraphs(curve, remaining))); err != nil {
+		return fmt.Errorf("%s: %w", context, err)
+	}
+	return nil
+}
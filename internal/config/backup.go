@@ -0,0 +1,132 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultConfigBackups is how many previous config versions are kept by default
+const defaultConfigBackups = 10
+
+// backupPrefix is the file name prefix of config backups
+const backupPrefix = "config.json."
+
+// GetBackupDir returns the directory holding previous versions of config.json
+func GetBackupDir() string {
+	return filepath.Join(configDir, "backups")
+}
+
+// backupPrevious copies the current config file to the backup directory if
+// its content differs from newData, then prunes old backups
+func backupPrevious(newData []byte, keep int) error {
+	current, err := os.ReadFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // first save, nothing to back up
+		}
+		return err
+	}
+
+	if bytes.Equal(current, newData) {
+		return nil
+	}
+
+	if err := os.MkdirAll(GetBackupDir(), 0o700); err != nil {
+		return err
+	}
+
+	// Sortable timestamp with sub-second precision so rapid saves don't collide
+	name := backupPrefix + time.Now().Format("20060102-150405.000000000")
+	if err := os.WriteFile(filepath.Join(GetBackupDir(), name), current, 0o600); err != nil {
+		return err
+	}
+
+	if keep <= 0 {
+		keep = defaultConfigBackups
+	}
+	backups, err := ListBackups()
+	if err != nil {
+		return err
+	}
+	for _, old := range backups[min(keep, len(backups)):] {
+		os.Remove(old)
+	}
+	return nil
+}
+
+// ListBackups returns the paths of config backups, newest first
+func ListBackups() ([]string, error) {
+	entries, err := os.ReadDir(GetBackupDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), backupPrefix) {
+			backups = append(backups, filepath.Join(GetBackupDir(), entry.Name()))
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups, nil
+}
+
+// Undo restores the most recent backup as the current config and removes that
+// backup, so repeated calls step further back in history.
+// Returns the restored config.
+func Undo() (*Config, error) {
+	backups, err := ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) == 0 {
+		return nil, fmt.Errorf("no previous config version available")
+	}
+
+	cfg, err := LoadBackup(backups[0])
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't back up the version being undone, or undo would just toggle
+	unlock, err := lockConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := cfg.save(false); err != nil {
+		return nil, err
+	}
+
+	if err := os.Remove(backups[0]); err != nil {
+		return nil, fmt.Errorf("failed to remove restored backup: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// LoadBackup reads the config backup at path without restoring it
+func LoadBackup(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse backup %s: %w", path, err)
+	}
+	if cfg.TempExcludes == nil {
+		cfg.TempExcludes = make(map[string]string)
+	}
+	return &cfg, nil
+}
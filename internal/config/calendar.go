@@ -0,0 +1,52 @@
+package config
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// Calendar providers
+const (
+	CalendarGoogle    = "google"
+	CalendarMicrosoft = "microsoft"
+)
+
+const (
+	defaultCalendarKeyword = "Focus"
+	defaultCalendarRefresh = 15 * time.Minute
+)
+
+// CalendarSettings configures reading focus blocks from a calendar
+type CalendarSettings struct {
+	Provider       string `json:"provider"` // google or microsoft
+	ClientID       string `json:"client_id"`
+	ClientSecret   string `json:"client_secret,omitempty"` // required by Google's device flow
+	Keyword        string `json:"keyword,omitempty"`       // events whose title or category contains this lock
+	RefreshMinutes int    `json:"refresh_minutes,omitempty"`
+}
+
+// GetKeyword returns the tag that marks focus events
+func (s *CalendarSettings) GetKeyword() string {
+	if s.Keyword == "" {
+		return defaultCalendarKeyword
+	}
+	return s.Keyword
+}
+
+// RefreshEvery returns how often the daemon re-reads the calendar
+func (s *CalendarSettings) RefreshEvery() time.Duration {
+	if s.RefreshMinutes <= 0 {
+		return defaultCalendarRefresh
+	}
+	return time.Duration(s.RefreshMinutes) * time.Minute
+}
+
+// GetCalendarCachePath returns the file holding the last fetched focus blocks
+func GetCalendarCachePath() string {
+	return filepath.Join(dataDir, "calendar.json")
+}
+
+// GetCalendarTokenPath returns the file holding the calendar OAuth token
+func GetCalendarTokenPath() string {
+	return filepath.Join(dataDir, "calendar_token.json")
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package config
+
+// lockConfigFile is a no-op on platforms without flock
+func lockConfigFile() (unlock func(), err error) {
+	return func() {}, nil
+}
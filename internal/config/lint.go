@@ -0,0 +1,170 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/baggiiiie/configlock/internal/fileutil"
+	"github.com/baggiiiie/configlock/internal/locker"
+	"github.com/baggiiiie/configlock/internal/logger"
+	"github.com/baggiiiie/configlock/internal/pathutil"
+)
+
+// Lint returns warnings about entries that are valid but likely not what was
+// meant: locked paths inside other locked paths, entries that look like
+// globs or match nothing, temporary unlocks that cover whole entries, and
+// locked paths covering the daemon log.
+// Shown by 'configlock config validate' and logged when the daemon starts.
+func (c *Config) Lint() []string {
+	var warnings []string
+	warnings = append(warnings, c.lintNested()...)
+	warnings = append(warnings, c.lintGlobs()...)
+	warnings = append(warnings, c.lintPrefixes("path_schedules", mapKeys(c.PathSchedules))...)
+	warnings = append(warnings, c.lintPrefixes("lock_methods", mapKeys(c.LockMethods))...)
+	warnings = append(warnings, c.lintScopes()...)
+	warnings = append(warnings, c.lintLogs()...)
+	return warnings
+}
+
+// mapKeys returns the sorted keys of m
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// lintNested warns about locked paths inside another locked path that
+// already locks them. Exact repeats are dropped when the config is loaded.
+func (c *Config) lintNested() []string {
+	var warnings []string
+	for _, path := range c.LockedPaths {
+		// Left to lintGlobs
+		if strings.ContainsAny(path, "*?[") {
+			continue
+		}
+		path = filepath.Clean(path)
+		for j, outer := range c.LockedPaths {
+			outer = filepath.Clean(outer)
+			if !pathutil.Inside(path, outer) {
+				continue
+			}
+			// A shallow directory only locks the files directly in it
+			if c.IsShallow(c.LockedPaths[j]) {
+				info, err := os.Lstat(path)
+				if filepath.Dir(path) != outer || err != nil || info.IsDir() {
+					continue
+				}
+			}
+			warnings = append(warnings, fmt.Sprintf("%s is inside %s, which already locks it; remove it unless it needs its own schedule or lock method", path, outer))
+		}
+	}
+	return warnings
+}
+
+// lintGlobs warns about locked paths that look like globs: entries are
+// locked as literal paths, so a glob matches nothing unless a file has
+// exactly that name
+func (c *Config) lintGlobs() []string {
+	var warnings []string
+	for _, path := range c.LockedPaths {
+		if !strings.ContainsAny(path, "*?[") {
+			continue
+		}
+		if _, err := os.Lstat(path); err == nil {
+			continue
+		}
+		matches, _ := filepath.Glob(path)
+		if len(matches) == 0 {
+			warnings = append(warnings, fmt.Sprintf("%s looks like a glob and matches nothing; locked paths are not expanded", path))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("%s looks like a glob, but locked paths are not expanded; add the %d path(s) it matches instead", path, len(matches)))
+		}
+	}
+	return warnings
+}
+
+// lintPrefixes warns about entries of a path prefix setting that apply to
+// no locked path
+func (c *Config) lintPrefixes(setting string, prefixes []string) []string {
+	var warnings []string
+	for _, prefix := range prefixes {
+		expanded := filepath.Clean(pathutil.ExpandHome(prefix))
+		if !slices.ContainsFunc(c.LockedPaths, func(path string) bool {
+			return pathutil.Within(path, expanded)
+		}) {
+			warnings = append(warnings, fmt.Sprintf("%s entry %s matches no locked path", setting, prefix))
+		}
+	}
+	return warnings
+}
+
+// lintScopes warns about temporary unlocks limited with --only whose
+// patterns match nothing or cover a whole locked path anyway
+func (c *Config) lintScopes() []string {
+	var warnings []string
+	active := c.ActiveExcludes()
+	for _, root := range mapKeys(c.TempExcludeScopes) {
+		patterns := c.TempExcludeScopes[root]
+		if !slices.Contains(active, root) {
+			continue
+		}
+		files, err := locker.CoveredFiles(context.Background(), root)
+		if err != nil {
+			continue
+		}
+		// Covered files have symlinks resolved
+		realRoot, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			realRoot = root
+		}
+
+		for _, pattern := range patterns {
+			if !slices.ContainsFunc(files, func(file string) bool {
+				return fileutil.InScope(realRoot, file, []string{pattern})
+			}) {
+				warnings = append(warnings, fmt.Sprintf("temporary unlock of %s: pattern %q matches no locked file", root, pattern))
+			}
+		}
+		if len(files) > 0 && !slices.ContainsFunc(files, func(file string) bool {
+			return !fileutil.InScope(realRoot, file, patterns)
+		}) {
+			warnings = append(warnings, fmt.Sprintf("temporary unlock of %s: --only %s covers every file, the whole directory is unlocked", root, strings.Join(patterns, " ")))
+		}
+		for _, path := range c.LockedPaths {
+			if pathutil.Inside(path, root) && fileutil.InScope(root, path, patterns) {
+				warnings = append(warnings, fmt.Sprintf("temporary unlock of %s: --only %s covers all of locked path %s", root, strings.Join(patterns, " "), path))
+			}
+		}
+	}
+	return warnings
+}
+
+// lintLogs warns about locked paths covering the daemon log. Entries can't
+// be appended to a locked log; the daemon only unlocks it to rotate it.
+func (c *Config) lintLogs() []string {
+	logPath := logger.GetLogger().GetLogPath()
+	if logPath == "" {
+		return nil
+	}
+	for _, path := range c.LockedPaths {
+		if !pathutil.Within(logPath, path) {
+			continue
+		}
+		if c.IsShallow(path) && filepath.Dir(logPath) != filepath.Clean(path) {
+			continue
+		}
+		if filepath.Clean(path) == logPath {
+			return []string{fmt.Sprintf("%s is the daemon log, which can't be written while it is locked", path)}
+		}
+		return []string{fmt.Sprintf("%s covers the daemon log %s, which can't be written while it is locked", path, logPath)}
+	}
+	return nil
+}
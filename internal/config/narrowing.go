@@ -0,0 +1,232 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/challenge"
+	"github.com/baggiiiie/configlock/internal/fileutil"
+	"github.com/baggiiiie/configlock/internal/locker"
+	"github.com/baggiiiie/configlock/internal/pathutil"
+)
+
+// Narrowing is how a new config locks less than the one it replaces
+type Narrowing struct {
+	Removed     []string // locked paths the new config drops
+	Shortened   bool     // the current lock period ends earlier
+	Trusted     []string // trusted processes the new config adds
+	Rescheduled []string // locked paths whose path_schedules entry changes
+	Expiring    []string // locked paths made temporary or expiring sooner
+	Excluded    []string // paths temporarily unlocked, or unlocked for longer
+	Graced      []string // locked paths left in a grace period for longer
+	Settings    []string // other settings that weaken the policy, described
+}
+
+// Empty reports whether the new config locks at least as much as the old one
+func (n Narrowing) Empty() bool {
+	return len(n.Removed) == 0 && !n.Shortened && len(n.Trusted) == 0 && len(n.Rescheduled) == 0 &&
+		len(n.Expiring) == 0 && len(n.Excluded) == 0 && len(n.Graced) == 0 && len(n.Settings) == 0
+}
+
+// String describes the narrowing, e.g. "removed ~/.zshrc and lowered
+// strictness to easy"
+func (n Narrowing) String() string {
+	var changes []string
+	if len(n.Removed) > 0 {
+		changes = append(changes, fmt.Sprintf("removed %s", strings.Join(n.Removed, ", ")))
+	}
+	if n.Shortened {
+		changes = append(changes, "shortened the lock hours")
+	}
+	if len(n.Trusted) > 0 {
+		changes = append(changes, fmt.Sprintf("trusted %s", strings.Join(n.Trusted, ", ")))
+	}
+	if len(n.Rescheduled) > 0 {
+		changes = append(changes, fmt.Sprintf("changed the schedule of %s", strings.Join(n.Rescheduled, ", ")))
+	}
+	if len(n.Expiring) > 0 {
+		changes = append(changes, fmt.Sprintf("made %s expire sooner", strings.Join(n.Expiring, ", ")))
+	}
+	if len(n.Excluded) > 0 {
+		changes = append(changes, fmt.Sprintf("temporarily unlocked %s", strings.Join(n.Excluded, ", ")))
+	}
+	if len(n.Graced) > 0 {
+		changes = append(changes, fmt.Sprintf("extended the grace period of %s", strings.Join(n.Graced, ", ")))
+	}
+	changes = append(changes, n.Settings...)
+	return strings.Join(changes, " and ")
+}
+
+// NarrowedBy compares next to c at now and returns how next weakens the
+// policy of c
+func (c *Config) NarrowedBy(next *Config, now time.Time) Narrowing {
+	var n Narrowing
+	for _, path := range c.LockedPaths {
+		if !slices.Contains(next.LockedPaths, path) {
+			n.Removed = append(n.Removed, path)
+		}
+	}
+	n.Shortened = next.LockRemaining(now) < c.LockRemaining(now)
+	for _, name := range next.TrustedProcesses {
+		if !slices.Contains(c.TrustedProcesses, name) {
+			n.Trusted = append(n.Trusted, name)
+		}
+	}
+
+	for _, path := range next.LockedPaths {
+		if next.PathSchedules[path] != c.PathSchedules[path] {
+			n.Rescheduled = append(n.Rescheduled, path)
+		}
+	}
+
+	// Expirations that unlock paths sooner: temp_paths, temp_excludes and
+	// grace_until
+	for _, path := range c.LockedPaths {
+		if !slices.Contains(next.LockedPaths, path) {
+			continue
+		}
+		if expiry, temp := next.TempPathExpiry(path); temp {
+			if before, wasTemp := c.TempPathExpiry(path); !wasTemp || expiry.Before(before) {
+				n.Expiring = append(n.Expiring, path)
+			}
+		}
+		if until, graced := next.InGrace(path, now); graced {
+			if before, wasGraced := c.InGrace(path, now); !wasGraced || until.After(before) {
+				n.Graced = append(n.Graced, path)
+			}
+		}
+	}
+	for _, path := range next.ActiveExcludes() {
+		expiry, _ := time.Parse(time.RFC3339, next.TempExcludes[path])
+		before, err := time.Parse(time.RFC3339, c.TempExcludes[path])
+		widened := len(c.TempExcludeScopes[path]) > 0 && !slices.Equal(next.TempExcludeScopes[path], c.TempExcludeScopes[path])
+		if err != nil || !before.After(now) || expiry.After(before) || widened {
+			n.Excluded = append(n.Excluded, path)
+		}
+	}
+	slices.Sort(n.Excluded)
+
+	if slices.Index(StrictnessLevels, next.GetStrictness()) < slices.Index(StrictnessLevels, c.GetStrictness()) {
+		n.Settings = append(n.Settings, fmt.Sprintf("lowered strictness to %s", next.GetStrictness()))
+	}
+	if curveEasier(next.ChallengeCurve, c.ChallengeCurve) {
+		n.Settings = append(n.Settings, "eased the challenge_curve")
+	}
+	if next.PanicDelayDuration() < c.PanicDelayDuration() {
+		n.Settings = append(n.Settings, fmt.Sprintf("lowered panic_delay to %dh", int(next.PanicDelayDuration().Hours())))
+	}
+	if requested, err := time.Parse(time.RFC3339, next.PanicRequestedAt); err == nil {
+		if before, err := time.Parse(time.RFC3339, c.PanicRequestedAt); err != nil || requested.Before(before) {
+			n.Settings = append(n.Settings, "requested an emergency unlock")
+		}
+	}
+	if c.StopPassphraseHash != "" && next.StopPassphraseHash != c.StopPassphraseHash {
+		if next.StopPassphraseHash == "" {
+			n.Settings = append(n.Settings, "dropped the stop passphrase")
+		} else {
+			n.Settings = append(n.Settings, "replaced the stop passphrase")
+		}
+	}
+
+	// Settings that leave files of the locked paths unlocked
+	var excluded []string
+	for _, file := range next.ExcludedFiles {
+		if !slices.Contains(c.ExcludedFiles, file) {
+			excluded = append(excluded, file)
+		}
+	}
+	if len(excluded) > 0 {
+		n.Settings = append(n.Settings, fmt.Sprintf("excluded %s", strings.Join(excluded, ", ")))
+	}
+	if next.SkipBinary && !c.SkipBinary {
+		n.Settings = append(n.Settings, "turned on skip_binary")
+	}
+	if next.SkipLargerThanMB > 0 && (c.SkipLargerThanMB == 0 || next.SkipLargerThanMB < c.SkipLargerThanMB) {
+		n.Settings = append(n.Settings, fmt.Sprintf("lowered skip_larger_than_mb to %d", next.SkipLargerThanMB))
+	}
+	if symlinkStrength(next.SymlinkPolicy) < symlinkStrength(c.SymlinkPolicy) {
+		n.Settings = append(n.Settings, fmt.Sprintf("changed symlink_policy to %s", next.SymlinkPolicy))
+	}
+	if next.SkipOpenFiles && !c.SkipOpenFiles {
+		n.Settings = append(n.Settings, "turned on skip_open_files")
+	}
+	if next.LockFailurePolicy == locker.FailureExclude && c.LockFailurePolicy != locker.FailureExclude {
+		n.Settings = append(n.Settings, "changed lock_failure_policy to exclude")
+	}
+	// lock_methods entries and lock_backend
+	var methods []string
+	for _, path := range c.LockedPaths {
+		if methodStrength(next.methodFor(path)) < methodStrength(c.methodFor(path)) {
+			methods = append(methods, path)
+		}
+	}
+	if len(methods) > 0 {
+		n.Settings = append(n.Settings, fmt.Sprintf("weakened the lock method of %s", strings.Join(methods, ", ")))
+	}
+	var shallow []string
+	for _, path := range next.ShallowPaths {
+		if !slices.Contains(c.ShallowPaths, path) {
+			shallow = append(shallow, path)
+		}
+	}
+	if len(shallow) > 0 {
+		n.Settings = append(n.Settings, fmt.Sprintf("made %s shallow", strings.Join(shallow, ", ")))
+	}
+	return n
+}
+
+// methodFor returns the lock method configured for path: the lock_methods
+// entry with the longest matching prefix, or lock_backend
+func (c *Config) methodFor(path string) string {
+	method, longest := c.LockBackend, -1
+	for prefix, m := range c.lockMethods() {
+		if pathutil.Within(path, prefix) && len(prefix) > longest {
+			method, longest = m, len(prefix)
+		}
+	}
+	return method
+}
+
+// methodStrength orders lock methods by how hard they are to undo without
+// configlock: read-only permissions and ACLs can be changed by the owner
+func methodStrength(method string) int {
+	switch method {
+	case locker.StrategyChmod:
+		return 0
+	case locker.StrategyACL:
+		return 1
+	default:
+		return 2 // immutable-flag, bind-ro or detected
+	}
+}
+
+// curveEasier reports whether curve asks for less typing than before at any
+// lock time remaining
+func curveEasier(curve, before []challenge.Step) bool {
+	// Both curves only change right after one of their steps
+	remaining := []time.Duration{0}
+	for _, step := range append(slices.Clip(curve), before...) {
+		at := time.Duration(step.Minutes) * time.Minute
+		remaining = append(remaining, at, at+time.Second)
+	}
+	for _, r := range remaining {
+		if challenge.Paragraphs(curve, r) < challenge.Paragraphs(before, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// symlinkStrength orders symlink policies by how many files they lock
+func symlinkStrength(policy string) int {
+	switch policy {
+	case fileutil.SymlinkFollow:
+		return 2
+	case fileutil.SymlinkIgnore:
+		return 0
+	default:
+		return 1 // lock-target, the default
+	}
+}
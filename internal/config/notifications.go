@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/schedule"
+)
+
+// Notification levels
+const (
+	NotificationsNormal = "normal" // every notification (default)
+	NotificationsQuiet  = "quiet"  // only important ones, no per-file change alerts
+	NotificationsOff    = "off"    // none; events are still logged
+)
+
+// NotificationLevels lists the valid notification levels
+var NotificationLevels = []string{NotificationsNormal, NotificationsQuiet, NotificationsOff}
+
+// defaultNotifyEvery is the minimum time between change alerts for one path
+const defaultNotifyEvery = 5 * time.Minute
+
+// validateNotifications checks the notification level and quiet hours
+func (c *Config) validateNotifications() error {
+	if c.Notifications != "" && !slices.Contains(NotificationLevels, c.Notifications) {
+		return fmt.Errorf("invalid notifications: %s (must be one of %s)", c.Notifications, strings.Join(NotificationLevels, ", "))
+	}
+	if _, err := schedule.ParseAll(c.QuietHours); err != nil {
+		return fmt.Errorf("invalid quiet_hours in config: %w", err)
+	}
+	return nil
+}
+
+// NotificationLevel returns the notification level in effect at now, which
+// is at most quiet during quiet hours
+func (c *Config) NotificationLevel(now time.Time) string {
+	level := c.Notifications
+	if level == "" {
+		level = NotificationsNormal
+	}
+	if level == NotificationsNormal {
+		if quiet, err := schedule.ParseAll(c.QuietHours); err == nil && quiet.Active(now) {
+			return NotificationsQuiet
+		}
+	}
+	return level
+}
+
+// NotifyEvery returns the minimum time between change alerts for one path
+func (c *Config) NotifyEvery() time.Duration {
+	if c.NotifyEveryMinutes <= 0 {
+		return defaultNotifyEvery
+	}
+	return time.Duration(c.NotifyEveryMinutes) * time.Minute
+}
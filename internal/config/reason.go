@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/schedule"
+)
+
+// LockReason explains in a few words why the default schedule is or isn't
+// locking at now, e.g. "within lock window 09:00-12:00 MON-FRI" or
+// "Sat is not a lock day"
+func (c *Config) LockReason(now time.Time) string {
+	if block, ok := c.focusBlocks.At(now); ok {
+		return fmt.Sprintf("in calendar focus event %q until %s", block.Title, block.End.Local().Format("15:04"))
+	}
+
+	if len(c.Windows) > 0 {
+		for _, entry := range c.Windows {
+			if window, err := schedule.Parse(entry); err == nil && window.Active(now) {
+				return fmt.Sprintf("within lock window %s", entry)
+			}
+		}
+		return "outside all lock windows"
+	}
+
+	weekday := int(now.Weekday())
+	if weekday == 0 { // Sunday
+		weekday = 7
+	}
+	if !slices.Contains(c.LockDays, weekday) {
+		return fmt.Sprintf("%s is not a lock day", now.Format("Mon"))
+	}
+	if c.isHoursActive(now) {
+		return fmt.Sprintf("within lock hours %s - %s", c.StartTime, c.EndTime)
+	}
+	if now.Format("15:04") < c.StartTime {
+		return fmt.Sprintf("lock hours start at %s", c.StartTime)
+	}
+	return fmt.Sprintf("lock hours ended at %s", c.EndTime)
+}
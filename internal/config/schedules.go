@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/pathutil"
+	"github.com/baggiiiie/configlock/internal/schedule"
+)
+
+// DefaultSchedule is the name used for the lock hours set by start_time,
+// end_time and lock_days (or windows), which apply to unbound paths
+const DefaultSchedule = ""
+
+// validateSchedules checks that named schedules parse and that every path
+// binding refers to a defined schedule
+func (c *Config) validateSchedules() error {
+	for name, windows := range c.Schedules {
+		if name == DefaultSchedule {
+			return fmt.Errorf("invalid schedules in config: schedule name must not be empty")
+		}
+		if _, err := schedule.ParseAll(windows); err != nil {
+			return fmt.Errorf("invalid schedule %q in config: %w", name, err)
+		}
+	}
+	for path, name := range c.PathSchedules {
+		if _, ok := c.Schedules[name]; !ok {
+			return fmt.Errorf("path_schedules binds %s to unknown schedule %q", path, name)
+		}
+	}
+	return nil
+}
+
+// ScheduleNames returns the default schedule followed by the named schedules
+func (c *Config) ScheduleNames() []string {
+	names := make([]string, 0, len(c.Schedules))
+	for name := range c.Schedules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{DefaultSchedule}, names...)
+}
+
+// ScheduleFor returns the schedule a path follows: the binding with the
+// longest matching path prefix, or DefaultSchedule
+func (c *Config) ScheduleFor(path string) string {
+	path = filepath.Clean(path)
+	best, name := "", DefaultSchedule
+	for prefix, bound := range c.PathSchedules {
+		prefix = filepath.Clean(pathutil.ExpandHome(prefix))
+		if !pathutil.Within(path, prefix) {
+			continue
+		}
+		if len(prefix) > len(best) {
+			best, name = prefix, bound
+		}
+	}
+	return name
+}
+
+// IsScheduleActive checks if now is within the named schedule
+func (c *Config) IsScheduleActive(name string, now time.Time) bool {
+	if name == DefaultSchedule {
+		return c.isDefaultActive(now)
+	}
+	windows, err := schedule.ParseAll(c.Schedules[name])
+	return err == nil && windows.Active(now)
+}
+
+// IsPathActive checks if the schedule a path follows is active at now. A
+// path in its grace period is not active yet.
+func (c *Config) IsPathActive(path string, now time.Time) bool {
+	if _, ok := c.InGrace(path, now); ok {
+		return false
+	}
+	return c.IsScheduleActive(c.ScheduleFor(path), now)
+}
+
+// timeUntilSchedule returns the duration from now until the named schedule starts
+func (c *Config) timeUntilSchedule(name string, now time.Time) time.Duration {
+	if name == DefaultSchedule {
+		return c.timeUntilDefault(now)
+	}
+	windows, err := schedule.ParseAll(c.Schedules[name])
+	if err != nil {
+		return time.Hour // fallback to 1 hour
+	}
+	if windows.Active(now) {
+		return 0
+	}
+	if next, ok := windows.NextStart(now); ok {
+		return next.Sub(now)
+	}
+	return time.Hour // fallback
+}
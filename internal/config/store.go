@@ -0,0 +1,69 @@
+package config
+
+import "sync"
+
+// Change describes a replacement of the config held by a Store
+type Change struct {
+	Old *Config
+	New *Config
+}
+
+// Store holds the current config for code that reads it from several
+// goroutines. Reloading swaps in a new *Config rather than modifying the old
+// one, so a snapshot returned by Get stays consistent for as long as it is used.
+type Store struct {
+	mu   sync.RWMutex
+	cfg  *Config
+	subs []chan Change
+}
+
+// NewStore returns a store holding cfg
+func NewStore(cfg *Config) *Store {
+	return &Store{cfg: cfg}
+}
+
+// Get returns the current config snapshot
+func (s *Store) Get() *Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+// Set replaces the current config and notifies subscribers
+func (s *Store) Set(cfg *Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.cfg
+	s.cfg = cfg
+	for _, ch := range s.subs {
+		change := Change{Old: old, New: cfg}
+		// Coalesce with a change the subscriber hasn't received yet, keeping
+		// the oldest Old so nothing in between is missed
+		select {
+		case pending := <-ch:
+			change.Old = pending.Old
+		default:
+		}
+		ch <- change
+	}
+}
+
+// Reload loads the config from disk and, if it is valid, makes it current
+func (s *Store) Reload() (*Config, error) {
+	cfg, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	s.Set(cfg)
+	return cfg, nil
+}
+
+// Subscribe returns a channel that receives every subsequent change. Changes
+// that arrive faster than they are received are merged into one.
+func (s *Store) Subscribe() <-chan Change {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch := make(chan Change, 1)
+	s.subs = append(s.subs, ch)
+	return ch
+}
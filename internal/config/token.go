@@ -0,0 +1,62 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// challengeTokenTTL is how long a challenge token stays valid after the
+// challenge was passed
+const challengeTokenTTL = 2 * time.Minute
+
+// ErrNoChallengeToken is returned by RedeemChallengeToken when no valid token
+// proves that the challenge was passed
+var ErrNoChallengeToken = errors.New("the typing challenge was not completed")
+
+func challengeTokenPath() string {
+	return filepath.Join(dataDir, "challenge.token")
+}
+
+// IssueChallengeToken records that the challenge was just passed and returns
+// the token to send along with the request it allows. The token can be
+// redeemed once, within challengeTokenTTL.
+func IssueChallengeToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to create challenge token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create data directory: %w", err)
+	}
+	if err := os.WriteFile(challengeTokenPath(), []byte(token), 0600); err != nil {
+		return "", fmt.Errorf("failed to write challenge token: %w", err)
+	}
+	return token, nil
+}
+
+// RedeemChallengeToken checks token against the one last issued and removes
+// it, so each passed challenge allows a single request
+func RedeemChallengeToken(token string) error {
+	path := challengeTokenPath()
+	info, err := os.Stat(path)
+	if err != nil {
+		return ErrNoChallengeToken
+	}
+	data, err := os.ReadFile(path)
+	os.Remove(path)
+	if err != nil || token == "" || time.Since(info.ModTime()) > challengeTokenTTL {
+		return ErrNoChallengeToken
+	}
+	if subtle.ConstantTimeCompare(data, []byte(token)) != 1 {
+		return ErrNoChallengeToken
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package daemon
+
+import (
+	"slices"
+
+	"github.com/baggiiiie/configlock/internal/ipc"
+	"github.com/baggiiiie/configlock/internal/locker"
+)
+
+// retryBusy locks the files of path that were left unlocked while another
+// process had them open for writing (skip_open_files), once they have been
+// closed, and returns the updated health of path
+func (d *Daemon) retryBusy(path string, health ipc.PathHealth) ipc.PathHealth {
+	var busy []string
+	for _, file := range health.Busy {
+		report, err := locker.LockExcept(d.ctx, file, nil)
+		switch {
+		case d.ctx.Err() != nil:
+			return health
+		case err != nil:
+			// Gone, or locked by the next full lock of path if it still fails
+			d.logger.Debugf("Not retrying %s: %v", file, err)
+		case len(report.Busy) > 0:
+			busy = append(busy, file)
+		case report.Err() != nil:
+			d.logLockError("lock", file, report.Err())
+		default:
+			health.Locked += report.Locked
+			d.logger.Infof("Locked: %s (no longer open for writing)", file)
+		}
+	}
+	health.Busy = busy
+	d.health[path] = health
+	return health
+}
+
+// lockedExceptBusy reports whether the only files status found unlocked are
+// ones still open for writing, which the next sweep retries
+func lockedExceptBusy(status *locker.LockStatus, busy []string) bool {
+	if len(status.Failed) > 0 || len(status.Unlocked) == 0 {
+		return false
+	}
+	for _, file := range status.Unlocked {
+		if !slices.Contains(busy, file) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+)
+
+// crashReportEvery limits crash reports, so a panic repeating on every sweep
+// doesn't fill the data directory
+const crashReportEvery = time.Hour
+
+// runGuarded runs one step of the main loop. A panic is logged with its stack
+// trace instead of ending the daemon, which would leave files in whatever
+// state the failed operation left them; crashed reports that it happened.
+func (d *Daemon) runGuarded(step func() bool) (stopped, crashed bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			d.recoverPanic(r, debug.Stack())
+			stopped, crashed = false, true
+		}
+	}()
+	return step(), false
+}
+
+// recoverPanic logs a panic of the main loop, writes a crash report if
+// crash_reports is set and re-establishes the watchers, which the failed
+// operation may have left half set up
+func (d *Daemon) recoverPanic(r any, stack []byte) {
+	now := time.Now()
+	d.logger.Errorf("Recovered from internal error: %v\n%s", r, stack)
+
+	if d.cfg().CrashReports && now.Sub(d.lastCrashReport) >= crashReportEvery {
+		if path, err := d.writeCrashReport(r, stack, now); err != nil {
+			d.logger.Warnf("Failed to write crash report: %v", err)
+		} else {
+			d.lastCrashReport = now
+			d.logger.Infof("Crash report written to %s", path)
+		}
+	}
+
+	if d.active {
+		if err := d.setupWatchers(); err != nil {
+			d.logger.Errorf("Failed to re-establish watchers: %v", err)
+		}
+	}
+	d.notify("ConfigLock: Internal Error",
+		"The daemon recovered from an internal error and keeps enforcing.\nSee 'configlock logs' for details.")
+}
+
+// writeCrashReport writes the panic, its stack trace and the daemon state to
+// a file in the data directory, returning its path
+func (d *Daemon) writeCrashReport(r any, stack []byte, now time.Time) (string, error) {
+	var report strings.Builder
+	fmt.Fprintf(&report, "configlock %s crash report, %s\n\n", d.version, now.Format(time.RFC3339))
+	fmt.Fprintf(&report, "panic: %v\n\n%s\n", r, stack)
+	fmt.Fprintf(&report, "active: %t\n", d.active)
+	fmt.Fprintf(&report, "locked paths: %d\n", len(d.cfg().LockedPaths))
+	fmt.Fprintf(&report, "sweep every: %s\n", d.sweepEvery)
+	if !d.lastEnforced.IsZero() {
+		fmt.Fprintf(&report, "last enforced: %s\n", d.lastEnforced.Format(time.RFC3339))
+	}
+
+	dir := config.GetDataDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "crash-"+now.Format("20060102-150405")+".txt")
+	return path, os.WriteFile(path, []byte(report.String()), 0o600)
+}
+
+// answer replies to a control socket command. If handling it panics, the
+// client is told so before the panic reaches runGuarded, rather than being
+// left waiting.
+func (d *Daemon) answer(req ipcRequest) {
+	reply := ipcReply{err: errors.New("internal error in the daemon, see 'configlock logs'")}
+	defer func() { req.reply <- reply }()
+	reply.result, reply.err = d.handleCommand(req.req)
+}
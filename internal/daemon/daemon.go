@@ -1,31 +1,122 @@
 package daemon
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/baggiiiie/configlock/internal/calendar"
 	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/fileutil"
+	"github.com/baggiiiie/configlock/internal/focus"
+	"github.com/baggiiiie/configlock/internal/heartbeat"
+	"github.com/baggiiiie/configlock/internal/hooks"
+	"github.com/baggiiiie/configlock/internal/ipc"
 	"github.com/baggiiiie/configlock/internal/locker"
 	"github.com/baggiiiie/configlock/internal/logger"
+	"github.com/baggiiiie/configlock/internal/mqtt"
 	"github.com/baggiiiie/configlock/internal/notifier"
+	"github.com/baggiiiie/configlock/internal/pathutil"
+	"github.com/baggiiiie/configlock/internal/sdnotify"
+	"github.com/baggiiiie/configlock/internal/service"
+	"github.com/baggiiiie/configlock/internal/stats"
+	"github.com/baggiiiie/configlock/internal/terminal"
+	"github.com/baggiiiie/configlock/internal/upgrade"
 	"github.com/fsnotify/fsnotify"
+	kardianos "github.com/kardianos/service"
 )
 
+// replaceRecheckDelay is how long to wait for an editor to finish renaming a
+// new file over a locked one before checking the path again
+const replaceRecheckDelay = 500 * time.Millisecond
+
+const (
+	relockGraceMinutes = 2 // minutes to postpone a re-lock while the file is still open
+	maxRelockDeferrals = 3 // re-lock is forced after this many postponements
+)
+
+// ipcRequest is a control socket command handed to the main loop, which owns
+// the daemon state
+type ipcRequest struct {
+	req   ipc.Request
+	reply chan ipcReply
+}
+
+type ipcReply struct {
+	result any
+	err    error
+}
+
 type Daemon struct {
-	cfg      *config.Config
-	watcher  *fsnotify.Watcher
-	logger   *logger.Logger
-	notifier *notifier.Notifier
-	stopCh   chan struct{}
-	active   bool // true when within work hours and watchers are set up
+	store     *config.Store
+	changes   <-chan config.Change // config reloads, handled by the main loop
+	watcher   *fsnotify.Watcher
+	logger    *logger.Logger
+	notifier  *notifier.Notifier
+	throttle  *notifier.Throttle // change alerts per path
+	stopCh    chan struct{}
+	ctx       context.Context // cancelled on shutdown, interrupts lock sweeps
+	cancel    context.CancelFunc
+	recheckCh chan string // locked files to re-check after a rename/remove event
+	syncedCh  chan error  // results of background calendar syncs
+	ipcCh     chan ipcRequest
+	expiredCh chan string  // temporary exclusions whose expiry timer fired
+	listener  net.Listener // control socket for 'configlock tray' and other clients
+	active    bool         // true when within work hours and watchers are set up
+
+	logsProtected atomic.Bool // the logs are append-only, read by the logger's rotation guard
+
+	activeSchedules map[string]bool // schedule name -> active, tracked independently
+
+	unlockSnapshots map[string]time.Time   // temp-excluded path -> latest mtime when first seen
+	relockDeferrals map[string]int         // temp-excluded path -> times its re-lock was postponed
+	relockTimers    map[string]*time.Timer // temp-excluded path -> timer firing at its expiry
+
+	health       map[string]ipc.PathHealth // locked path -> result of its last lock this activation
+	lastEnforced time.Time                 // end of the last complete enforcement sweep
+
+	sweepOnly map[string]bool // locked paths left unwatched by watch limits, enforced by the sweep alone
+
+	missing map[string]time.Time // missing locked path -> when first found missing
+	held    *heldPolicy          // policy kept in force after an edit made without the CLI
+
+	sweepEvery time.Duration // current interval between enforcement sweeps
+	violated   bool          // a violation or lost event since the last sweep
+
+	summary        sweepSummary // enforcement since the last summary line
+	lastSweepPaths int          // locked paths the last sweep enforced
+
+	promptState string // last line written to the prompt state file
+
+	version string // version of the running binary, for upgrade notifications
+
+	lastCrashReport time.Time // when the last crash report was written
 }
 
+// The sweep runs every minSweepInterval and backs off to maxSweepInterval
+// while the watchers cover every locked path and nothing needs fixing
+const (
+	minSweepInterval = 30 * time.Second
+	maxSweepInterval = 5 * time.Minute
+)
+
+// upgradeCheckEvery is how often the daemon looks for a new release; the
+// upgrade package only asks GitHub once a day
+const upgradeCheckEvery = time.Hour
+
 // getStateFilePath returns the path to the daemon state file
 // This file is used to detect abnormal termination (e.g., kill -9)
 func getStateFilePath() string {
@@ -51,8 +142,17 @@ func checkAbnormalTermination() bool {
 	return err == nil
 }
 
-// New creates a new daemon instance
-func New() (*Daemon, error) {
+// SignalReload asks a running daemon to reload its configuration over the
+// control socket. The PID in the state file is not signalled: the file
+// outlives a daemon that was killed, and the PID may since belong to another
+// process.
+func SignalReload() error {
+	return ipc.Send(ipc.Request{Command: ipc.CommandReload}, nil)
+}
+
+// New creates a new daemon instance for the given version of configlock,
+// logging to log. Lock operations are logged there too.
+func New(version string, log *logger.Logger) (*Daemon, error) {
 	cfg, err := config.Load()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
@@ -63,12 +163,35 @@ func New() (*Daemon, error) {
 		return nil, fmt.Errorf("failed to create watcher: %w", err)
 	}
 
+	log.SetLevel(cfg.GetLogLevel())
+	locker.SetLogger(log)
+
+	store := config.NewStore(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Daemon{
-		cfg:      cfg,
-		watcher:  watcher,
-		logger:   logger.GetLogger(),
-		notifier: notifier.New("ConfigLock"),
-		stopCh:   make(chan struct{}),
+		store:     store,
+		changes:   store.Subscribe(),
+		watcher:   watcher,
+		logger:    log,
+		notifier:  notifier.New("ConfigLock"),
+		throttle:  notifier.NewThrottle(),
+		stopCh:    make(chan struct{}),
+		ctx:       ctx,
+		cancel:    cancel,
+		recheckCh: make(chan string),
+		syncedCh:  make(chan error),
+		ipcCh:     make(chan ipcRequest),
+		expiredCh: make(chan string),
+
+		unlockSnapshots: make(map[string]time.Time),
+		relockDeferrals: make(map[string]int),
+		relockTimers:    make(map[string]*time.Timer),
+		health:          make(map[string]ipc.PathHealth),
+		activeSchedules: make(map[string]bool),
+		sweepOnly:       make(map[string]bool),
+		sweepEvery:      minSweepInterval,
+		missing:         loadMissing(),
+		version:         version,
 	}, nil
 }
 
@@ -76,8 +199,9 @@ func New() (*Daemon, error) {
 //
 // The daemon uses two mechanisms to ensure immutable flags stay applied:
 //  1. File system watching (fsnotify) - provides instant reaction to changes
-//  2. Periodic sweep (every 30 seconds) - catches changes that fsnotify might miss,
-//     such as manual flag removal via 'sudo chattr -i' or 'sudo chflags noschg'
+//  2. Periodic sweep (every 30 seconds, backing off to 5 minutes while the
+//     watchers are healthy and nothing changes) - catches changes that fsnotify
+//     might miss, such as manual flag removal via 'sudo chattr -i' or 'sudo chflags noschg'
 //
 // Outside work hours, the daemon sleeps until work hours start.
 func (d *Daemon) Start() error {
@@ -93,82 +217,597 @@ func (d *Daemon) Start() error {
 	}
 
 	d.logger.Info("Starting configlock daemon")
+	d.logger.SetRotateGuard(d.guardRotation)
+	d.selfTest()
+
+	// Control socket for status queries
+	if listener, err := ipc.Listen(); err != nil {
+		d.logger.Warnf("Failed to open control socket: %v", err)
+	} else {
+		d.listener = listener
+		go ipc.Serve(listener, d.handleIPC)
+	}
 
 	// Set up signal handling
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
 
+	// The main loop only sees signals between operations, so also cancel a
+	// running lock sweep as soon as a shutdown signal arrives
+	ctx, stopSignals := signal.NotifyContext(d.ctx, syscall.SIGTERM, syscall.SIGINT)
+	defer stopSignals()
+	d.ctx = ctx
+
 	// Create timer for next check
 	timer := time.NewTimer(0) // fires immediately for initial check
 	defer timer.Stop()
 
-	for {
+	// Heartbeat ticker for the optional dead man's switch
+	heartbeatTicker := time.NewTicker(d.cfg().HeartbeatEvery())
+	defer heartbeatTicker.Stop()
+
+	// Calendar ticker for refreshing focus events
+	calendarTicker := time.NewTicker(d.calendarRefreshEvery())
+	defer calendarTicker.Stop()
+	d.syncCalendar()
+
+	// Upgrade ticker for announcing new releases
+	upgradeTicker := time.NewTicker(upgradeCheckEvery)
+	defer upgradeTicker.Stop()
+	go d.checkUpgrade()
+
+	// Under systemd, ping the watchdog from the main loop so a hung loop
+	// gets the daemon restarted
+	var watchdog <-chan time.Time
+	if interval, ok := sdnotify.WatchdogInterval(); ok {
+		watchdogTicker := time.NewTicker(interval)
+		defer watchdogTicker.Stop()
+		watchdog = watchdogTicker.C
+	}
+	// A config edited without the CLI while the daemon was down
+	d.checkDirectEdit(d.cfg(), nil, time.Now())
+	for _, warning := range d.cfg().Lint() {
+		d.logger.Warnf("Config: %s", warning)
+	}
+	// Within lock hours the first tick activates and writes it
+	if !d.isWithinLockHours(time.Now()) {
+		d.writePromptState(promptUnlocked, time.Now())
+	}
+
+	if _, err := sdnotify.Notify(sdnotify.Ready); err != nil {
+		d.logger.Warnf("Failed to notify systemd: %v", err)
+	}
+
+	// step handles a single event of the main loop and reports whether the
+	// daemon stopped
+	step := func() bool {
 		select {
 		case <-d.stopCh:
 			d.logger.Info("Daemon stopped")
-			return nil
+			return true
 
 		case sig := <-sigCh:
 			d.logger.Infof("Received signal: %v", sig)
 			if sig == syscall.SIGHUP {
 				d.logger.Info("Reloading configuration")
+				sdnotify.Notify(sdnotify.Reloading)
 				d.reloadConfig()
-				if d.active {
-					d.setupWatchers()
-				}
+				sdnotify.Notify(sdnotify.Ready)
 			} else {
 				d.gracefulShutdown()
-				return nil
+				return true
 			}
 
 		case event := <-d.watcher.Events:
 			if !d.active {
-				continue
+				return false
 			}
 			// Ignore events on configlock's own config file
 			configDir := config.GetConfigDir()
 			configPath := config.GetConfigPath()
-			if event.Name != configPath && event.Name != configDir &&
-				!strings.HasPrefix(event.Name, configDir+string(filepath.Separator)) {
+			if event.Name != configPath && !pathutil.Within(event.Name, configDir) {
 				d.logger.Infof("File event detected: %s %s", event.Op, event.Name)
 			}
-			d.handleFileEvent(event.Name)
+			d.handleFileEvent(event)
+			d.tightenSweep(timer)
+
+		case path := <-d.recheckCh:
+			if d.active {
+				d.recheckReplaced(path)
+				d.tightenSweep(timer)
+			}
 
 		case err := <-d.watcher.Errors:
 			d.logger.Errorf("Watcher error: %v", err)
+			// Events may have been dropped, so don't rely on the watchers
+			d.violated = true
+			d.tightenSweep(timer)
+
+		case <-heartbeatTicker.C:
+			if d.active && d.cfg().HeartbeatURL != "" {
+				go d.sendHeartbeat(d.cfg().HeartbeatURL)
+			}
+
+		case req := <-d.ipcCh:
+			d.answer(req)
+
+		case path := <-d.expiredCh:
+			delete(d.relockTimers, path)
+			if d.active {
+				d.logger.Infof("Temporary unlock expired: %s", path)
+				d.enforce(time.Now())
+			}
+
+		case <-calendarTicker.C:
+			d.syncCalendar()
+
+		case <-upgradeTicker.C:
+			go d.checkUpgrade()
+
+		case <-watchdog:
+			sdnotify.Notify(sdnotify.Watchdog)
+
+		case err := <-d.syncedCh:
+			if err != nil {
+				d.logger.Warnf("Calendar sync failed: %v", err)
+				return false
+			}
+			// Pick up the new focus blocks
+			d.reloadConfig()
+
+		case change := <-d.changes:
+			d.configChanged(change)
+			heartbeatTicker.Reset(d.cfg().HeartbeatEvery())
+			calendarTicker.Reset(d.calendarRefreshEvery())
+			if d.active {
+				d.setupWatchers()
+			}
+			// Re-evaluate lock hours, which may have changed
+			timer.Reset(0)
 
 		case <-timer.C:
-			withinWorkHours := d.cfg.IsWithinWorkHours()
+			if d.executePanicIfDue() {
+				return false
+			}
+
+			// Every decision in this tick uses the same timestamp
+			now := time.Now()
+			d.updateSchedules(now)
+			d.releaseHeld(now)
+			d.expireTempPaths(now)
+			withinWorkHours := d.isWithinLockHours(now)
 
 			if withinWorkHours && !d.active {
 				// Transition: entering work hours
-				d.activate()
-				timer.Reset(30 * time.Second)
+				d.activate(now)
+				d.sweepEvery = minSweepInterval
+				d.violated = false
+				timer.Reset(d.sweepEvery)
 			} else if !withinWorkHours && d.active {
 				// Transition: leaving work hours
 				d.deactivate()
-				sleepDuration := d.cfg.TimeUntilWorkHours()
+				sleepDuration := d.timeUntilLockHours(now)
 				d.logger.Infof("Sleeping until work hours start (%s)", sleepDuration.Round(time.Minute))
-				timer.Reset(sleepDuration)
+				timer.Reset(d.capSleepForPanic(sleepDuration))
 			} else if d.active {
-				// Already active, enforce and check again in 30s
-				d.enforce()
-				timer.Reset(30 * time.Second)
+				// Already active, enforce and check again after the sweep interval
+				d.enforce(now)
+				timer.Reset(d.capForPathChanges(d.nextSweep(), now))
 			} else {
 				// Still inactive, sleep until work hours
-				sleepDuration := d.cfg.TimeUntilWorkHours()
+				sleepDuration := d.timeUntilLockHours(now)
 				d.logger.Infof("Outside work hours, sleeping until start (%s)", sleepDuration.Round(time.Minute))
-				timer.Reset(sleepDuration)
+				timer.Reset(d.capSleepForPanic(sleepDuration))
+			}
+		}
+		return false
+	}
+
+	for {
+		stopped, crashed := d.runGuarded(step)
+		if stopped {
+			return nil
+		}
+		if crashed {
+			// Sweep again soon, but not at once: an event that panics every
+			// time would otherwise keep the loop spinning
+			d.violated = true
+			timer.Reset(minSweepInterval)
+		}
+	}
+}
+
+// selfTest checks that locking works on the filesystem of every locked path,
+// logs the backend used for each and warns loudly when locks would silently
+// be read-only permissions the user can undo
+func (d *Daemon) selfTest() {
+	// Lock tools are only run from root-owned system directories
+	for _, err := range locker.ResolveTools() {
+		d.logger.Errorf("Lock tool unavailable: %v", err)
+	}
+
+	var fallback, broken []string
+	for _, path := range d.cfg().LockedPaths {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		result, err := locker.Probe(path)
+		if err != nil {
+			d.logger.Errorf("Self-test failed for %s (%s on %s): %v", path, result.Backend, result.FSType, err)
+			broken = append(broken, path)
+			continue
+		}
+		if result.Fallback() {
+			d.logger.Warnf("Lock backend for %s: %s falls back to %s on %s", path, result.Backend, result.Effective, result.FSType)
+			fallback = append(fallback, path)
+			continue
+		}
+		d.logger.Infof("Lock backend for %s: %s on %s", path, result.Backend, result.FSType)
+		if locker.IsWindowsDrive(path) {
+			d.logger.Warnf("%s is on a Windows drive under WSL, where it can only be made read-only", path)
+		}
+	}
+
+	var messages []string
+	if len(fallback) > 0 {
+		messages = append(messages, fmt.Sprintf("%d path(s) will only be made read-only, which can be undone without root (e.g. %s).", len(fallback), fallback[0]))
+	}
+	if len(broken) > 0 {
+		messages = append(messages, fmt.Sprintf("Locking does not work for %d path(s) (e.g. %s). See 'configlock logs'.", len(broken), broken[0]))
+	}
+	if len(messages) > 0 {
+		d.notify("ConfigLock", strings.Join(messages, "\n"))
+	}
+}
+
+// updateSchedules tracks each schedule's active state. While the daemon stays
+// active, paths of a schedule that ended are unlocked here and paths of a
+// schedule that started are locked by the next enforce.
+func (d *Daemon) updateSchedules(now time.Time) {
+	names := d.cfg().ScheduleNames()
+	for name := range d.activeSchedules {
+		if !slices.Contains(names, name) {
+			delete(d.activeSchedules, name)
+		}
+	}
+
+	for _, name := range names {
+		active := d.cfg().IsScheduleActive(name, now)
+		if active == d.activeSchedules[name] {
+			continue
+		}
+		d.activeSchedules[name] = active
+		if name == config.DefaultSchedule {
+			continue // transitions are logged by activate and deactivate
+		}
+		if active {
+			d.logger.Infof("Schedule %q started", name)
+		} else {
+			d.logger.Infof("Schedule %q ended", name)
+			if d.active {
+				d.unlockSchedule(name)
+			}
+		}
+	}
+}
+
+// unlockSchedule unlocks the paths bound to a schedule that has ended
+func (d *Daemon) unlockSchedule(name string) {
+	for _, path := range d.cfg().LockedPaths {
+		if d.cfg().ScheduleFor(path) != name {
+			continue
+		}
+		delete(d.health, path)
+		d.unlockPath(d.ctx, path)
+	}
+}
+
+// handleIPC passes a control socket command to the main loop and waits for
+// the answer
+func (d *Daemon) handleIPC(r ipc.Request) (any, error) {
+	req := ipcRequest{req: r, reply: make(chan ipcReply, 1)}
+	select {
+	case d.ipcCh <- req:
+	case <-d.stopCh:
+		return nil, fmt.Errorf("daemon is stopping")
+	}
+	reply := <-req.reply
+	return reply.result, reply.err
+}
+
+// handleCommand answers a control socket command; runs on the main loop
+func (d *Daemon) handleCommand(req ipc.Request) (any, error) {
+	switch req.Command {
+	case ipc.CommandStatus:
+		status := ipc.Status{
+			Active:       d.active,
+			LockedPaths:  d.cfg().LockedPaths,
+			TempExcludes: make(map[string]string),
+		}
+		for _, path := range d.cfg().ActiveExcludes() {
+			status.TempExcludes[path] = d.cfg().TempExcludes[path]
+		}
+		for _, name := range d.cfg().ScheduleNames()[1:] {
+			if status.Schedules == nil {
+				status.Schedules = make(map[string]bool)
 			}
+			status.Schedules[name] = d.activeSchedules[name]
+		}
+		if next, ok := d.cfg().NextTransition(time.Now()); ok {
+			status.NextTransition = next
+		}
+		if executesAt, pending := d.panicExecutesAt(time.Now()); pending {
+			status.PanicExecutesAt = executesAt
+		}
+		if d.active {
+			status.LastEnforced = d.lastEnforced
+			status.Health = maps.Clone(d.health)
+			status.SweepOnly = slices.Sorted(maps.Keys(d.sweepOnly))
+			status.SweepEvery = d.sweepEvery
+		}
+		return status, nil
+	case ipc.CommandTempUnlock:
+		return d.tempUnlock(req)
+	case ipc.CommandTempCancel:
+		return d.cancelTempUnlock(req)
+	case ipc.CommandRelock:
+		return d.relock(req.Path)
+	case ipc.CommandReload:
+		d.logger.Info("Reloading configuration")
+		d.reloadConfig()
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown command: %s", req.Command)
+	}
+}
+
+// tempUnlock excludes a locked path from locking until req.Until or for
+// req.Minutes (0 = config default), unlocks it and schedules its re-lock for
+// the moment the exclusion expires. With req.Only, just the matching files of
+// a directory are excluded. The client runs the challenge and asks for the
+// reason; the daemon checks the token proving the challenge was passed, the
+// reason during lock hours and that the unlock ends within MaxTempUnlock.
+func (d *Daemon) tempUnlock(req ipc.Request) (ipc.TempUnlock, error) {
+	path, minutes, only := req.Path, req.Minutes, req.Only
+	current := d.cfg()
+	if err := current.RequireLockedPath(path); err != nil {
+		return ipc.TempUnlock{}, err
+	}
+	now := time.Now()
+	if current.IsPathActive(path, now) && len(strings.TrimSpace(req.Reason)) < config.MinReasonLength {
+		return ipc.TempUnlock{}, fmt.Errorf("a reason of at least %d characters is required during lock hours", config.MinReasonLength)
+	}
+	if minutes <= 0 {
+		minutes = current.TempDuration
+	}
+	expiresAt := req.Until
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(time.Duration(minutes) * time.Minute)
+	}
+	if !expiresAt.After(now) || expiresAt.Sub(now) > config.MaxTempUnlock {
+		return ipc.TempUnlock{}, fmt.Errorf("a temporary unlock must end within the next %d hours", int(config.MaxTempUnlock.Hours()))
+	}
+	if d.strictness(now) != config.StrictnessEasy {
+		if err := config.RedeemChallengeToken(req.Token); err != nil {
+			return ipc.TempUnlock{}, err
+		}
+	}
+
+	cfg, err := config.Update(func(cfg *config.Config) error {
+		cfg.AddTempExcludeUntil(path, expiresAt)
+		cfg.SetTempExcludeScope(path, only)
+		cfg.SetTempExcludeReason(path, req.Reason)
+		return nil
+	})
+	if err != nil {
+		return ipc.TempUnlock{}, fmt.Errorf("failed to save config: %w", err)
+	}
+	d.store.Set(cfg)
+	// The exclusion is no longer one the held policy holds back
+	if d.held != nil {
+		d.held.excludes = slices.DeleteFunc(d.held.excludes, func(held string) bool { return held == path })
+	}
+
+	delete(d.health, path)
+	unlock := locker.UnlockContext
+	if len(only) > 0 {
+		unlock = func(ctx context.Context, path string) error {
+			return locker.UnlockScope(ctx, path, only)
 		}
 	}
+	if err := unlock(d.ctx, path); err != nil {
+		d.logLockError("unlock", path, err)
+		return ipc.TempUnlock{}, fmt.Errorf("failed to unlock %s: %w", path, err)
+	}
+
+	if timer, ok := d.relockTimers[path]; ok {
+		timer.Stop()
+	}
+	d.relockTimers[path] = time.AfterFunc(time.Until(expiresAt), func() {
+		select {
+		case d.expiredCh <- path:
+		case <-d.stopCh:
+		}
+	})
+
+	return ipc.TempUnlock{Path: path, ExpiresAt: expiresAt}, nil
+}
+
+// cancelTempUnlock ends the temporary exclusion of req.Path, or of every path
+// with req.All, before it expires and re-locks the paths right away
+func (d *Daemon) cancelTempUnlock(req ipc.Request) (ipc.TempCancel, error) {
+	paths := d.cfg().ActiveExcludes()
+	if !req.All {
+		if !slices.Contains(paths, req.Path) {
+			return ipc.TempCancel{}, fmt.Errorf("%s is not temporarily unlocked", req.Path)
+		}
+		paths = []string{req.Path}
+	}
+	if len(paths) == 0 {
+		return ipc.TempCancel{}, nil
+	}
+
+	cfg, err := config.Update(func(cfg *config.Config) error {
+		for _, path := range paths {
+			cfg.RemoveTempExclude(path)
+		}
+		return nil
+	})
+	if err != nil {
+		return ipc.TempCancel{}, fmt.Errorf("failed to save config: %w", err)
+	}
+	d.store.Set(cfg)
+
+	now := time.Now()
+	for _, path := range paths {
+		d.forgetTempUnlock(path)
+		d.logger.Infof("Temporary unlock cancelled: %s", path)
+		d.lockPath(path, now)
+	}
+	if d.active {
+		d.ensureWatches()
+	}
+
+	return ipc.TempCancel{Paths: paths}, nil
+}
+
+// relock ends any temporary exclusion of a locked path and locks it again
+// right away, even if it still looks locked
+func (d *Daemon) relock(path string) (ipc.Relock, error) {
+	if err := d.cfg().RequireLockedPath(path); err != nil {
+		return ipc.Relock{}, err
+	}
+
+	if _, excluded := d.cfg().TempExcludes[path]; excluded {
+		cfg, err := config.Update(func(cfg *config.Config) error {
+			cfg.RemoveTempExclude(path)
+			return nil
+		})
+		if err != nil {
+			return ipc.Relock{}, fmt.Errorf("failed to save config: %w", err)
+		}
+		d.store.Set(cfg)
+	}
+	d.forgetTempUnlock(path)
+
+	now := time.Now()
+	if !d.cfg().IsPathActive(path, now) {
+		return ipc.Relock{Path: path}, nil
+	}
+
+	d.logger.Infof("Re-locking on request: %s", path)
+	d.lockPath(path, now)
+	d.ensureWatches()
+	if health := d.health[path]; health.Error != "" {
+		return ipc.Relock{}, fmt.Errorf("failed to lock %s: %s", path, health.Error)
+	}
+	return ipc.Relock{Path: path, Locked: true}, nil
+}
+
+// forgetTempUnlock drops the daemon's bookkeeping for a path's temporary
+// exclusion, and its lock health so the next lock is a full one
+func (d *Daemon) forgetTempUnlock(path string) {
+	if timer, ok := d.relockTimers[path]; ok {
+		timer.Stop()
+		delete(d.relockTimers, path)
+	}
+	delete(d.unlockSnapshots, path)
+	delete(d.relockDeferrals, path)
+	delete(d.health, path)
+}
+
+// calendarRefreshEvery returns how often calendar focus events are refreshed
+func (d *Daemon) calendarRefreshEvery() time.Duration {
+	if d.cfg().Calendar == nil {
+		return time.Hour // nothing to refresh, checked again after a reload
+	}
+	return d.cfg().Calendar.RefreshEvery()
+}
+
+// syncCalendar refreshes calendar focus events in the background, reporting
+// the result on syncedCh
+func (d *Daemon) syncCalendar() {
+	if d.cfg().Calendar == nil {
+		return
+	}
+	settings := *d.cfg().Calendar
+	go func() {
+		_, err := calendar.Sync(&settings)
+		select {
+		case d.syncedCh <- err:
+		case <-d.stopCh:
+		}
+	}()
+}
+
+// capSleepForPanic shortens a sleep so a pending panic request is executed on time
+func (d *Daemon) capSleepForPanic(sleep time.Duration) time.Duration {
+	executesAt, pending := d.panicExecutesAt(time.Now())
+	if !pending {
+		return sleep
+	}
+	return max(min(sleep, time.Until(executesAt)), 0)
+}
+
+// executePanicIfDue performs a requested emergency unlock once its delay has elapsed
+// The daemon stops its own service, which triggers the regular graceful shutdown
+// (unlock all paths) without the service manager restarting it.
+// Returns true if the panic was executed.
+func (d *Daemon) executePanicIfDue() bool {
+	now := time.Now()
+	executesAt, pending := d.panicExecutesAt(now)
+	if !pending || now.Before(executesAt) {
+		return false
+	}
+
+	d.logger.Warnf("Emergency unlock requested at %s is due, unlocking all paths and stopping", d.cfg().PanicRequestedAt)
+	cfg, err := config.Update(func(cfg *config.Config) error {
+		cfg.CancelPanic()
+		return nil
+	})
+	if err != nil {
+		d.logger.Errorf("Failed to clear panic request: %v", err)
+	} else {
+		d.store.Set(cfg)
+	}
+
+	d.notify("ConfigLock", "Emergency unlock executed.\nAll paths are unlocked and the daemon is stopping.")
+
+	// Stop asynchronously: the service manager waits for this process to exit,
+	// which happens once the resulting SIGTERM reaches the main loop
+	go func() {
+		svc, err := service.New()
+		if err == nil {
+			if status, statusErr := svc.Status(); statusErr == nil && status == kardianos.StatusRunning {
+				svc.Stop()
+				return
+			}
+		}
+		// Not running under the service manager, terminate directly
+		if self, err := os.FindProcess(os.Getpid()); err == nil {
+			self.Signal(syscall.SIGTERM)
+		}
+	}()
+
+	return true
 }
 
 // gracefulShutdown unlocks all configured paths and stops the daemon
 func (d *Daemon) gracefulShutdown() {
 	d.logger.Info("Graceful shutdown initiated")
+	sdnotify.Notify(sdnotify.Stopping)
 	removeStateFile() // Remove state file to indicate clean shutdown
-	d.unlockAll()
+	if d.active {
+		d.logSummary(time.Now())
+	}
+	d.writePromptState(promptStopped, time.Now())
+	// Unlocking on the way out must finish even though d.ctx is cancelled
+	d.unlockAll(context.Background())
+	d.releaseLogs()
+	d.releaseReference()
+	if d.active {
+		d.runFocusShortcut(d.cfg().FocusOffShortcut)
+	}
 	d.Stop()
 }
 
@@ -176,6 +815,10 @@ func (d *Daemon) gracefulShutdown() {
 func (d *Daemon) Stop() {
 	d.logger.Info("Stopping configlock daemon")
 	close(d.stopCh)
+	d.cancel()
+	if d.listener != nil {
+		d.listener.Close()
+	}
 	if d.watcher != nil {
 		d.watcher.Close()
 	}
@@ -183,41 +826,159 @@ func (d *Daemon) Stop() {
 }
 
 // activate sets up watchers and enforces locks when entering work hours
-func (d *Daemon) activate() {
+func (d *Daemon) activate(now time.Time) {
 	d.logger.Info("Entering work hours, activating")
 	d.active = true
+	d.summary = sweepSummary{since: now}
 	if err := d.setupWatchers(); err != nil {
 		d.logger.Errorf("Failed to setup watchers: %v", err)
 	}
-	d.enforce()
+	d.checkHardlinks()
+	d.protectLogs()
+	d.protectReference()
+	d.enforce(now)
+	d.reportInterimChanges()
+	if d.cfg().HeartbeatURL != "" {
+		go d.sendHeartbeat(d.cfg().HeartbeatURL)
+	}
+	d.runFocusShortcut(d.cfg().FocusOnShortcut)
+	d.writePromptState(promptLocked, now)
+	d.emit(hooks.EventActivate, "")
+}
+
+// runFocusShortcut runs the configured macOS Focus shortcut, if any
+func (d *Daemon) runFocusShortcut(name string) {
+	if name == "" {
+		return
+	}
+	if err := focus.RunShortcut(name); err != nil {
+		d.logger.Warnf("Failed to run focus shortcut: %v", err)
+		return
+	}
+	d.logger.Infof("Ran focus shortcut %q", name)
+}
+
+// checkHardlinks warns about locked files with hard links outside the locked paths,
+// since edits through those links don't generate events on watched paths
+func (d *Daemon) checkHardlinks() {
+	for _, path := range d.cfg().LockedPaths {
+		links, err := fileutil.ExternalHardlinks(path)
+		if err != nil {
+			continue
+		}
+		for _, link := range links {
+			d.logger.Warnf("File has %d hard link(s) outside locked path %s: %s", link.External, path, link.Path)
+		}
+	}
 }
 
 // deactivate removes watchers and unlocks paths when leaving work hours
 func (d *Daemon) deactivate() {
 	d.logger.Info("Leaving work hours, deactivating")
+	d.logSummary(time.Now())
 	d.active = false
+	clear(d.health)
 	d.clearWatchers()
 	d.reloadConfig()
-	d.unlockAll()
+	d.recordInterimSnapshot(time.Now())
+	d.unlockAll(d.ctx)
+	d.releaseLogs()
+	d.releaseReference()
+	d.runFocusShortcut(d.cfg().FocusOffShortcut)
+	d.writePromptState(promptUnlocked, time.Now())
+	d.emit(hooks.EventDeactivate, "")
 }
 
-// unlockAll unlocks all configured paths
-func (d *Daemon) unlockAll() {
-	for _, path := range d.cfg.LockedPaths {
-		if err := locker.Unlock(path); err != nil {
-			d.logger.Errorf("Failed to unlock %s: %v", path, err)
+// unlockAll unlocks all configured paths, stopping early if ctx is cancelled
+func (d *Daemon) unlockAll(ctx context.Context) {
+	for _, path := range d.lockedPaths(time.Now()) {
+		if ctx.Err() != nil {
+			return
 		}
+		d.unlockPath(ctx, path)
 	}
 }
 
-// reloadConfig reloads configuration from disk
-func (d *Daemon) reloadConfig() {
-	cfg, err := config.Load()
+// unlockPath unlocks path, logging the outcome
+func (d *Daemon) unlockPath(ctx context.Context, path string) {
+	if err := locker.UnlockContext(ctx, path); err != nil {
+		d.logLockError("unlock", path, err)
+		return
+	}
+	d.logger.Infof("Unlocked: %s", path)
+}
+
+// logLockError logs a lock/unlock failure, listing every failed file for directories
+func (d *Daemon) logLockError(action, path string, err error) {
+	var multiErr *locker.MultiError
+	if !errors.As(err, &multiErr) {
+		d.logger.Errorf("Failed to %s %s: %v", action, path, err)
+		return
+	}
+
+	d.logger.Errorf("Failed to %s %d file(s) in %s", action, len(multiErr.Errors), path)
+	for _, file := range multiErr.Paths() {
+		d.logger.Errorf("  %s: %v", file, multiErr.Errors[file])
+	}
+}
+
+// excludeFailed adds the files of path that failed to lock to excluded_files
+// (lock_failure_policy: exclude), so later sweeps leave them alone
+func (d *Daemon) excludeFailed(path string, failures []locker.LockError) {
+	files := make([]string, len(failures))
+	for i, failure := range failures {
+		d.logger.Warnf("Excluding %s from locking from now on: %v", failure.Path, failure.Err)
+		files[i] = failure.Path
+	}
+	cfg, err := config.Update(func(cfg *config.Config) error {
+		cfg.AddExcludedFiles(files)
+		return nil
+	})
 	if err != nil {
-		d.logger.Errorf("Failed to reload config: %v", err)
+		d.logger.Errorf("Failed to save the files excluded from %s: %v", path, err)
 		return
 	}
-	d.cfg = cfg
+	d.store.Set(cfg)
+}
+
+// cfg returns the current config snapshot
+func (d *Daemon) cfg() *config.Config {
+	return d.store.Get()
+}
+
+// reloadConfig reloads configuration from disk. The main loop picks up the
+// resulting change from d.changes.
+func (d *Daemon) reloadConfig() {
+	if _, err := d.store.Reload(); err != nil {
+		d.logger.Errorf("Failed to reload config: %v", err)
+	}
+}
+
+// configChanged reacts to a reloaded config
+func (d *Daemon) configChanged(change config.Change) {
+	d.logger.SetLevel(change.New.GetLogLevel())
+	// Temporary unlocks and lock hours may have changed
+	d.writePromptState(d.currentPromptState(), time.Now())
+
+	// Locked paths or filters may have changed, walk directories afresh
+	locker.ClearScanCache()
+	d.checkDirectEdit(change.New, change.Old, time.Now())
+
+	// Exclusions that appeared since the last load were made by temp-unlock,
+	// unless the config was edited without it
+	if d.active && !change.New.EditedDirectly() {
+		previous := change.Old.ActiveExcludes()
+		for _, path := range change.New.ActiveExcludes() {
+			// Postponed re-locks are extended by the daemon itself
+			if _, deferred := d.relockDeferrals[path]; deferred {
+				continue
+			}
+			if !slices.Contains(previous, path) {
+				d.logger.Infof("Path temporarily unlocked: %s", path)
+				d.emit(hooks.EventTempUnlock, path)
+			}
+		}
+	}
 }
 
 // clearWatchers removes all file system watchers
@@ -234,9 +995,15 @@ func (d *Daemon) setupWatchers() error {
 		d.watcher.Remove(path)
 	}
 
-	// Add watches for all locked paths
-	for _, path := range d.cfg.LockedPaths {
-		if err := d.addWatch(path); err != nil {
+	// Add watches for all locked paths, trying again those that hit the
+	// watch limit before
+	clear(d.sweepOnly)
+	for _, path := range d.lockedPaths(time.Now()) {
+		err := d.addWatch(path)
+		if hint, limited := watchLimitHint(err); limited {
+			d.sweepOnly[path] = true
+			d.logger.Errorf("Watch limit reached, %s is only enforced by the periodic sweep: %v. Raise the limit with: %s", path, err, hint)
+		} else if err != nil {
 			d.logger.Warnf("Failed to watch %s: %v", path, err)
 		}
 	}
@@ -244,108 +1011,578 @@ func (d *Daemon) setupWatchers() error {
 	return nil
 }
 
+// watchLimitHint reports whether err means the system ran out of file
+// watches, and how to raise the limit
+func watchLimitHint(err error) (string, bool) {
+	switch {
+	case errors.Is(err, syscall.ENOSPC):
+		return "sudo sysctl fs.inotify.max_user_watches=524288", true
+	case errors.Is(err, syscall.EMFILE):
+		if runtime.GOOS == "linux" {
+			return "sudo sysctl fs.inotify.max_user_instances=1024", true
+		}
+		// kqueue uses a file descriptor per watch
+		return "ulimit -n 10240 (or launchctl limit maxfiles)", true
+	}
+	return "", false
+}
+
 // addWatch adds a path to the watcher
 func (d *Daemon) addWatch(path string) error {
-	// Check if path exists
+	var lastErr error
+	for _, target := range watchTargets(path) {
+		if err := d.watcher.Add(target); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// watchTargets returns the paths to watch for a locked path.
+// Locked files are watched together with their parent directory, so that a
+// file that is deleted and recreated (losing its own watch) is noticed.
+// Missing files are watched through their parent only, until they reappear.
+func watchTargets(path string) []string {
 	info, err := os.Stat(path)
 	if err != nil {
-		return err
+		return []string{filepath.Dir(path)}
 	}
 
 	// If it's a directory, watch it
 	if info.IsDir() {
-		return d.watcher.Add(path)
+		return []string{path}
 	}
 
-	// For files, watch the file itself
-	return d.watcher.Add(path)
+	// For files, watch the file itself and its parent directory
+	return []string{path, filepath.Dir(path)}
+}
+
+// ensureWatches re-establishes watches lost because a locked file or its
+// parent directory was deleted and recreated
+func (d *Daemon) ensureWatches() {
+	watched := make(map[string]bool)
+	for _, path := range d.watcher.WatchList() {
+		watched[path] = true
+	}
+
+	for _, path := range d.lockedPaths(time.Now()) {
+		// Retried when the config changes rather than on every sweep
+		if d.sweepOnly[path] {
+			continue
+		}
+		for _, target := range watchTargets(path) {
+			if watched[target] {
+				continue
+			}
+			if err := d.watcher.Add(target); err == nil {
+				watched[target] = true
+				d.logger.Infof("Re-established watch on %s", target)
+			}
+		}
+	}
 }
 
 // enforce applies locks to all configured paths if within lock hours
-func (d *Daemon) enforce() {
-	// Clean expired temporary exclusions and save only if something was cleaned
-	if d.cfg.CleanExpiredExcludes() {
-		if err := d.cfg.Save(); err != nil {
+func (d *Daemon) enforce(now time.Time) {
+	// Give editors a chance to save before expired exclusions are re-locked
+	d.snapshotTempExcludes()
+	expired := d.cfg().ExpiredExcludes()
+	var deferred []string
+	for _, path := range expired {
+		if d.deferRelockIfOpen(path) {
+			deferred = append(deferred, path)
+		}
+	}
+
+	// Clean expired temporary exclusions and save only if something changed.
+	// The update is applied to the config on disk so changes made by the CLI
+	// since the last reload aren't overwritten.
+	for _, path := range expired {
+		if !slices.Contains(deferred, path) {
+			// Re-lock in full, even if the path still looks locked
+			delete(d.health, path)
+		}
+	}
+
+	if len(expired) > 0 {
+		cfg, err := config.Update(func(cfg *config.Config) error {
+			for _, path := range deferred {
+				cfg.AddTempExclude(path, relockGraceMinutes)
+			}
+			cfg.CleanExpiredExcludes()
+			return nil
+		})
+		if err != nil {
 			d.logger.Errorf("Failed to save config after cleaning exclusions: %v", err)
+		} else {
+			d.store.Set(cfg)
 		}
 	}
 
-	d.logger.Info("Enforcing locks")
+	d.ensureWatches()
+
+	d.logger.Debug("Enforcing locks")
 
-	for _, path := range d.cfg.LockedPaths {
-		if d.cfg.IsTemporarilyExcluded(path) {
-			d.logger.Infof("Skipping temporarily excluded path: %s", path)
+	enforced := 0
+	for _, path := range d.lockedPaths(now) {
+		if d.ctx.Err() != nil {
+			d.logger.Info("Shutting down, enforcement interrupted")
+			return
+		}
+		// Locking large trees can take longer than the watchdog timeout
+		sdnotify.Notify(sdnotify.Watchdog)
+		if d.isTemporarilyExcluded(path, now) {
+			d.logger.Debugf("Skipping temporarily excluded path: %s", path)
+			d.summary.excluded++
+			continue
+		}
+		if !d.isPathActive(path, now) {
 			continue
 		}
-		d.lockPath(path)
+		d.lockPath(path, now)
+		enforced++
 	}
+	d.lastEnforced = time.Now()
+	d.recordSweep(now, enforced)
+}
+
+// nextSweep returns the delay until the next enforcement sweep. It doubles
+// after each clean sweep up to maxSweepInterval and drops back to
+// minSweepInterval after a violation, a lock that had to be re-applied or
+// while any locked path is unwatched or failing to lock.
+func (d *Daemon) nextSweep() time.Duration {
+	if d.violated || len(d.sweepOnly) > 0 || d.lockFailing() {
+		d.sweepEvery = minSweepInterval
+	} else {
+		d.sweepEvery = min(d.sweepEvery*2, maxSweepInterval)
+	}
+	d.violated = false
+	return d.sweepEvery
+}
+
+// tightenSweep brings the next sweep forward to minSweepInterval after a
+// violation while the sweep has backed off
+func (d *Daemon) tightenSweep(timer *time.Timer) {
+	if !d.active || !d.violated || d.sweepEvery <= minSweepInterval {
+		return
+	}
+	d.logger.Infof("Sweeping every %s again after a change", minSweepInterval)
+	d.sweepEvery = minSweepInterval
+	timer.Reset(minSweepInterval)
+}
+
+// lockFailing reports whether the last lock of any locked path failed
+func (d *Daemon) lockFailing() bool {
+	for _, health := range d.health {
+		if health.Error != "" || health.Failed > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotTempExcludes records the latest modification time of newly seen
+// temporary exclusions so changes can be detected when they expire
+func (d *Daemon) snapshotTempExcludes() {
+	for _, path := range d.cfg().ActiveExcludes() {
+		if _, seen := d.unlockSnapshots[path]; seen {
+			continue
+		}
+		if modTime, err := fileutil.LatestModTime(path); err == nil {
+			d.unlockSnapshots[path] = modTime
+		}
+	}
+}
+
+// deferRelockIfOpen postpones re-locking an expired temporary exclusion while
+// an application still has the path open, so unsaved work isn't lost to a
+// sudden immutable flag. Returns true if the exclusion should be extended.
+func (d *Daemon) deferRelockIfOpen(path string) bool {
+	snapshot, seen := d.unlockSnapshots[path]
+	changed := false
+	if modTime, err := fileutil.LatestModTime(path); err == nil && seen {
+		changed = modTime.After(snapshot)
+	}
+	if changed {
+		d.logger.Infof("Path was modified during temporary unlock: %s", path)
+	}
+
+	open, err := locker.IsOpen(path)
+	if err != nil || !open || d.relockDeferrals[path] >= maxRelockDeferrals {
+		delete(d.unlockSnapshots, path)
+		delete(d.relockDeferrals, path)
+		return false
+	}
+
+	d.relockDeferrals[path]++
+	d.logger.Infof("Path still open, postponing re-lock by %d minutes (%d/%d): %s",
+		relockGraceMinutes, d.relockDeferrals[path], maxRelockDeferrals, path)
+
+	message := fmt.Sprintf("%s is still open and will be locked in %d minutes.\nSave your work now.", filepath.Base(path), relockGraceMinutes)
+	if changed {
+		message = fmt.Sprintf("%s was edited and is still open. It will be locked in %d minutes.\nSave your work now.", filepath.Base(path), relockGraceMinutes)
+	}
+	d.notify("ConfigLock", message)
+
+	return true
 }
 
 // handleFileEvent processes a file system event and re-locks the appropriate path
-func (d *Daemon) handleFileEvent(eventPath string) {
-	// Ignore events on configlock's own config file to prevent feedback loop
+func (d *Daemon) handleFileEvent(event fsnotify.Event) {
+	eventPath := event.Name
+	now := time.Now()
+
+	// Ignore events on configlock's own config file and logs to prevent feedback loop
 	configDir := config.GetConfigDir()
 	configPath := config.GetConfigPath()
-	if eventPath == configPath || eventPath == configDir ||
-		strings.HasPrefix(eventPath, configDir+string(filepath.Separator)) {
+	if eventPath == configPath || pathutil.Within(eventPath, configDir) || d.isOwnLog(eventPath) {
 		return
 	}
 
+	// A changed file may now pass or fail the size and binary filters
+	locker.InvalidateScan(eventPath)
+
 	// Find all locked paths that match or contain this event path
-	for _, lockedPath := range d.cfg.LockedPaths {
-		// Skip if temporarily excluded
-		if d.cfg.IsTemporarilyExcluded(lockedPath) {
+	for _, lockedPath := range d.lockedPaths(now) {
+		// Skip if temporarily excluded or its schedule is not active
+		if d.isTemporarilyExcluded(lockedPath, now) || !d.isPathActive(lockedPath, now) {
+			continue
+		}
+
+		// Editors that save by renaming a temp file over the original leave a
+		// new, unlocked inode behind and drop the watch on the old one.
+		// A create seen through the parent directory watch means the file reappeared.
+		if eventPath == lockedPath && event.Has(fsnotify.Rename|fsnotify.Remove) {
+			d.handleReplaced(lockedPath)
+			continue
+		}
+		if eventPath == lockedPath && event.Has(fsnotify.Create) {
+			d.relockReplaced(lockedPath, now)
 			continue
 		}
 
 		// Check if event path is the locked path itself or within it
-		if eventPath == lockedPath || strings.HasPrefix(eventPath, lockedPath+string(filepath.Separator)) {
+		if eventPath == lockedPath {
+			if d.trustedChange(lockedPath, now) {
+				continue
+			}
 			d.logger.Infof("Event detected on locked path %s, re-applying lock", lockedPath)
-			d.sendManualChangeNotification(lockedPath)
-			d.lockPath(lockedPath)
+			d.reportViolation(lockedPath)
+			d.lockPath(lockedPath, now)
+		} else if pathutil.Inside(eventPath, lockedPath) {
+			// Files covered by a scoped temp-unlock may be edited
+			if d.isTemporarilyExcluded(eventPath, now) {
+				continue
+			}
+			// Subdirectories of a directory added with --recursive=false
+			// are left alone
+			if d.cfg().IsShallow(lockedPath) {
+				if info, err := os.Lstat(eventPath); err == nil && info.IsDir() {
+					continue
+				}
+			}
+			// Files the filters or excluded_files leave out of locking the
+			// directory are left out here too
+			if !locker.Covers(lockedPath, eventPath) {
+				continue
+			}
+			if d.trustedChange(eventPath, now) {
+				continue
+			}
+			// Lock the affected entry itself: a file created or renamed into a
+			// locked directory is a new inode that checking the directory misses
+			d.logger.Infof("Event detected in locked path %s, re-applying lock to %s", lockedPath, eventPath)
+			d.reportViolation(eventPath)
+			d.lockPath(eventPath, now)
 		}
 	}
 }
 
-// sendManualChangeNotification sends a system notification when manual changes are detected
+// handleReplaced re-locks a locked file that was renamed over or removed.
+// If the path does not exist yet, it is checked again shortly, since editors
+// remove or rename the original before moving the new file into place.
+func (d *Daemon) handleReplaced(path string) {
+	if _, err := os.Stat(path); err != nil {
+		time.AfterFunc(replaceRecheckDelay, func() {
+			select {
+			case d.recheckCh <- path:
+			case <-d.stopCh:
+			}
+		})
+		return
+	}
+	d.relockReplaced(path, time.Now())
+}
+
+// recheckReplaced re-locks a replaced file once it has reappeared.
+// If it is still missing, the periodic sweep takes over.
+func (d *Daemon) recheckReplaced(path string) {
+	if d.isTemporarilyExcluded(path, time.Now()) {
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		d.logger.Warnf("Locked file was removed and has not reappeared: %s", path)
+		return
+	}
+	d.relockReplaced(path, time.Now())
+}
+
+// relockReplaced moves the watch over to the new inode of a replaced locked
+// file and, unless a trusted process replaced it, treats the replacement as
+// a violation and re-locks it
+func (d *Daemon) relockReplaced(path string, now time.Time) {
+	d.watcher.Remove(path)
+	if err := d.addWatch(path); err != nil {
+		d.logger.Warnf("Failed to watch %s: %v", path, err)
+	}
+	if d.trustedChange(path, now) {
+		return
+	}
+
+	d.logger.Warnf("Locked file was replaced (write via rename): %s", path)
+	d.reportViolation(path)
+	d.lockPath(path, now)
+}
+
+// reportViolation notifies the user about a change to a locked path and
+// runs the violation hook
+func (d *Daemon) reportViolation(path string) {
+	d.violated = true
+	d.sendManualChangeNotification(path)
+	d.emit(hooks.EventViolation, path)
+}
+
+// emit records a lifecycle event for reports, runs the hook configured for
+// it and publishes it to MQTT, the last two in the background
+func (d *Daemon) emit(event, path string) {
+	e := hooks.Event{Name: event, Path: path, Time: time.Now()}
+	if event == hooks.EventTempUnlock {
+		e.Reason = d.cfg().TempExcludeReason(path)
+	}
+	if err := stats.Record(stats.Event{Name: e.Name, Path: e.Path, Time: e.Time, Reason: e.Reason}); err != nil {
+		d.logger.Warnf("Failed to record %s event: %v", event, err)
+	}
+
+	command := map[string]string{
+		hooks.EventActivate:   d.cfg().Hooks.OnActivate,
+		hooks.EventDeactivate: d.cfg().Hooks.OnDeactivate,
+		hooks.EventViolation:  d.cfg().Hooks.OnViolation,
+		hooks.EventTempUnlock: d.cfg().Hooks.OnTempUnlock,
+	}[event]
+	if command != "" {
+		go func() {
+			if err := hooks.Run(command, e); err != nil {
+				d.logger.Warnf("%v", err)
+			}
+		}()
+	}
+
+	if d.cfg().MQTTURL != "" {
+		go d.publishEvent(d.cfg().MQTTURL, d.cfg().GetMQTTTopic(), e)
+	}
+}
+
+// mqttEvent is the JSON payload published for each event
+type mqttEvent struct {
+	Event  string `json:"event"`
+	Path   string `json:"path,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	Time   string `json:"time"`
+}
+
+// publishEvent publishes an event to <topic>/event. Activation changes are
+// also published, retained, to <topic>/state as "locked" or "unlocked" so
+// dashboards show the current state.
+func (d *Daemon) publishEvent(brokerURL, topic string, e hooks.Event) {
+	payload, _ := json.Marshal(mqttEvent{Event: e.Name, Path: e.Path, Reason: e.Reason, Time: e.Time.Format(time.RFC3339)})
+	if err := mqtt.Publish(brokerURL, topic+"/event", payload, false); err != nil {
+		d.logger.Warnf("Failed to publish %s event: %v", e.Name, err)
+		return
+	}
+
+	state := map[string]string{hooks.EventActivate: "locked", hooks.EventDeactivate: "unlocked"}[e.Name]
+	if state == "" {
+		return
+	}
+	if err := mqtt.Publish(brokerURL, topic+"/state", []byte(state), true); err != nil {
+		d.logger.Warnf("Failed to publish state: %v", err)
+	}
+}
+
+// notify sends a system notification unless notifications are turned off.
+// Failures are only logged.
+func (d *Daemon) notify(title, message string) {
+	if d.cfg().NotificationLevel(time.Now()) == config.NotificationsOff {
+		return
+	}
+	if err := d.notifier.Notify(title, message); err != nil {
+		d.logger.Warnf("Failed to send notification: %v", err)
+	}
+}
+
+// sendManualChangeNotification sends a system notification when manual
+// changes are detected, at most once per path per NotifyEvery so an editor
+// retrying a save doesn't cause a storm of alerts
 func (d *Daemon) sendManualChangeNotification(path string) {
+	now := time.Now()
+	if d.cfg().NotificationLevel(now) != config.NotificationsNormal {
+		return
+	}
+	if !d.throttle.Allow(path, now, d.cfg().NotifyEvery()) {
+		d.logger.Infof("Change alert for %s throttled", path)
+		return
+	}
+
 	title := "ConfigLock Alert"
 	message := fmt.Sprintf("Detected manual change to locked file: %s\nConfigLock will re-apply the lock.", filepath.Base(path))
+	if runtime.GOOS == "darwin" {
+		// Alerts wait for a click, so don't hold up the main loop
+		go d.askTempUnlock(path, title, message)
+		return
+	}
+	d.notify(title, message)
+}
 
-	if err := d.notifier.Notify(title, message); err != nil {
-		d.logger.Warnf("Failed to send notification: %v", err)
-		// Don't fail the entire operation if notification fails
+// tempUnlockAction is the alert button that starts a temp-unlock
+const tempUnlockAction = "Temp-unlock 5 min"
+
+// askTempUnlock shows a change alert offering a short temp-unlock. Accepting
+// opens a terminal running 'configlock temp-unlock', so the typing challenge
+// still applies and the unlock reaches the daemon over the control socket.
+func (d *Daemon) askTempUnlock(path, title, message string) {
+	button, err := d.notifier.Ask(title, message, []string{"Dismiss", tempUnlockAction})
+	if err != nil {
+		d.logger.Warnf("%v", err)
+		d.notify(title, message)
+		return
+	}
+	if button != tempUnlockAction {
+		return
+	}
+
+	lockedPath := d.lockedPathFor(path)
+	d.logger.Infof("Temp-unlock of %s requested from alert", lockedPath)
+	if err := terminal.Run("temp-unlock", lockedPath, "--duration", "5"); err != nil {
+		d.logger.Warnf("Failed to start temp-unlock: %v", err)
 	}
 }
 
+// lockedPathFor returns the configured locked path that contains path
+func (d *Daemon) lockedPathFor(path string) string {
+	for _, lockedPath := range d.cfg().LockedPaths {
+		if pathutil.Within(path, lockedPath) {
+			return lockedPath
+		}
+	}
+	return path
+}
+
+// sendHeartbeat pings the configured dead man's switch URL
+// A missed heartbeat while locks should be enforced alerts whoever monitors the check
+func (d *Daemon) sendHeartbeat(url string) {
+	if err := heartbeat.Ping(url); err != nil {
+		d.logger.Warnf("Heartbeat failed: %v", err)
+	}
+}
+
+// checkUpgrade announces a new release with a desktop notification, once per
+// release. It is low priority, so it waits while notifications are quiet.
+func (d *Daemon) checkUpgrade() {
+	upgrade.NotifyOnce(d.version, func(latest string) bool {
+		if d.cfg().NotificationLevel(time.Now()) != config.NotificationsNormal {
+			return false
+		}
+		d.logger.Infof("A new version of configlock is available: %s (current: %s)", latest, d.version)
+		d.notify("ConfigLock Update",
+			fmt.Sprintf("configlock %s is available (current: %s).\n%s", latest, d.version, upgrade.Instructions))
+		return true
+	})
+}
+
 // sendKillNotification sends a system notification when daemon was killed abnormally
 func (d *Daemon) sendKillNotification() {
 	title := "ConfigLock Alert"
 	message := "ConfigLock daemon was killed and has been restarted.\nYour config files are now protected again."
-
-	if err := d.notifier.Notify(title, message); err != nil {
-		d.logger.Warnf("Failed to send kill notification: %v", err)
-	}
+	d.notify(title, message)
 }
 
 // lockPath applies a lock to a specific path if not already locked
-func (d *Daemon) lockPath(path string) {
+func (d *Daemon) lockPath(path string, now time.Time) {
+	isLockedPath := slices.Contains(d.lockedPaths(now), path)
 	if _, err := os.Stat(path); err != nil {
-		d.logger.Warnf("Path no longer exists: %s", path)
+		if isLockedPath {
+			d.pathMissing(path, now)
+		} else {
+			d.logger.Warnf("Path no longer exists: %s", path)
+		}
 		return
 	}
+	if isLockedPath {
+		d.pathFound(path)
+	}
 
-	if d.cfg.IsTemporarilyExcluded(path) {
+	if d.isTemporarilyExcluded(path, now) {
 		return
 	}
 
-	// Skip if already locked
-	if locked, err := locker.IsLocked(path); err == nil && locked {
+	// Paths bound to a schedule that is not active stay unlocked
+	if !d.isPathActive(path, now) {
 		return
 	}
 
-	d.logger.Infof("Locking: %s", path)
-	if err := locker.Lock(path); err != nil {
-		d.logger.Errorf("Failed to lock %s: %v", path, err)
+	// Skip if already locked, once a full lock this activation has recorded
+	// how many files of the path carry the lock. A sample of the files of a
+	// directory is read, not just the directory itself.
+	health, known := d.health[path]
+	if len(health.Busy) > 0 {
+		health = d.retryBusy(path, health)
+	}
+	if known || !isLockedPath {
+		status, err := locker.CheckLockedExcept(d.ctx, path, locker.CheckSampled, d.tempExcludeScope(path, now))
+		if err == nil && (status.AllLocked() || lockedExceptBusy(status, health.Busy)) {
+			return
+		}
+	}
+
+	if known {
+		d.logger.Infof("Re-locking %s: its lock is missing", path)
+		d.summary.relocked++
+	} else {
+		d.logger.Infof("Locking: %s", path)
+	}
+	d.violated = true
+	// Files of a scoped temp-unlock stay unlocked
+	report, err := locker.LockExcept(d.ctx, path, d.tempExcludeScope(path, now))
+	if err == nil {
+		err = report.Err()
+	}
+	if d.ctx.Err() != nil {
+		return
+	}
+	if report != nil && len(report.NewlyExcluded) > 0 {
+		d.excludeFailed(path, report.NewlyExcluded)
+	}
+	if report != nil && isLockedPath {
+		health := ipc.PathHealth{
+			Locked:   report.Locked,
+			Failed:   len(report.Failed),
+			Excluded: report.Excluded + len(report.NewlyExcluded),
+			Busy:     report.Busy,
+			LockedAt: now,
+		}
+		if err != nil {
+			health.Error = err.Error()
+		}
+		d.health[path] = health
+	}
+	switch {
+	case err != nil:
+		d.logLockError("lock", path, err)
+	case report == nil:
+	case len(report.Busy) > 0:
+		d.logger.Infof("Locked: %s (%d file(s)), leaving %d open for writing until they are closed: %s",
+			path, report.Locked, len(report.Busy), strings.Join(report.Busy, ", "))
+	default:
+		d.logger.Infof("Locked: %s (%d file(s))", path, report.Locked)
 	}
 }
@@ -0,0 +1,160 @@
+package daemon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/locker"
+	"github.com/baggiiiie/configlock/internal/sdnotify"
+)
+
+// interimListed is how many changed files the summary notification names
+const interimListed = 3
+
+// interimSnapshot records the content of the locked files when lock hours
+// end, so the changes made outside lock hours can be reported when they
+// start again
+type interimSnapshot struct {
+	Time   time.Time                    `json:"time"`
+	Hashes map[string]map[string]string `json:"hashes"` // locked path -> file -> sha256
+}
+
+// interimStatePath returns the file the snapshot is kept in, so it survives
+// daemon restarts and reboots outside lock hours
+func interimStatePath() string {
+	return filepath.Join(config.GetCacheDir(), "offhours.json")
+}
+
+// hashFile returns the hex sha256 of a file's content
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashPath returns the hashes of the files locking path covers. Files that
+// can't be read are left out.
+func (d *Daemon) hashPath(path string) (map[string]string, error) {
+	files, err := locker.CoveredFiles(d.ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string]string, len(files))
+	for _, file := range files {
+		if sum, err := hashFile(file); err == nil {
+			hashes[file] = sum
+		}
+	}
+	return hashes, nil
+}
+
+// recordInterimSnapshot hashes the locked files when leaving lock hours
+func (d *Daemon) recordInterimSnapshot(now time.Time) {
+	snapshot := interimSnapshot{Time: now, Hashes: make(map[string]map[string]string)}
+	for _, path := range d.cfg().LockedPaths {
+		if d.ctx.Err() != nil {
+			return
+		}
+		// Hashing large trees can take longer than the watchdog timeout
+		sdnotify.Notify(sdnotify.Watchdog)
+		hashes, err := d.hashPath(path)
+		if err != nil {
+			continue
+		}
+		snapshot.Hashes[path] = hashes
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err == nil {
+		err = os.MkdirAll(config.GetCacheDir(), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(interimStatePath(), data, 0o600)
+	}
+	if err != nil {
+		d.logger.Warnf("Failed to record locked files for the off-hours summary: %v", err)
+	}
+}
+
+// reportInterimChanges compares the locked files with the snapshot taken
+// when lock hours last ended and reports which of them changed since. This
+// is only a summary: changes made outside lock hours are allowed.
+func (d *Daemon) reportInterimChanges() {
+	data, err := os.ReadFile(interimStatePath())
+	if err != nil {
+		return
+	}
+	var snapshot interimSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		_ = os.Remove(interimStatePath())
+		return
+	}
+
+	var changed []string
+	for _, path := range d.cfg().LockedPaths {
+		if d.ctx.Err() != nil {
+			return
+		}
+		before, ok := snapshot.Hashes[path]
+		if !ok {
+			continue
+		}
+		sdnotify.Notify(sdnotify.Watchdog)
+		after, err := d.hashPath(path)
+		if err != nil {
+			after = nil
+		}
+		for file, sum := range after {
+			switch previous, existed := before[file]; {
+			case !existed:
+				changed = append(changed, file+" (added)")
+			case previous != sum:
+				changed = append(changed, file)
+			}
+		}
+		for file := range before {
+			if _, exists := after[file]; !exists {
+				changed = append(changed, file+" (removed)")
+			}
+		}
+	}
+	_ = os.Remove(interimStatePath())
+
+	since := snapshot.Time.Format("Mon 15:04")
+	if len(changed) == 0 {
+		d.logger.Infof("No locked files changed since lock hours ended (%s)", since)
+		return
+	}
+	slices.Sort(changed)
+	d.logger.Infof("%d locked file(s) changed since lock hours ended (%s):", len(changed), since)
+	for _, file := range changed {
+		d.logger.Infof("  %s", file)
+	}
+
+	names := make([]string, 0, interimListed)
+	for _, file := range changed[:min(len(changed), interimListed)] {
+		names = append(names, filepath.Base(file))
+	}
+	message := strings.Join(names, ", ")
+	if more := len(changed) - len(names); more > 0 {
+		message += fmt.Sprintf(" and %d more", more)
+	}
+	d.notify("ConfigLock: Changed Since "+since,
+		fmt.Sprintf("%d locked file(s) changed outside lock hours: %s.\nSee 'configlock logs' for the full list.", len(changed), message))
+}
@@ -0,0 +1,135 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/baggiiiie/configlock/internal/locker"
+	"github.com/baggiiiie/configlock/internal/logger"
+	"github.com/baggiiiie/configlock/internal/stats"
+)
+
+// protectedLogs returns the files kept append-only during lock hours: the
+// daemon log, its backup from the last rotation if there is one, and the
+// stats store, which records unlocks and challenges
+func protectedLogs(logPath string) []string {
+	var paths []string
+	if logPath != "" {
+		paths = append(paths, logPath)
+		if _, err := os.Stat(logPath + ".old"); err == nil {
+			paths = append(paths, logPath+".old")
+		}
+	}
+	return append(paths, stats.GetStatsPath())
+}
+
+// AppendOnlyFiles returns the files the daemon makes append-only during lock
+// hours, including the log backup a rotation will create
+func AppendOnlyFiles() []string {
+	logPath := logger.GetLogger().GetLogPath()
+	paths := protectedLogs(logPath)
+	if logPath != "" && !slices.Contains(paths, logPath+".old") {
+		paths = append(paths, logPath+".old")
+	}
+	return paths
+}
+
+// protectLogs makes the logs append-only so entries about unlock attempts
+// can't be erased during lock hours
+func (d *Daemon) protectLogs() {
+	for _, path := range protectedLogs(d.logger.GetLogPath()) {
+		// The flag can only be set on an existing file
+		if f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644); err == nil {
+			f.Close()
+		}
+		if err := locker.SetAppendOnly(path, true); err != nil {
+			d.logger.Warnf("Failed to make %s append-only: %v", path, err)
+		}
+	}
+	d.logsProtected.Store(true)
+}
+
+// isOwnLog reports whether path is one of the daemon's logs, which change on
+// every entry and on rotation
+func (d *Daemon) isOwnLog(path string) bool {
+	logPath := d.logger.GetLogPath()
+	if logPath != "" && path == logPath+".old" {
+		return true
+	}
+	return slices.Contains(protectedLogs(logPath), path)
+}
+
+// guardRotation lifts the protection of the log file and its backup while
+// the logger rotates it: the append-only flag set during lock hours, and the
+// lock of a locked path the log directory is in. Either makes the rename
+// fail, leaving the log to grow.
+func (d *Daemon) guardRotation(logPath, backupPath string) (func(), error) {
+	protected := d.logsProtected.Load()
+	var relock []string
+	var errs []error
+	for _, path := range []string{filepath.Dir(logPath), logPath, backupPath} {
+		if _, err := os.Lstat(path); err != nil {
+			continue
+		}
+		if protected && path != filepath.Dir(logPath) {
+			if err := locker.SetAppendOnly(path, false); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			}
+		}
+		locked, err := locker.UnlockEntry(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		} else if locked {
+			relock = append(relock, path)
+		}
+	}
+
+	restore := func() {
+		if protected {
+			for _, path := range []string{logPath, backupPath} {
+				if err := locker.SetAppendOnly(path, true); err != nil {
+					d.logger.Warnf("Failed to make %s append-only after rotation: %v", path, err)
+				}
+			}
+		}
+		for _, path := range relock {
+			if err := locker.LockEntry(path); err != nil {
+				d.logger.Warnf("Failed to lock %s again after log rotation: %v", path, err)
+			}
+		}
+	}
+	return restore, errors.Join(errs...)
+}
+
+// releaseLogs clears the append-only flag of the logs, logging failures
+func (d *Daemon) releaseLogs() {
+	d.logsProtected.Store(false)
+	if err := releaseLogFiles(d.logger.GetLogPath()); err != nil {
+		d.logger.Warnf("Failed to clear append-only flag: %v", err)
+	}
+}
+
+// ReleaseLogs clears the append-only flag protectLogs sets. The daemon does
+// this itself outside lock hours and when it stops; 'configlock reset' calls
+// it in case the daemon was killed before it could.
+func ReleaseLogs() error {
+	return releaseLogFiles(logger.GetLogger().GetLogPath())
+}
+
+// releaseLogFiles clears the append-only flag of the logs for the log file
+// at logPath
+func releaseLogFiles(logPath string) error {
+	var lastErr error
+	for _, path := range protectedLogs(logPath) {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if err := locker.SetAppendOnly(path, false); err != nil {
+			lastErr = fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return lastErr
+}
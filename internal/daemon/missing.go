@@ -0,0 +1,91 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+)
+
+// missingStatePath returns the file recording when each missing locked path
+// was first found missing, kept across daemon restarts so the prune delay
+// isn't reset by every restart
+func missingStatePath() string {
+	return filepath.Join(config.GetCacheDir(), "missing.json")
+}
+
+// loadMissing reads when locked paths were first found missing; a missing
+// or corrupt file is empty
+func loadMissing() map[string]time.Time {
+	missing := make(map[string]time.Time)
+	if data, err := os.ReadFile(missingStatePath()); err == nil {
+		_ = json.Unmarshal(data, &missing)
+	}
+	return missing
+}
+
+// saveMissing writes d.missing, dropping paths no longer in the lock list
+func (d *Daemon) saveMissing() {
+	locked := d.cfg().LockedPaths
+	for path := range d.missing {
+		if !slices.Contains(locked, path) {
+			delete(d.missing, path)
+		}
+	}
+
+	data, err := json.MarshalIndent(d.missing, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(config.GetCacheDir(), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(missingStatePath(), data, 0o600)
+	}
+	if err != nil {
+		d.logger.Warnf("Failed to save missing paths: %v", err)
+	}
+}
+
+// pathMissing handles a locked path that no longer exists: the first time
+// it warns and notifies, and once it has been missing for
+// prune_missing_after_days it is removed from the lock list
+func (d *Daemon) pathMissing(path string, now time.Time) {
+	since, known := d.missing[path]
+	if !known {
+		d.missing[path] = now
+		d.saveMissing()
+		d.logger.Warnf("Locked path no longer exists: %s", path)
+		d.notify("ConfigLock: Path Missing",
+			fmt.Sprintf("%s no longer exists.\nRun 'configlock prune' to remove it from the lock list.", path))
+		return
+	}
+
+	after := d.cfg().PruneMissingAfter()
+	if after == 0 || now.Sub(since) < after {
+		return
+	}
+	cfg, err := config.Update(func(cfg *config.Config) error {
+		cfg.RemovePath(path)
+		return nil
+	})
+	if err != nil {
+		d.logger.Errorf("Failed to remove missing path %s: %v", path, err)
+		return
+	}
+	d.store.Set(cfg)
+	d.saveMissing()
+	d.logger.Infof("Removed %s from the lock list, missing since %s", path, since.Format(time.DateOnly))
+}
+
+// pathFound forgets that a locked path was missing once it exists again
+func (d *Daemon) pathFound(path string) {
+	if _, known := d.missing[path]; !known {
+		return
+	}
+	delete(d.missing, path)
+	d.saveMissing()
+	d.logger.Infof("Locked path exists again: %s", path)
+}
@@ -0,0 +1,83 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+)
+
+// Lock states written to the prompt state file
+const (
+	promptLocked   = "locked"   // within lock hours, locks enforced
+	promptUnlocked = "unlocked" // outside lock hours
+	promptStopped  = "stopped"  // the daemon shut down
+)
+
+// promptStatePath returns the file shell prompts and status bars read the
+// lock state from without running anything. It holds one line:
+//
+//	<state> <next transition, Unix seconds or 0> <temporarily unlocked paths>
+//
+// e.g. "locked 1760626800 1". The format is stable; fields may be added at
+// the end of the line, so readers should ignore any they don't know.
+func promptStatePath() string {
+	return filepath.Join(config.GetCacheDir(), "state")
+}
+
+// writePromptState updates the prompt state file if the state changed. The
+// file is replaced by a rename, so readers never see it half written.
+func (d *Daemon) writePromptState(state string, now time.Time) {
+	var until int64
+	if state != promptStopped {
+		if next, ok := d.cfg().NextTransition(now); ok {
+			until = next.Unix()
+		}
+	}
+	line := fmt.Sprintf("%s %d %d\n", state, until, len(d.cfg().ActiveExcludes()))
+	if line == d.promptState {
+		return
+	}
+
+	if err := writeAtomic(promptStatePath(), []byte(line)); err != nil {
+		d.logger.Warnf("Failed to write prompt state: %v", err)
+		return
+	}
+	d.promptState = line
+}
+
+// currentPromptState returns the state for the prompt state file while the
+// daemon runs
+func (d *Daemon) currentPromptState() string {
+	if d.active {
+		return promptLocked
+	}
+	return promptUnlocked
+}
+
+// writeAtomic writes data to a temporary file next to path and renames it
+// over path
+func writeAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,35 @@
+package daemon
+
+import "time"
+
+// summaryEvery is how often enforcement is summarized in one log line. Sweeps
+// themselves only log what they change.
+const summaryEvery = time.Hour
+
+// sweepSummary counts enforcement since the last summary line
+type sweepSummary struct {
+	since    time.Time
+	sweeps   int
+	relocked int // paths locked again because their lock was missing
+	excluded int // times a temporarily excluded path was skipped
+}
+
+// recordSweep counts a finished sweep over paths locked paths, logging the
+// summary once summaryEvery has passed since the last one
+func (d *Daemon) recordSweep(now time.Time, paths int) {
+	d.summary.sweeps++
+	d.lastSweepPaths = paths
+	if now.Sub(d.summary.since) >= summaryEvery {
+		d.logSummary(now)
+	}
+}
+
+// logSummary logs the enforcement since the last summary, if there was any,
+// and starts counting afresh
+func (d *Daemon) logSummary(now time.Time) {
+	if s := d.summary; s.sweeps > 0 {
+		d.logger.Infof("Enforcement over the last %s: %d sweep(s) of %d locked path(s), %d re-locked, %d skipped as temporarily excluded",
+			now.Sub(s.since).Round(time.Second), s.sweeps, d.lastSweepPaths, s.relocked, s.excluded)
+	}
+	d.summary = sweepSummary{since: now}
+}
@@ -0,0 +1,280 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/hooks"
+	"github.com/baggiiiie/configlock/internal/locker"
+	"github.com/baggiiiie/configlock/internal/pathutil"
+)
+
+// heldPolicy keeps the policy from before an edit of the config made without
+// the CLI in force until the end of the day
+type heldPolicy struct {
+	cfg      *config.Config // config as configlock last saved it
+	paths    []string       // locked paths the edit removed or made expire sooner
+	excludes []string       // paths the edit temporarily unlocked
+	grace    []string       // locked paths whose grace period the edit extended
+	schedule bool           // the edit shortened the current lock period
+	trusted  bool           // the edit added trusted processes
+	settings bool           // the edit weakened locker settings or strictness
+	until    time.Time
+}
+
+// checkDirectEdit treats an edit of the config made without the CLI that
+// narrows the policy (see config.NarrowedBy) as a violation: the previous
+// policy stays in force until the end of the day. The policy is compared with
+// the copy configlock last saved, or with old, the config the daemon had
+// loaded, if that copy is missing.
+func (d *Daemon) checkDirectEdit(cfg, old *config.Config, now time.Time) {
+	// Loading the config gave the locker its settings
+	d.applyHeldSettings(now)
+	if !cfg.EditedDirectly() {
+		return
+	}
+	previous, err := config.LoadLastSaved()
+	if err != nil {
+		previous = old
+	}
+	if previous == nil {
+		if cfg.IsWithinWorkHours(now) {
+			d.logger.Errorf("Config can't be checked for edits made without configlock: %v", err)
+			d.notify("ConfigLock: Config Tampering",
+				fmt.Sprintf("The copy of the config configlock last saved is missing or unreadable, so edits can't be checked.\n%s", config.GetLastSavedPath()))
+			d.emit(hooks.EventViolation, config.GetLastSavedPath())
+		}
+		return
+	}
+	if !previous.IsWithinWorkHours(now) {
+		return
+	}
+
+	narrowing := previous.NarrowedBy(cfg, now)
+	if narrowing.Empty() {
+		return
+	}
+
+	// An earlier hold keeps what it already holds
+	removed := slices.Concat(narrowing.Removed, narrowing.Expiring)
+	excludes, grace := narrowing.Excluded, narrowing.Graced
+	shortened := narrowing.Shortened || len(narrowing.Rescheduled) > 0
+	holdTrusted, settings := len(narrowing.Trusted) > 0, len(narrowing.Settings) > 0
+	if d.held != nil {
+		for _, path := range d.held.paths {
+			_, temp := cfg.TempPathExpiry(path)
+			if !slices.Contains(removed, path) && (temp || !slices.Contains(cfg.LockedPaths, path)) {
+				removed = append(removed, path)
+			}
+		}
+		excludes = pathutil.Dedupe(slices.Concat(excludes, d.held.excludes))
+		grace = pathutil.Dedupe(slices.Concat(grace, d.held.grace))
+		shortened = shortened || d.held.schedule
+		holdTrusted = holdTrusted || d.held.trusted
+		settings = settings || d.held.settings
+	}
+	year, month, day := now.Date()
+	d.held = &heldPolicy{
+		cfg:      previous,
+		paths:    removed,
+		excludes: excludes,
+		grace:    grace,
+		schedule: shortened,
+		trusted:  holdTrusted,
+		settings: settings,
+		until:    time.Date(year, month, day+1, 0, 0, 0, 0, now.Location()),
+	}
+	d.applyHeldSettings(now)
+
+	summary := narrowing.String()
+	d.logger.Errorf("Config edited without configlock during lock hours (%s), keeping the previous policy until midnight", summary)
+	d.notify("ConfigLock: Config Tampering",
+		fmt.Sprintf("The config was edited directly and %s.\nThe previous policy stays in force until midnight; use 'configlock rm' or 'configlock edit time' instead.", summary))
+	d.emit(hooks.EventViolation, config.GetConfigPath())
+}
+
+// heldActive reports whether a held policy is in force at now
+func (d *Daemon) heldActive(now time.Time) bool {
+	return d.held != nil && now.Before(d.held.until)
+}
+
+// releaseHeld ends a held policy once its time is up, unlocking the paths it
+// kept locked as 'configlock rm' would have
+func (d *Daemon) releaseHeld(now time.Time) {
+	if d.held == nil || d.heldActive(now) {
+		return
+	}
+	for _, path := range d.held.paths {
+		if slices.Contains(d.cfg().LockedPaths, path) {
+			continue
+		}
+		d.unlockPath(d.ctx, path)
+	}
+	// Exclusions the edit made take effect as if made by temp-unlock
+	for _, path := range d.held.excludes {
+		if !slices.Contains(d.cfg().ActiveExcludes(), path) {
+			continue
+		}
+		var err error
+		if only := d.cfg().TempExcludeScope(path); len(only) > 0 {
+			err = locker.UnlockScope(d.ctx, path, only)
+		} else {
+			err = locker.UnlockContext(d.ctx, path)
+		}
+		if err != nil {
+			d.logLockError("unlock", path, err)
+		}
+	}
+	if d.held.settings {
+		locker.SetOptions(d.cfg().LockerOptions())
+	}
+	d.logger.Info("Previous policy no longer held, the edited config is in force")
+	d.held = nil
+}
+
+// applyHeldSettings gives the locker the settings of a held policy that
+// holds back weakened ones
+func (d *Daemon) applyHeldSettings(now time.Time) {
+	if d.heldActive(now) && d.held.settings {
+		locker.SetOptions(d.held.cfg.LockerOptions())
+	}
+}
+
+// strictness is the strictness of the config, or that of a held policy if the
+// edit it holds back weakened settings
+func (d *Daemon) strictness(now time.Time) string {
+	if d.heldActive(now) && d.held.settings {
+		return d.held.cfg.GetStrictness()
+	}
+	return d.cfg().GetStrictness()
+}
+
+// protectReference locks the copy of the config configlock last saved for
+// lock hours, so it can't be edited to match an edit of the config
+func (d *Daemon) protectReference() {
+	path := config.GetLastSavedPath()
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+	if err := locker.Lock(path); err != nil {
+		d.logger.Warnf("Failed to lock %s: %v", path, err)
+	}
+}
+
+// releaseReference unlocks the copy protectReference locked
+func (d *Daemon) releaseReference() {
+	path := config.GetLastSavedPath()
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+	if err := locker.Unlock(path); err != nil {
+		d.logger.Warnf("Failed to unlock %s: %v", path, err)
+	}
+}
+
+// lockedPaths returns the locked paths of the config and those kept locked
+// by a held policy
+func (d *Daemon) lockedPaths(now time.Time) []string {
+	paths := d.cfg().LockedPaths
+	if !d.heldActive(now) {
+		return paths
+	}
+	for _, path := range d.held.paths {
+		if !slices.Contains(paths, path) {
+			paths = append(slices.Clip(paths), path)
+		}
+	}
+	return paths
+}
+
+// isWithinLockHours is IsWithinWorkHours of the config, extended by the lock
+// hours of a held policy
+func (d *Daemon) isWithinLockHours(now time.Time) bool {
+	if d.cfg().IsWithinWorkHours(now) {
+		return true
+	}
+	return d.heldActive(now) && d.held.schedule && d.held.cfg.IsWithinWorkHours(now)
+}
+
+// isPathActive is IsPathActive of the config, extended by a held policy for
+// the paths and lock hours it holds
+func (d *Daemon) isPathActive(path string, now time.Time) bool {
+	if d.cfg().IsPathActive(path, now) {
+		return true
+	}
+	return d.heldActive(now) && (d.held.schedule || slices.Contains(d.held.paths, path) || slices.Contains(d.held.grace, path)) &&
+		d.held.cfg.IsPathActive(path, now)
+}
+
+// isTemporarilyExcluded is IsTemporarilyExcluded of the config, except for
+// paths under an exclusion a held policy holds back, which follow the held
+// config
+func (d *Daemon) isTemporarilyExcluded(path string, now time.Time) bool {
+	if d.holdsExclude(path, now) {
+		return d.held.cfg.IsTemporarilyExcluded(path)
+	}
+	return d.cfg().IsTemporarilyExcluded(path)
+}
+
+// tempExcludeScope is TempExcludeScope of the config, or of the held config
+// for an exclusion a held policy holds back
+func (d *Daemon) tempExcludeScope(path string, now time.Time) []string {
+	if d.holdsExclude(path, now) {
+		return d.held.cfg.TempExcludeScope(path)
+	}
+	return d.cfg().TempExcludeScope(path)
+}
+
+// holdsExclude reports whether path is under an exclusion a held policy
+// holds back
+func (d *Daemon) holdsExclude(path string, now time.Time) bool {
+	if !d.heldActive(now) {
+		return false
+	}
+	return slices.ContainsFunc(d.held.excludes, func(root string) bool {
+		return pathutil.Within(path, root)
+	})
+}
+
+// panicExecutesAt is PanicExecutesAt of the config, held back until the
+// request of the held config is due, or the held policy ends, if the edit
+// requested the emergency unlock or shortened its delay
+func (d *Daemon) panicExecutesAt(now time.Time) (time.Time, bool) {
+	executesAt, pending := d.cfg().PanicExecutesAt()
+	if !pending || !d.heldActive(now) || !d.held.settings {
+		return executesAt, pending
+	}
+	held, heldPending := d.held.cfg.PanicExecutesAt()
+	if !heldPending {
+		held = d.held.until
+	}
+	if held.After(executesAt) {
+		executesAt = held
+	}
+	return executesAt, true
+}
+
+// timeUntilLockHours is TimeUntilWorkHours of the config, shortened to when
+// the lock hours of a held policy start or the policy ends
+func (d *Daemon) timeUntilLockHours(now time.Time) time.Duration {
+	until := d.cfg().TimeUntilWorkHours(now)
+	if d.heldActive(now) {
+		until = min(until, d.held.until.Sub(now))
+		if d.held.schedule {
+			until = min(until, d.held.cfg.TimeUntilWorkHours(now))
+		}
+	}
+	return until
+}
+
+// trustedProcesses returns the trusted processes of the config, or those of
+// a held policy if the edit it holds back added some
+func (d *Daemon) trustedProcesses(now time.Time) []string {
+	if d.heldActive(now) && d.held.trusted {
+		return d.held.cfg.TrustedProcesses
+	}
+	return d.cfg().TrustedProcesses
+}
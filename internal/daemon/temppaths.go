@@ -0,0 +1,63 @@
+package daemon
+
+import (
+	"slices"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/locker"
+	"github.com/baggiiiie/configlock/internal/pathutil"
+)
+
+// expireTempPaths removes paths added with 'configlock add --temp' from the
+// lock list once their time is up, unlocking them as 'configlock rm' would
+func (d *Daemon) expireTempPaths(now time.Time) {
+	expired := d.cfg().ExpiredTempPaths(now)
+	if len(expired) == 0 {
+		return
+	}
+
+	cfg, err := config.Update(func(cfg *config.Config) error {
+		for _, path := range cfg.ExpiredTempPaths(now) {
+			cfg.RemovePath(path)
+		}
+		return nil
+	})
+	if err != nil {
+		d.logger.Errorf("Failed to remove expired temporary paths: %v", err)
+		return
+	}
+	d.store.Set(cfg)
+
+	for _, path := range expired {
+		d.logger.Infof("Temporary lock of %s expired, removed from the lock list", path)
+		delete(d.health, path)
+		// A held policy may still keep the path locked, or locking a locked
+		// directory it is in may cover it
+		if slices.Contains(d.lockedPaths(now), path) || d.coveredByLocked(path, now) {
+			continue
+		}
+		d.unlockPath(d.ctx, path)
+	}
+}
+
+// coveredByLocked reports whether path is inside an active locked path whose
+// lock covers it
+func (d *Daemon) coveredByLocked(path string, now time.Time) bool {
+	return slices.ContainsFunc(d.lockedPaths(now), func(locked string) bool {
+		return pathutil.Inside(path, locked) && d.isPathActive(locked, now) && locker.Covers(locked, path)
+	})
+}
+
+// capForPathChanges shortens the wait until the next tick so a temporary
+// path is unlocked when it expires, and a path is locked when its grace
+// period ends, rather than at the next sweep
+func (d *Daemon) capForPathChanges(wait time.Duration, now time.Time) time.Duration {
+	if next, ok := d.cfg().NextTempPathExpiry(); ok {
+		wait = min(wait, max(next.Sub(now), time.Second))
+	}
+	if next, ok := d.cfg().NextGraceEnd(now); ok {
+		wait = min(wait, max(next.Sub(now), time.Second))
+	}
+	return wait
+}
@@ -0,0 +1,21 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/writer"
+)
+
+// trustedChange reports whether a change to a locked path was made by one
+// of trusted_processes. Such a change is neither alerted on nor re-locked
+// right away, which would fail the tool halfway through applying its
+// changes; the next sweep, within minSweepInterval, locks the path again.
+func (d *Daemon) trustedChange(path string, now time.Time) bool {
+	p, ok := writer.Trusted(path, d.trustedProcesses(now))
+	if !ok {
+		return false
+	}
+	d.logger.Infof("Change to %s by trusted process %s, not a violation", path, p.Name)
+	d.violated = true
+	return true
+}
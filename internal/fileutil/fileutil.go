@@ -1,19 +1,135 @@
 package fileutil
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
-// CollectFilesRecursively collects all files in a directory, skipping .git and .jj
-func CollectFilesRecursively(root string) ([]string, error) {
-	var files []string
+// binarySniffLen is how many leading bytes are inspected to detect binary files
+const binarySniffLen = 8000
 
-	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+// Symlink policies for symlinks found inside collected directories
+const (
+	SymlinkIgnore     = "ignore"      // skip symlinks entirely
+	SymlinkFollow     = "follow"      // collect target files and descend into target directories
+	SymlinkLockTarget = "lock-target" // collect target files, skip directory targets (default)
+)
+
+// ValidateSymlinkPolicy returns an error unless policy is one of Symlink*
+// or empty
+func ValidateSymlinkPolicy(policy string) error {
+	switch policy {
+	case "", SymlinkIgnore, SymlinkFollow, SymlinkLockTarget:
+		return nil
+	}
+	return fmt.Errorf("unknown symlink policy %q (want %s, %s or %s)", policy, SymlinkIgnore, SymlinkFollow, SymlinkLockTarget)
+}
+
+// symlinkPolicy returns policy, or the default for an empty one
+func symlinkPolicy(policy string) string {
+	if policy == "" {
+		return SymlinkLockTarget
+	}
+	return policy
+}
+
+// CollectOptions filters which files WalkFiles reports
+type CollectOptions struct {
+	MaxFileSize int64  // skip files larger than this many bytes (0 = no limit)
+	SkipBinary  bool   // skip files that look binary (contain a NUL byte)
+	Symlinks    string // symlink policy, empty means SymlinkLockTarget
+	Shallow     bool   // only the files directly inside the root, not in subdirectories
+}
+
+// collector streams the files of a single walk to fn
+type collector struct {
+	ctx     context.Context
+	opts    CollectOptions
+	fn      func(path string) error
+	seen    map[string]struct{}  // files passed to fn, to dedupe symlink targets; nil when symlinks are ignored
+	visited map[string]struct{}  // real directories already walked, to stop symlink loops
+	dirs    map[string]time.Time // walked directories -> modification time
+	skipped int
+	special map[string]string // special files skipped -> kind
+}
+
+// Scan describes a walk of a directory: how many files were skipped and what
+// the walked directories looked like. Files is left for the caller to fill
+// in when it keeps the walk's result.
+type Scan struct {
+	Files   []string
+	Skipped int
+	Special map[string]string // special files skipped, path -> kind (see SpecialKind)
+
+	dirs    map[string]time.Time
+	started time.Time
+}
+
+// Stale reports whether the scan may no longer match the directory: a
+// directory's modification time changes whenever an entry is added, removed
+// or renamed in it. Edits to a file's content go unnoticed, which only
+// matters for the size and binary filters. Directories modified within a
+// second of the scan are treated as changed, since the walk may have raced
+// with the change and file systems with coarse timestamps hide a second one.
+func (s *Scan) Stale() bool {
+	for dir, modTime := range s.dirs {
+		info, err := os.Stat(dir)
+		if err != nil || !info.ModTime().Equal(modTime) {
+			return true
+		}
+		if !modTime.Before(s.started.Add(-time.Second)) {
+			return true
+		}
+	}
+	return false
+}
+
+// WalkFiles calls fn for each file in a directory as soon as the walk finds
+// it, so large trees can be processed without holding every path. .git and
+// .jj are skipped, as are files excluded by opts; symlinks are handled
+// according to opts.Symlinks. The walk stops with ctx's error once ctx is
+// cancelled, or with fn's error. The returned Scan counts the skipped files
+// and can tell whether the directory has changed since.
+func WalkFiles(ctx context.Context, root string, opts CollectOptions, fn func(path string) error) (*Scan, error) {
+	started := time.Now()
+	opts.Symlinks = symlinkPolicy(opts.Symlinks)
+	c := &collector{
+		ctx:     ctx,
+		opts:    opts,
+		fn:      fn,
+		visited: make(map[string]struct{}),
+		dirs:    make(map[string]time.Time),
+	}
+	// Without symlinks every file is reached by exactly one path
+	if opts.Symlinks == SymlinkFollow || opts.Symlinks == SymlinkLockTarget {
+		c.seen = make(map[string]struct{})
+	}
+	err := c.walk(root)
+	return &Scan{Skipped: c.skipped, Special: c.special, dirs: c.dirs, started: started}, err
+}
+
+// walk collects files under root, recursing into symlinked directories when following
+func (c *collector) walk(root string) error {
+	if realRoot, err := filepath.EvalSymlinks(root); err == nil {
+		if _, done := c.visited[realRoot]; done {
+			return nil
+		}
+		c.visited[realRoot] = struct{}{}
+	}
+
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
+		if err := c.ctx.Err(); err != nil {
+			return err
+		}
 
 		// Skip .git and .jj directories
 		if d.IsDir() {
@@ -21,24 +137,398 @@ func CollectFilesRecursively(root string) ([]string, error) {
 			if name == ".git" || name == ".jj" {
 				return filepath.SkipDir
 			}
+			if c.opts.Shallow && path != root {
+				return filepath.SkipDir
+			}
+			if info, err := d.Info(); err == nil {
+				c.dirs[path] = info.ModTime()
+			}
+			return nil
 		}
 
-		// Add files (not directories)
-		if !d.IsDir() {
-			// Also skip if path contains /.git/ or /.jj/
-			if strings.Contains(path, "/.git/") || strings.Contains(path, "/.jj/") {
-				return nil
-			}
+		// Also skip if path contains /.git/ or /.jj/
+		if strings.Contains(path, "/.git/") || strings.Contains(path, "/.jj/") {
+			return nil
+		}
+
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+
+		if d.Type()&os.ModeSymlink != 0 {
+			return c.addSymlink(absPath)
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil // vanished while walking
+		}
+		return c.add(absPath, info)
+	})
+}
+
+// addSymlink applies the symlink policy to a symlink found while walking
+func (c *collector) addSymlink(path string) error {
+	policy := c.opts.Symlinks
+	if policy != SymlinkFollow && policy != SymlinkLockTarget {
+		c.skipped++
+		return nil
+	}
+
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		c.skipped++ // broken symlink
+		return nil
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		c.skipped++
+		return nil
+	}
+
+	if info.IsDir() {
+		if policy == SymlinkFollow && !c.opts.Shallow {
+			return c.walk(target)
+		}
+		c.skipped++
+		return nil
+	}
+
+	return c.add(target, info)
+}
+
+// add passes a single file to fn unless it is filtered out or already seen
+func (c *collector) add(path string, info os.FileInfo) error {
+	if _, dup := c.seen[path]; dup {
+		return nil
+	}
+	if kind := SpecialKind(info.Mode()); kind != "" {
+		if c.special == nil {
+			c.special = make(map[string]string)
+		}
+		c.special[path] = kind
+		c.skipped++
+		return nil
+	}
+	if skip, err := shouldSkip(path, info, c.opts); err == nil && skip {
+		c.skipped++
+		return nil
+	}
+	if c.seen != nil {
+		c.seen[path] = struct{}{}
+	}
+	return c.fn(path)
+}
+
+// SpecialKind names the kind of a special file, such as a socket, named pipe
+// or device node, or returns "" for regular files, directories and symlinks.
+// Special files are never locked: chattr and chflags fail on them or, for a
+// device node, open the device behind it.
+func SpecialKind(mode os.FileMode) string {
+	switch {
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&os.ModeNamedPipe != 0:
+		return "named pipe"
+	case mode&os.ModeCharDevice != 0:
+		return "character device"
+	case mode&os.ModeDevice != 0:
+		return "block device"
+	case mode&os.ModeIrregular != 0:
+		return "irregular file"
+	}
+	return ""
+}
+
+// Collected reports whether a walk with opts collects the entry at path,
+// found inside a walked directory, or, for a directory, walks it. Shallow is
+// up to the caller, who knows the root.
+func Collected(path string, opts CollectOptions) bool {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return false
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		policy := symlinkPolicy(opts.Symlinks)
+		if policy != SymlinkFollow && policy != SymlinkLockTarget {
+			return false
+		}
+		if info, err = os.Stat(path); err != nil {
+			return false
+		}
+		if info.IsDir() {
+			return policy == SymlinkFollow
+		}
+	}
+	if info.IsDir() {
+		return true
+	}
+	if SpecialKind(info.Mode()) != "" {
+		return false
+	}
+	skip, err := shouldSkip(path, info, opts)
+	return err == nil && !skip
+}
 
-			absPath, err := filepath.Abs(path)
-			if err != nil {
-				return err
+// shouldSkip applies the size and binary filters of opts to a single file
+func shouldSkip(path string, info os.FileInfo, opts CollectOptions) (bool, error) {
+	if opts.MaxFileSize > 0 && info.Size() > opts.MaxFileSize {
+		return true, nil
+	}
+
+	if opts.SkipBinary && info.Mode().IsRegular() {
+		return IsBinary(path)
+	}
+
+	return false, nil
+}
+
+// IsBinary reports whether a file looks binary, using the same heuristic as
+// git: a NUL byte within the first few kilobytes
+func IsBinary(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	buf := make([]byte, binarySniffLen)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+
+	return bytes.IndexByte(buf[:n], 0) != -1, nil
+}
+
+// InScope reports whether path, inside the directory root, matches one of
+// patterns. Patterns are globs relative to root using '/' (e.g.
+// "lua/plugins/*.lua") and also match everything below a matching directory.
+func InScope(root, path string, patterns []string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	for {
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(filepath.FromSlash(pattern), rel); ok {
+				return true
 			}
-			files = append(files, absPath)
 		}
+		rel = filepath.Dir(rel)
+		if rel == "." {
+			return false
+		}
+	}
+}
+
+// LatestModTime returns the most recent modification time of a file,
+// or of any file inside a directory
+func LatestModTime(path string) (time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	latest := info.ModTime()
+	if !info.IsDir() {
+		return latest, nil
+	}
 
+	_, err = WalkFiles(context.Background(), path, CollectOptions{}, func(file string) error {
+		if fi, err := os.Stat(file); err == nil && fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
 		return nil
 	})
+	return latest, err
+}
+
+// inode identifies a file independently of the path used to reach it
+type inode struct {
+	dev uint64
+	ino uint64
+}
+
+// Hardlink describes a file with hard links that live outside a managed tree
+type Hardlink struct {
+	Path     string // one managed path of the file
+	Links    uint64 // total hard link count
+	External uint64 // links not reachable from the managed tree
+}
+
+// ExternalHardlinks finds files under path (a file or directory) that have
+// additional hard links outside of it. Editing through such a link changes the
+// managed content without generating events on any watched path.
+func ExternalHardlinks(path string) ([]Hardlink, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	type group struct {
+		path  string
+		links uint64
+		count uint64
+	}
+	groups := make(map[inode]*group)
+	var order []inode
+	visit := func(file string) error {
+		fi, err := os.Stat(file)
+		if err != nil || !fi.Mode().IsRegular() {
+			return nil
+		}
+		id, links, ok := inodeOf(fi)
+		if !ok || links < 2 {
+			return nil
+		}
+		if g, exists := groups[id]; exists {
+			g.count++
+			return nil
+		}
+		groups[id] = &group{path: file, links: links, count: 1}
+		order = append(order, id)
+		return nil
+	}
+
+	if info.IsDir() {
+		if _, err := WalkFiles(context.Background(), path, CollectOptions{}, visit); err != nil {
+			return nil, err
+		}
+	} else {
+		visit(path)
+	}
+
+	var result []Hardlink
+	for _, id := range order {
+		g := groups[id]
+		if g.links > g.count {
+			result = append(result, Hardlink{Path: g.path, Links: g.links, External: g.links - g.count})
+		}
+	}
+	return result, nil
+}
+
+// Owner describes files under a managed tree that belong to another user
+type Owner struct {
+	UID     uint32
+	Files   int    // files and directories owned by UID
+	Example string // one of them
+}
+
+// ForeignOwners finds the users other than uid that own path or, for a
+// directory, any of the files inside it. Only the owner or root can change
+// the permissions of a file, so the read-only fallback can't lock these.
+func ForeignOwners(path string, uid int) ([]Owner, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make(map[uint32]*Owner)
+	var order []uint32
+	visit := func(file string, fi os.FileInfo) {
+		owner, ok := ownerOf(fi)
+		if !ok || int(owner) == uid {
+			return
+		}
+		if o, exists := owners[owner]; exists {
+			o.Files++
+			return
+		}
+		owners[owner] = &Owner{UID: owner, Files: 1, Example: file}
+		order = append(order, owner)
+	}
+
+	visit(path, info)
+	if info.IsDir() {
+		if _, err := WalkFiles(context.Background(), path, CollectOptions{}, func(file string) error {
+			if fi, err := os.Stat(file); err == nil {
+				visit(file, fi)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]Owner, 0, len(order))
+	for _, owner := range order {
+		result = append(result, *owners[owner])
+	}
+	return result, nil
+}
+
+// CopyTree copies a file, or a directory recursively, from src to dst,
+// preserving permission bits and overwriting existing files
+func CopyTree(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return CopyFile(src, dst)
+	}
+
+	return filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode().IsRegular():
+			return CopyFile(path, target)
+		default:
+			return nil // skip symlinks and special files
+		}
+	})
+}
+
+// CopyFile copies a single regular file, replacing dst and preserving
+// permission bits. Missing parent directories of dst are created.
+func CopyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	perm := info.Mode().Perm()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
 
-	return files, err
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(dst, perm)
 }
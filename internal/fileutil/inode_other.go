@@ -0,0 +1,15 @@
+//go:build !unix
+
+package fileutil
+
+import "os"
+
+// inodeOf is not supported on this platform
+func inodeOf(info os.FileInfo) (inode, uint64, bool) {
+	return inode{}, 0, false
+}
+
+// ownerOf is not supported on this platform
+func ownerOf(info os.FileInfo) (uint32, bool) {
+	return 0, false
+}
@@ -0,0 +1,84 @@
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/baggiiiie/configlock/internal/pathutil"
+)
+
+// Sensitive is a path whose exact permissions matter to the programs using
+// it, or that other tools must be able to change
+type Sensitive struct {
+	Path   string
+	Reason string
+}
+
+// sensitivePaths lists the known sensitive paths. Entries starting with ~/
+// are relative to the home directory.
+var sensitivePaths = []Sensitive{
+	{"~/.ssh", "SSH keys and authorized_keys: ssh and sshd refuse keys readable by others and ignore files with the wrong mode"},
+	{"~/.gnupg", "GnuPG keys: gpg warns about and may refuse keys readable by others"},
+	{"~/.netrc", "credentials that must stay readable only by you"},
+	{"~/.pgpass", "credentials that must stay readable only by you"},
+	{"~/Library/LaunchAgents", "launchd agents, including configlock's own: nothing can install, update or remove agents while locked"},
+	{"~/.config/systemd/user", "systemd user units, including configlock's own: nothing can install, update or remove units while locked"},
+	{"/Library/LaunchAgents", "launchd agents: nothing can install, update or remove agents while locked"},
+	{"/Library/LaunchDaemons", "launchd daemons: nothing can install, update or remove daemons while locked"},
+	{"/etc/crontab", "crontab: cron skips crontabs with an unexpected mode"},
+	{"/etc/cron.d", "crontabs: cron skips crontabs with an unexpected mode"},
+	{"/var/spool/cron", "user crontabs: cron skips crontabs with an unexpected mode, and crontab -e can't change them while locked"},
+	{"/var/at/tabs", "user crontabs: cron skips crontabs with an unexpected mode, and crontab -e can't change them while locked"},
+	{"/usr/lib/cron/tabs", "user crontabs: cron skips crontabs with an unexpected mode, and crontab -e can't change them while locked"},
+	{"/etc/sudoers", "sudo configuration: sudo refuses a sudoers file with the wrong mode, which can lock you out of sudo"},
+	{"/etc/sudoers.d", "sudo configuration: sudo refuses a sudoers file with the wrong mode, which can lock you out of sudo"},
+}
+
+// expandSensitive returns the known sensitive paths with ~ expanded
+func expandSensitive() []Sensitive {
+	home, _ := os.UserHomeDir()
+	result := make([]Sensitive, 0, len(sensitivePaths))
+	for _, s := range sensitivePaths {
+		if strings.HasPrefix(s.Path, "~/") {
+			if home == "" {
+				continue
+			}
+			s.Path = pathutil.ExpandHome(s.Path)
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+// SensitivePath reports whether path is a sensitive path or inside one.
+// The chmod fallback only removes write permission from these, instead of
+// making them readable by everyone.
+func SensitivePath(path string) (Sensitive, bool) {
+	path = filepath.Clean(path)
+	for _, s := range expandSensitive() {
+		if pathutil.Within(path, s.Path) {
+			return s, true
+		}
+	}
+	return Sensitive{}, false
+}
+
+// SensitivePaths returns the sensitive paths that locking path would lock:
+// the one containing path, or those that exist inside a directory
+func SensitivePaths(path string) []Sensitive {
+	if s, ok := SensitivePath(path); ok {
+		return []Sensitive{s}
+	}
+	path = filepath.Clean(path)
+	var result []Sensitive
+	for _, s := range expandSensitive() {
+		if !pathutil.Within(s.Path, path) {
+			continue
+		}
+		if _, err := os.Lstat(s.Path); err == nil {
+			result = append(result, s)
+		}
+	}
+	return result
+}
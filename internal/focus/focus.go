@@ -0,0 +1,22 @@
+package focus
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// RunShortcut runs a macOS Shortcut by name through the shortcuts CLI.
+// Focus modes cannot be toggled directly from the command line, so users
+// create shortcuts with the "Set Focus" action and configlock runs them.
+func RunShortcut(name string) error {
+	if runtime.GOOS != "darwin" {
+		return fmt.Errorf("focus modes are only supported on macOS")
+	}
+
+	output, err := exec.Command("shortcuts", "run", name).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("shortcut %q failed: %v, output: %s", name, err, string(output))
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const requestTimeout = 10 * time.Second
+
+// Ping sends a single heartbeat to a healthchecks.io style URL.
+// Any 2xx response is treated as success.
+func Ping(url string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create heartbeat request: %w", err)
+	}
+	req.Header.Set("User-Agent", "configlock-heartbeat")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send heartbeat: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected heartbeat status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// Lifecycle events that hooks can be attached to
+const (
+	EventActivate   = "activate"    // lock hours started
+	EventDeactivate = "deactivate"  // lock hours ended
+	EventViolation  = "violation"   // a change to a locked path was detected
+	EventTempUnlock = "temp_unlock" // a path was temporarily unlocked
+)
+
+// timeout bounds how long a hook may run before it is killed
+const timeout = 30 * time.Second
+
+// Event describes a lifecycle transition passed to a hook
+type Event struct {
+	Name   string
+	Path   string // affected path, empty for activate and deactivate
+	Reason string // reason given for a temp unlock
+	Time   time.Time
+}
+
+// Run executes command through the shell with the event described in
+// CONFIGLOCK_* environment variables
+func Run(command string, event Event) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(),
+		"CONFIGLOCK_EVENT="+event.Name,
+		"CONFIGLOCK_PATH="+event.Path,
+		"CONFIGLOCK_REASON="+event.Reason,
+		"CONFIGLOCK_TIME="+event.Time.Format(time.RFC3339),
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("hook for %s failed: %v, output: %s", event.Name, err, string(output))
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+)
+
+// Commands understood by the daemon
+const (
+	CommandStatus     = "status"
+	CommandTempUnlock = "temp-unlock"
+	CommandTempCancel = "temp-cancel"
+	CommandRelock     = "relock"
+	CommandReload     = "reload"
+)
+
+// ErrNotRunning is returned when no daemon is listening on the control socket
+var ErrNotRunning = errors.New("daemon is not running")
+
+const (
+	requestTimeout = 5 * time.Second
+	unlockTimeout  = 2 * time.Minute // unlocking or re-locking a large directory takes a while
+)
+
+// Status is the daemon's answer to CommandStatus
+type Status struct {
+	Active          bool              `json:"active"`                   // within lock hours of any schedule
+	Schedules       map[string]bool   `json:"schedules,omitempty"`      // named schedule -> active
+	NextTransition  time.Time         `json:"next_transition,omitzero"` // when lock hours next start or end
+	LockedPaths     []string          `json:"locked_paths"`
+	TempExcludes    map[string]string `json:"temp_excludes,omitempty"`    // path -> expiration ISO8601
+	PanicExecutesAt time.Time         `json:"panic_executes_at,omitzero"` // pending emergency unlock
+
+	LastEnforced time.Time             `json:"last_enforced,omitzero"` // end of the last enforcement sweep
+	Health       map[string]PathHealth `json:"health,omitempty"`       // locked path -> result of its last lock
+	SweepOnly    []string              `json:"sweep_only,omitempty"`   // locked paths not watched because of watch limits
+	SweepEvery   time.Duration         `json:"sweep_every,omitempty"`  // current interval between sweeps
+}
+
+// PathHealth is the outcome of the daemon's last lock of a locked path
+type PathHealth struct {
+	Locked   int       `json:"locked"`             // files carrying the lock
+	Failed   int       `json:"failed"`             // files that could not be locked
+	Excluded int       `json:"excluded,omitempty"` // files left unlocked as excluded_files
+	Busy     []string  `json:"busy,omitempty"`     // files left unlocked while open for writing (skip_open_files)
+	Error    string    `json:"error,omitempty"`
+	LockedAt time.Time `json:"locked_at"`
+}
+
+// Request is a single command sent to the daemon
+type Request struct {
+	Command string `json:"command"`
+	Path    string `json:"path,omitempty"`    // CommandTempUnlock, CommandTempCancel, CommandRelock
+	Minutes int    `json:"minutes,omitempty"` // CommandTempUnlock, 0 = config default
+
+	// CommandTempUnlock: only unlock files of the directory matching these globs
+	Only   []string `json:"only,omitempty"`
+	Reason string   `json:"reason,omitempty"` // CommandTempUnlock
+
+	// CommandTempUnlock: absolute expiry, used instead of Minutes when set
+	Until time.Time `json:"until,omitzero"`
+
+	// CommandTempUnlock: proof that the challenge was passed, see
+	// config.IssueChallengeToken
+	Token string `json:"token,omitempty"`
+
+	All bool `json:"all,omitempty"` // CommandTempCancel: every exclusion instead of Path's
+}
+
+// TempUnlock is the daemon's answer to CommandTempUnlock
+type TempUnlock struct {
+	Path      string    `json:"path"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// TempCancel is the daemon's answer to CommandTempCancel
+type TempCancel struct {
+	Paths []string `json:"paths"` // paths whose exclusion was ended and that were re-locked
+}
+
+// Relock is the daemon's answer to CommandRelock
+type Relock struct {
+	Path   string `json:"path"`
+	Locked bool   `json:"locked"` // false outside the path's lock hours
+}
+
+// Response carries either the command's result or an error message
+type Response struct {
+	Error  string          `json:"error,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// Handler answers a request with a JSON-encodable result
+type Handler func(req Request) (any, error)
+
+// GetSocketPath returns the path of the daemon's control socket
+func GetSocketPath() string {
+	return filepath.Join(config.GetConfigDir(), "daemon.sock")
+}
+
+// Listen creates the control socket, replacing a stale one left behind by a
+// daemon that did not shut down cleanly
+func Listen() (net.Listener, error) {
+	path := GetSocketPath()
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("another daemon is listening on %s", path)
+	}
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	// Only the owner may talk to the daemon
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict %s: %w", path, err)
+	}
+	return listener, nil
+}
+
+// Serve answers requests on listener until it is closed
+func Serve(listener net.Listener, handler Handler) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		go serveConn(conn, handler)
+	}
+}
+
+// serveConn answers a single request on conn
+func serveConn(conn net.Conn, handler Handler) {
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(requestTimeout))
+
+	var req Request
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err == nil {
+		err = json.Unmarshal(line, &req)
+	}
+
+	var resp Response
+	if err != nil {
+		resp.Error = fmt.Sprintf("invalid request: %v", err)
+	} else if result, err := handler(req); err != nil {
+		resp.Error = err.Error()
+	} else if resp.Result, err = json.Marshal(result); err != nil {
+		resp.Error = fmt.Sprintf("failed to encode result: %v", err)
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(requestTimeout))
+	json.NewEncoder(conn).Encode(resp)
+}
+
+// Query sends a command without arguments to the running daemon and decodes
+// its result into v
+func Query(command string, v any) error {
+	return Send(Request{Command: command}, v)
+}
+
+// Send sends a request to the running daemon and decodes its result into v
+func Send(req Request, v any) error {
+	conn, err := net.DialTimeout("unix", GetSocketPath(), requestTimeout)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotRunning, err)
+	}
+	defer conn.Close()
+	timeout := requestTimeout
+	if req.Command == CommandTempUnlock || req.Command == CommandTempCancel || req.Command == CommandRelock {
+		timeout = unlockTimeout
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, v)
+}
@@ -0,0 +1,36 @@
+package locker
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// SetAppendOnly sets or clears the append-only flag of a file, so that while
+// it is set existing content can't be changed or removed (chattr +a on Linux,
+// which needs root, chflags uappnd on macOS)
+func SetAppendOnly(path string, on bool) error {
+	var args []string
+	switch runtime.GOOS {
+	case "linux":
+		args = []string{"chattr", "-a", path}
+		if on {
+			args[1] = "+a"
+		}
+		output, err := privilegedCommand(args[0], args[1:]...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("chattr %s failed: %v, output: %s", args[1], err, string(output))
+		}
+	case "darwin":
+		args = []string{"chflags", "nouappnd", path}
+		if on {
+			args[1] = "uappnd"
+		}
+		output, err := command(args[0], args[1:]...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("chflags %s failed: %v, output: %s", args[1], err, string(output))
+		}
+	default:
+		return fmt.Errorf("append-only flag: %w: %s", ErrUnsupportedOS, runtime.GOOS)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package locker
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Backend locks and unlocks single files or directories. Directory trees are
+// walked by the locker, so backends only ever see one path at a time.
+type Backend interface {
+	Name() string
+	Lock(path string) error
+	Unlock(path string) error
+	IsLocked(path string) (bool, error)
+}
+
+// TreeBackend is implemented by backends whose lock on a directory covers
+// everything below it (such as a read-only mount), so the tree isn't walked
+type TreeBackend interface {
+	Backend
+	CoversTree() bool
+}
+
+var (
+	backendsMu sync.RWMutex
+	backends   = make(map[string]Backend)
+)
+
+// Register makes a backend selectable by name, replacing any backend
+// registered under the same name (e.g. a fake in tests)
+func Register(b Backend) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	backends[b.Name()] = b
+}
+
+// Backends returns the names of all registered backends
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getBackend returns the backend registered under name
+func getBackend(name string) (Backend, bool) {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	b, ok := backends[name]
+	return b, ok
+}
+
+// backendFor returns the backend used for path (see StrategyFor)
+func backendFor(path string) (Backend, error) {
+	name := StrategyFor(path)
+	b, ok := getBackend(name)
+	if !ok {
+		return nil, fmt.Errorf("lock backend %q is not available", name)
+	}
+	return b, nil
+}
+
+// coversTree reports whether b locks a whole directory tree at once
+func coversTree(b Backend) bool {
+	tb, ok := b.(TreeBackend)
+	return ok && tb.CoversTree()
+}
+
+func init() {
+	Register(immutableBackend{})
+	Register(chmodBackend{})
+	Register(aclBackend{})
+	Register(bindROBackend{})
+}
+
+// immutableBackend uses chattr +i on Linux and chflags uchg/schg on macOS,
+// falling back to chmod where the flag cannot be set
+type immutableBackend struct{}
+
+func (immutableBackend) Name() string { return StrategyImmutable }
+
+func (immutableBackend) Lock(path string) error {
+	switch runtime.GOOS {
+	case "linux":
+		return lockLinux(path)
+	case "darwin":
+		return lockDarwin(path)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedOS, runtime.GOOS)
+	}
+}
+
+func (immutableBackend) Unlock(path string) error {
+	switch runtime.GOOS {
+	case "linux":
+		return unlockLinux(path)
+	case "darwin":
+		return unlockDarwin(path)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedOS, runtime.GOOS)
+	}
+}
+
+func (immutableBackend) IsLocked(path string) (bool, error) {
+	switch runtime.GOOS {
+	case "linux":
+		return isLockedLinux(path)
+	case "darwin":
+		return isLockedDarwin(path)
+	default:
+		return false, fmt.Errorf("%w: %s", ErrUnsupportedOS, runtime.GOOS)
+	}
+}
+
+// chmodBackend makes files read-only, for filesystems without immutable flags
+type chmodBackend struct{}
+
+func (chmodBackend) Name() string { return StrategyChmod }
+
+func (chmodBackend) Lock(path string) error {
+	fsType, _ := DetectStrategy(path)
+	if err := fallbackLock(path); err != nil {
+		return fmt.Errorf("chmod failed on %s filesystem: %w", fsType, err)
+	}
+	getLogger().Debugf("LOCK (%s): made %s read-only", fsType, path)
+	return nil
+}
+
+func (chmodBackend) Unlock(path string) error {
+	fsType, _ := DetectStrategy(path)
+	if err := fallbackUnlock(path); err != nil {
+		return fmt.Errorf("chmod failed on %s filesystem: %w", fsType, err)
+	}
+	getLogger().Debugf("UNLOCK (%s): restored mode of %s", fsType, path)
+	return nil
+}
+
+func (chmodBackend) IsLocked(path string) (bool, error) {
+	return isReadOnly(path)
+}
+
+// aclBackend adds a deny-write ACL entry (setfacl on Linux, chmod +a on macOS)
+type aclBackend struct{}
+
+func (aclBackend) Name() string                       { return StrategyACL }
+func (aclBackend) Lock(path string) error             { return lockACL(path) }
+func (aclBackend) Unlock(path string) error           { return unlockACL(path) }
+func (aclBackend) IsLocked(path string) (bool, error) { return isLockedACL(path) }
+
+// bindROBackend bind-mounts the locked path read-only over itself (Linux, root)
+type bindROBackend struct{}
+
+func (bindROBackend) Name() string                       { return StrategyBindRO }
+func (bindROBackend) Lock(path string) error             { return lockBindReadOnly(path) }
+func (bindROBackend) Unlock(path string) error           { return unlockBindReadOnly(path) }
+func (bindROBackend) IsLocked(path string) (bool, error) { return isOnReadOnlyMount(path) }
+func (bindROBackend) CoversTree() bool                   { return true }
@@ -0,0 +1,138 @@
+package locker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/baggiiiie/configlock/internal/fileutil"
+)
+
+// CheckMode selects which of the files covered by a directory CheckLocked
+// reads the lock of
+type CheckMode int
+
+const (
+	CheckAll     CheckMode = iota // every file
+	CheckAny                      // files until one is locked
+	CheckSampled                  // up to sampleSize files spread over the tree
+)
+
+// sampleSize is how many files of a directory CheckSampled reads, besides the
+// directory itself
+const sampleSize = 16
+
+// LockStatus is the lock state of the files a locked path covers
+type LockStatus struct {
+	Total    int         // files covered, a directory itself included
+	Checked  int         // files whose lock was read
+	Locked   int         // checked files that are locked
+	Excluded int         // files left out because they are in Options.Excluded
+	Unlocked []string    // checked files that are not locked
+	Failed   []LockError // checked files whose lock couldn't be read
+}
+
+// AllLocked reports whether every checked file is locked, excluded files
+// aside
+func (s *LockStatus) AllLocked() bool {
+	return s.Locked == s.Checked && s.Checked+s.Excluded > 0
+}
+
+// AnyLocked reports whether at least one checked file is locked
+func (s *LockStatus) AnyLocked() bool {
+	return s.Locked > 0
+}
+
+// CheckLocked reads the lock of the files locking path covers, unlike
+// IsLocked, which for a directory only sees the directory itself
+func CheckLocked(ctx context.Context, path string, mode CheckMode) (*LockStatus, error) {
+	return CheckLockedExcept(ctx, path, mode, nil)
+}
+
+// CheckLockedExcept is like CheckLocked but leaves out the files of a
+// directory that match one of patterns (see fileutil.InScope), as LockExcept
+// leaves them unlocked
+func CheckLockedExcept(ctx context.Context, path string, mode CheckMode, patterns []string) (*LockStatus, error) {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		realPath = path
+	}
+	info, err := os.Stat(realPath)
+	if err != nil {
+		return nil, fmt.Errorf("path does not exist: %s", realPath)
+	}
+
+	// A single file, or a tree locked as a whole, has one lock to read
+	files := []string{realPath}
+	excluded := 0
+	if info.IsDir() {
+		backend, err := backendFor(realPath)
+		if err != nil {
+			return nil, err
+		}
+		if !coversTree(backend) {
+			files = nil
+			skip := excludedSet(getOptions())
+			_, err := walkFiles(ctx, realPath, collectOptions(path, realPath), func(file string) error {
+				switch {
+				case skip[file]:
+					excluded++
+				case len(patterns) == 0 || !fileutil.InScope(realPath, file, patterns):
+					files = append(files, file)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to collect files: %w", err)
+			}
+			if skip[realPath] {
+				excluded++
+			} else {
+				files = append(files, realPath)
+			}
+		}
+	}
+
+	status := &LockStatus{Total: len(files), Excluded: excluded}
+	if mode == CheckSampled {
+		files = sample(files, sampleSize)
+	}
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return status, err
+		}
+		status.Checked++
+		backend, err := backendFor(file)
+		if err != nil {
+			status.Failed = append(status.Failed, LockError{Path: file, Err: err})
+			continue
+		}
+		locked, err := backend.IsLocked(file)
+		switch {
+		case err != nil:
+			status.Failed = append(status.Failed, LockError{Path: file, Err: err})
+		case locked:
+			status.Locked++
+			if mode == CheckAny {
+				return status, nil
+			}
+		default:
+			status.Unlocked = append(status.Unlocked, file)
+		}
+	}
+	return status, nil
+}
+
+// sample returns up to n of files spread evenly over them, always including
+// the last one (a directory itself, which is collected after its contents)
+func sample(files []string, n int) []string {
+	if len(files) <= n+1 {
+		return files
+	}
+	picked := make([]string, 0, n+1)
+	for i := range n {
+		picked = append(picked, files[i*(len(files)-1)/n])
+	}
+	return append(picked, files[len(files)-1])
+}
@@ -0,0 +1,42 @@
+package locker
+
+import (
+	"os"
+	"os/exec"
+)
+
+// How lock tools that need root are run, chosen once by the user
+const (
+	ElevationSudo = "sudo" // run them through sudo
+	ElevationNone = "none" // accept the chmod fallback
+)
+
+// privilegedCommand returns a command for a lock tool that may need root,
+// run through sudo when the user opted into it
+func privilegedCommand(name string, args ...string) *exec.Cmd {
+	if getOptions().Elevation != ElevationSudo || os.Geteuid() == 0 {
+		return command(name, args...)
+	}
+	// Never wait for a password on a terminal nobody is looking at: -n fails
+	// instead, -A asks through the SUDO_ASKPASS helper
+	flag := "-n"
+	if os.Getenv("SUDO_ASKPASS") != "" {
+		flag = "-A"
+	}
+	// sudo runs the tool by absolute path too, not through its secure_path
+	path, err := toolPath(name)
+	if err != nil {
+		return command(name, args...) // fails with err when run
+	}
+	return command("sudo", append([]string{flag, path}, args...)...)
+}
+
+// NeedsElevation reports whether locking path would silently fall back to
+// read-only permissions because setting immutable flags requires root
+func NeedsElevation(path string) bool {
+	if os.Geteuid() == 0 || getOptions().Elevation == ElevationSudo {
+		return false
+	}
+	result, err := Probe(path)
+	return err == nil && result.Fallback()
+}
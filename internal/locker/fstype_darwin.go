@@ -0,0 +1,33 @@
+package locker
+
+import "syscall"
+
+// filesystemType returns the name of the filesystem containing path
+func filesystemType(path string) (string, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return "", err
+	}
+
+	name := make([]byte, 0, len(st.Fstypename))
+	for _, c := range st.Fstypename {
+		if c == 0 {
+			break
+		}
+		name = append(name, byte(c))
+	}
+	return string(name), nil
+}
+
+// mntReadOnly is MNT_RDONLY, which the syscall package doesn't define on darwin
+const mntReadOnly = 0x1
+
+// ReadOnlyFilesystem reports whether path is on a filesystem mounted
+// read-only, where nothing can be changed or locked
+func ReadOnlyFilesystem(path string) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return false
+	}
+	return st.Flags&mntReadOnly != 0
+}
@@ -0,0 +1,53 @@
+package locker
+
+import "syscall"
+
+// linuxFilesystems maps statfs magic numbers to filesystem names
+var linuxFilesystems = map[int64]string{
+	0xEF53:     "ext4", // shared by ext2/ext3/ext4
+	0x9123683E: "btrfs",
+	0x58465342: "xfs",
+	0xF2F52010: "f2fs",
+	0x2FC12FC1: "zfs",
+	0x52654973: "reiserfs",
+	0x3153464A: "jfs",
+	0x01021994: "tmpfs",
+	0x794C7630: "overlay",
+	0x6969:     "nfs",
+	0xFF534D42: "cifs",
+	0xFE534D42: "smb2",
+	0x4D44:     "vfat",
+	0x2011BAB0: "exfat",
+	0x5346544E: "ntfs",
+	0x65735546: "fuse",
+	0x01021997: "9p",
+	0xF15F:     "ecryptfs",
+}
+
+// filesystemType returns the name of the filesystem containing path
+func filesystemType(path string) (string, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return "", err
+	}
+	// Type is an int32 on 32-bit builds: converting it straight to int64
+	// would sign-extend magics with the high bit set, such as btrfs's
+	if name, ok := linuxFilesystems[int64(uint32(st.Type))]; ok {
+		return name, nil
+	}
+	// WSL 1 mounts Windows drives as drvfs, which has no magic of its own
+	if IsWSL() && mountFSType(path) == "drvfs" {
+		return "drvfs", nil
+	}
+	return "unknown", nil
+}
+
+// ReadOnlyFilesystem reports whether path is on a filesystem mounted
+// read-only, where nothing can be changed or locked
+func ReadOnlyFilesystem(path string) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return false
+	}
+	return st.Flags&syscall.MS_RDONLY != 0 // ST_RDONLY shares the value
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package locker
+
+// filesystemType is not supported on this platform
+func filesystemType(path string) (string, error) {
+	return "unknown", nil
+}
+
+// ReadOnlyFilesystem is not supported on this platform
+func ReadOnlyFilesystem(path string) bool {
+	return false
+}
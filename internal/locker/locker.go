@@ -1,19 +1,263 @@
 package locker
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"runtime"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/baggiiiie/configlock/internal/fileutil"
 	"github.com/baggiiiie/configlock/internal/logger"
 )
 
+// Options controls which files inside locked directories are locked
+type Options struct {
+	MaxFileSize int64  // skip files larger than this many bytes (0 = no limit)
+	SkipBinary  bool   // skip files that look binary
+	Symlinks    string // policy for symlinks inside directories (see fileutil.Symlink*)
+
+	// Lock method per path prefix (see Backends), overriding detection
+	Methods map[string]string
+
+	// Directories locked without their subdirectories
+	Shallow []string
+
+	// File recording the modes the chmod fallback replaced, so unlocking
+	// restores them (empty = don't record)
+	ModeFile string
+
+	// Lock method for all other paths, overriding detection
+	Backend string
+
+	// How lock tools that need root are run (see Elevation*)
+	Elevation string
+
+	// What happens when some files of a directory fail to lock (see Failure*)
+	FailurePolicy string
+
+	// Files inside locked directories that are never locked, such as those
+	// excluded by FailureExclude
+	Excluded []string
+
+	// Leave files another process has open for writing unlocked, so they
+	// aren't locked mid-write; the next lock retries them
+	SkipOpenFiles bool
+}
+
+// Policies for the files of a directory that fail to lock
+const (
+	FailureSkip    = "skip"    // lock the other files and report the failures (default)
+	FailureAbort   = "abort"   // stop at the first failure and unlock the files locked so far
+	FailureExclude = "exclude" // exclude failing files from locking from now on
+)
+
+// ValidateFailurePolicy returns an error unless policy is one of Failure*
+// or empty
+func ValidateFailurePolicy(policy string) error {
+	switch policy {
+	case "", FailureSkip, FailureAbort, FailureExclude:
+		return nil
+	}
+	return fmt.Errorf("unknown lock failure policy %q (want %s, %s or %s)", policy, FailureSkip, FailureAbort, FailureExclude)
+}
+
+// errAborted stops a directory walk at the first failure under FailureAbort
+var errAborted = errors.New("aborted at the first failure")
+
+var (
+	optionsMu sync.RWMutex
+	options   Options
+)
+
+// SetOptions sets the options used by subsequent lock operations
+func SetOptions(opts Options) {
+	optionsMu.Lock()
+	defer optionsMu.Unlock()
+	options = opts
+}
+
+var (
+	loggerMu sync.RWMutex
+	log      *logger.Logger
+)
+
+// SetLogger sets the logger lock operations are reported to, instead of the
+// default logger
+func SetLogger(l *logger.Logger) {
+	loggerMu.Lock()
+	defer loggerMu.Unlock()
+	log = l
+}
+
+// getLogger returns the logger set with SetLogger, or the default logger
+func getLogger() *logger.Logger {
+	loggerMu.RLock()
+	defer loggerMu.RUnlock()
+	if log != nil {
+		return log
+	}
+	return logger.GetLogger()
+}
+
+// getOptions returns the current lock options
+func getOptions() Options {
+	optionsMu.RLock()
+	defer optionsMu.RUnlock()
+	return options
+}
+
+// ErrUnsupportedOS is returned by lock methods that don't work on this platform
+var ErrUnsupportedOS = errors.New("unsupported OS")
+
+// ErrLockNotApplied is returned when a lock tool reports success but reading
+// the lock back shows it isn't there, as on some FUSE mounts that accept
+// chattr and ignore the flag
+var ErrLockNotApplied = errors.New("lock reported success but is not applied")
+
+// LockError records a failure to lock or unlock a single file
+type LockError struct {
+	Path string
+	Err  error
+}
+
+func (e *LockError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *LockError) Unwrap() error {
+	return e.Err
+}
+
+// Report summarizes a lock operation over one or more files
+type Report struct {
+	Total    int
+	Locked   int
+	Skipped  int // files filtered by Options or that disappeared before locking
+	Excluded int // files left alone because they are in Options.Excluded
+	Failed   []LockError
+
+	// Files that failed to lock and are to be excluded from now on
+	// (FailureExclude). The caller adds them to Options.Excluded.
+	NewlyExcluded []LockError
+
+	// Aborted is set when FailureAbort stopped the operation and the files
+	// it had locked were unlocked again
+	Aborted bool
+
+	// Files left unlocked because another process had them open for
+	// writing (Options.SkipOpenFiles)
+	Busy []string
+}
+
+// Err returns nil if every file succeeded (or was excluded), the error itself
+// for a single file, or a *MultiError describing every failed file
+func (r *Report) Err() error {
+	if len(r.Failed) == 0 {
+		return nil
+	}
+	if r.Total == 1 {
+		return r.Failed[0].Err
+	}
+	if r.Aborted {
+		return fmt.Errorf("locking %s, the files locked before it were unlocked again: %w", errAborted, &r.Failed[0])
+	}
+	return newMultiError(r.Failed)
+}
+
+// maxErrorsInMessage limits how many per-file failures MultiError.Error prints
+const maxErrorsInMessage = 3
+
+// MultiError is returned when locking or unlocking a directory fails for one
+// or more files. Errors maps each failed path to its error.
+type MultiError struct {
+	Errors map[string]error
+}
+
+func newMultiError(failures []LockError) *MultiError {
+	m := &MultiError{Errors: make(map[string]error, len(failures))}
+	for _, failure := range failures {
+		m.Errors[failure.Path] = failure.Err
+	}
+	return m
+}
+
+// Paths returns the failed paths in sorted order
+func (m *MultiError) Paths() []string {
+	paths := make([]string, 0, len(m.Errors))
+	for path := range m.Errors {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Error summarizes the failures, listing the first few paths
+func (m *MultiError) Error() string {
+	paths := m.Paths()
+	var parts []string
+	for _, path := range paths[:min(len(paths), maxErrorsInMessage)] {
+		parts = append(parts, fmt.Sprintf("%s: %v", path, m.Errors[path]))
+	}
+	if len(paths) > maxErrorsInMessage {
+		parts = append(parts, fmt.Sprintf("and %d more", len(paths)-maxErrorsInMessage))
+	}
+	return fmt.Sprintf("%d file(s) failed: %s", len(paths), strings.Join(parts, "; "))
+}
+
+// Unwrap returns a *LockError per failed file so errors.Is and errors.As see
+// through
+func (m *MultiError) Unwrap() []error {
+	errs := make([]error, 0, len(m.Errors))
+	for _, path := range m.Paths() {
+		errs = append(errs, &LockError{Path: path, Err: m.Errors[path]})
+	}
+	return errs
+}
+
+// ProgressFunc is called after each file of a lock operation is processed.
+// total is 0 while a directory is still being walked.
+type ProgressFunc func(done, total int)
+
 // Lock applies immutable flags to a path recursively
 func Lock(path string) error {
+	return LockContext(context.Background(), path)
+}
+
+// LockContext is like Lock but stops early once ctx is cancelled, leaving
+// the files processed so far locked
+func LockContext(ctx context.Context, path string) error {
+	report, err := LockWithProgress(ctx, path, nil)
+	if err != nil {
+		return err
+	}
+	return report.Err()
+}
+
+// LockWithProgress applies immutable flags to a path recursively, calling
+// progress after each file and returning a per-file summary of the operation.
+// Files are locked while the directory is walked. If ctx is cancelled or the
+// walk fails, the remaining files are left alone and the error is returned
+// along with the report so far.
+func LockWithProgress(ctx context.Context, path string, progress ProgressFunc) (*Report, error) {
+	return lockTree(ctx, path, nil, progress)
+}
+
+// LockExcept is like LockWithProgress but leaves the files of a directory
+// that match one of patterns (see fileutil.InScope) alone
+func LockExcept(ctx context.Context, path string, patterns []string) (*Report, error) {
+	return lockTree(ctx, path, patterns, nil)
+}
+
+// lockTree locks path recursively, except files matching patterns
+func lockTree(ctx context.Context, path string, except []string, progress ProgressFunc) (*Report, error) {
+	reloadModes()
+	defer flushModes()
+
 	// Resolve symlinks
 	realPath, err := filepath.EvalSymlinks(path)
 	if err != nil {
@@ -24,49 +268,252 @@ func Lock(path string) error {
 	// Check if path exists
 	info, err := os.Stat(realPath)
 	if err != nil {
-		return fmt.Errorf("path does not exist: %s", realPath)
+		return nil, fmt.Errorf("path does not exist: %s", realPath)
 	}
 
-	// If it's a directory, collect files respecting .gitignore and lock each file
-	if info.IsDir() {
-		files, err := fileutil.CollectFilesRecursively(realPath)
-		if err != nil {
-			return fmt.Errorf("failed to collect files: %w", err)
+	backend, err := backendFor(realPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Backends such as a read-only bind mount cover the whole tree at once
+	if coversTree(backend) {
+		report := &Report{Total: 1}
+		if err := backend.Lock(realPath); err != nil {
+			report.Failed = append(report.Failed, LockError{Path: realPath, Err: err})
+		} else {
+			report.Locked++
 		}
+		if progress != nil {
+			progress(1, 1)
+		}
+		return report, nil
+	}
 
-		var lastErr error
-		for _, file := range files {
-			if err := lockFile(file); err != nil {
-				lastErr = err
+	opts := getOptions()
+	var busy map[string]bool
+	if opts.SkipOpenFiles {
+		if busy, err = openForWriting(realPath); err != nil {
+			getLogger().Warnf("Failed to find files open for writing in %s: %v", realPath, err)
+		}
+	}
+
+	report := &Report{}
+	var lockedFiles []string // locked so far, to undo under FailureAbort
+	// lock locks a single file and records the outcome
+	lock := func(file string) {
+		if busy[file] {
+			report.Busy = append(report.Busy, file)
+			return
+		}
+		report.Total++
+		if err := lockFile(file); err != nil {
+			if _, statErr := os.Lstat(file); os.IsNotExist(statErr) {
+				report.Skipped++
+			} else if opts.FailurePolicy == FailureExclude && info.IsDir() {
+				report.NewlyExcluded = append(report.NewlyExcluded, LockError{Path: file, Err: err})
+			} else {
+				report.Failed = append(report.Failed, LockError{Path: file, Err: err})
+			}
+		} else {
+			report.Locked++
+			if opts.FailurePolicy == FailureAbort {
+				lockedFiles = append(lockedFiles, file)
 			}
 		}
+	}
 
-		// Also lock the directory itself
-		if err := lockFile(realPath); err != nil {
-			lastErr = err
+	if !info.IsDir() {
+		if kind := fileutil.SpecialKind(info.Mode()); kind != "" {
+			return report, fmt.Errorf("%s is a %s, which can't be locked", realPath, kind)
 		}
+		lock(realPath)
+		if progress != nil {
+			progress(1, 1)
+		}
+		return report, nil
+	}
+
+	// Files are locked as the walk finds them, so the total is only known at the end
+	excluded := excludedSet(opts)
+	skipped, err := walkFiles(ctx, realPath, collectOptions(path, realPath), func(file string) error {
+		if len(except) > 0 && fileutil.InScope(realPath, file, except) {
+			return nil
+		}
+		if excluded[file] {
+			report.Excluded++
+			return nil
+		}
+		lock(file)
+		if progress != nil {
+			progress(report.Total, 0)
+		}
+		if opts.FailurePolicy == FailureAbort && len(report.Failed) > 0 {
+			return errAborted
+		}
+		return nil
+	})
+	report.Skipped += skipped
+	if ctx.Err() != nil {
+		return report, fmt.Errorf("locking %s interrupted: %w", realPath, ctx.Err())
+	}
+	if errors.Is(err, errAborted) {
+		abortLock(report, lockedFiles)
+		return report, nil
+	}
+	if err != nil {
+		return report, fmt.Errorf("failed to collect files: %w", err)
+	}
+	if excluded[realPath] {
+		report.Excluded++
+		return report, nil
+	}
+
+	// Also lock the directory itself, after its contents
+	lock(realPath)
+	if opts.FailurePolicy == FailureAbort && len(report.Failed) > 0 {
+		abortLock(report, lockedFiles)
+		return report, nil
+	}
+	if progress != nil {
+		progress(report.Total, report.Total)
+	}
+	return report, nil
+}
+
+// abortLock unlocks the files a directory lock stopped by FailureAbort had
+// locked, leaving the directory unlocked rather than partially locked
+func abortLock(report *Report, lockedFiles []string) {
+	for _, file := range lockedFiles {
+		if err := unlockFile(file); err == nil {
+			report.Locked--
+		}
+	}
+	report.Aborted = true
+}
+
+// excludedSet returns the files of opts.Excluded as a set
+func excludedSet(opts Options) map[string]bool {
+	set := make(map[string]bool, len(opts.Excluded))
+	for _, file := range opts.Excluded {
+		set[file] = true
+	}
+	return set
+}
+
+// collectOptions returns the file filters of the current options for a
+// locked directory, given as configured and with symlinks resolved
+func collectOptions(path, realPath string) fileutil.CollectOptions {
+	opts := getOptions()
+	return fileutil.CollectOptions{
+		MaxFileSize: opts.MaxFileSize,
+		SkipBinary:  opts.SkipBinary,
+		Symlinks:    opts.Symlinks,
+		Shallow:     slices.Contains(opts.Shallow, path) || slices.Contains(opts.Shallow, realPath),
+	}
+}
+
+// Covers reports whether locking the directory lockedPath locks path, an
+// entry inside it: path passes the size, binary, symlink and shallow filters
+// and is not one of Options.Excluded. An entry that no longer exists counts
+// as covered, so its removal is still seen.
+func Covers(lockedPath, path string) bool {
+	if _, err := os.Lstat(path); err != nil {
+		return true
+	}
+	realPath, err := filepath.EvalSymlinks(lockedPath)
+	if err != nil {
+		realPath = lockedPath
+	}
+	opts := collectOptions(lockedPath, realPath)
+	if rel, err := filepath.Rel(lockedPath, path); err == nil && opts.Shallow && strings.Contains(rel, string(filepath.Separator)) {
+		return false
+	}
+
+	skip := excludedSet(getOptions())
+	if skip[path] {
+		return false
+	}
+	if real, err := filepath.EvalSymlinks(path); err == nil && skip[real] {
+		return false
+	}
+	return fileutil.Collected(path, opts)
+}
 
-		return lastErr
+// CoveredFiles returns the files that locking path covers: path itself, or
+// the files inside it after size, binary and symlink filters. Paths are
+// below path with symlinks resolved.
+func CoveredFiles(ctx context.Context, path string) ([]string, error) {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		realPath = path
+	}
+	info, err := os.Stat(realPath)
+	if err != nil {
+		return nil, fmt.Errorf("path does not exist: %s", realPath)
+	}
+	if !info.IsDir() {
+		return []string{realPath}, nil
 	}
 
-	// For single files, lock directly
-	return lockFile(realPath)
+	var files []string
+	_, err = walkFiles(ctx, realPath, collectOptions(path, realPath), func(file string) error {
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect files: %w", err)
+	}
+	return files, nil
 }
 
-// lockFile locks a single file or directory
+// Lock strategies, chosen per filesystem
+const (
+	StrategyImmutable = "immutable-flag" // chattr +i on Linux, chflags uchg/schg on macOS
+	StrategyChmod     = "chmod"          // read-only permissions
+	StrategyACL       = "acl"            // deny-write ACL entry (setfacl, chmod +a)
+	StrategyBindRO    = "bind-ro"        // read-only bind mount over the locked path (Linux, root)
+)
+
+// chmodFilesystems lists filesystems that do not support immutable flags
+var chmodFilesystems = []string{
+	"nfs", "cifs", "smb2", "smbfs", "vfat", "msdos", "exfat", "ntfs",
+	"fuse", "osxfuse", "macfuse", "9p", "webdav", "drvfs",
+}
+
+// DetectStrategy returns the filesystem type containing path and the lock
+// strategy used for it. Unknown filesystems use immutable flags, which still
+// fall back to chmod if the flag cannot be applied.
+func DetectStrategy(path string) (fsType, strategy string) {
+	fsType, err := filesystemType(path)
+	if err != nil {
+		return "unknown", StrategyImmutable
+	}
+	if slices.Contains(chmodFilesystems, fsType) {
+		return fsType, StrategyChmod
+	}
+	return fsType, StrategyImmutable
+}
+
+// lockFile locks a single file or directory with the backend chosen for it
 func lockFile(path string) error {
-	switch runtime.GOOS {
-	case "linux":
-		return lockLinux(path)
-	case "darwin":
-		return lockDarwin(path)
-	default:
-		return fmt.Errorf("unsupported OS: %s", runtime.GOOS)
+	backend, err := backendFor(path)
+	if err != nil {
+		return err
 	}
+	return backend.Lock(path)
 }
 
 // Unlock removes immutable flags from a path recursively
 func Unlock(path string) error {
+	return UnlockContext(context.Background(), path)
+}
+
+// UnlockContext is like Unlock but stops early once ctx is cancelled
+func UnlockContext(ctx context.Context, path string) error {
+	reloadModes()
+	defer flushModes()
+
 	// Resolve symlinks
 	realPath, err := filepath.EvalSymlinks(path)
 	if err != nil {
@@ -80,6 +527,10 @@ func Unlock(path string) error {
 		return fmt.Errorf("path does not exist: %s", realPath)
 	}
 
+	if backend, err := backendFor(realPath); err == nil && coversTree(backend) {
+		return backend.Unlock(realPath)
+	}
+
 	// If it's a directory, collect files respecting .gitignore and unlock each file
 	if info.IsDir() {
 		// Unlock the directory itself first
@@ -87,128 +538,240 @@ func Unlock(path string) error {
 			return fmt.Errorf("failed to unlock directory: %w", err)
 		}
 
-		files, err := fileutil.CollectFilesRecursively(realPath)
+		// Size and binary filters are not applied so files locked under
+		// earlier settings still get unlocked
+		var failures []LockError
+		_, err := walkFiles(ctx, realPath, fileutil.CollectOptions{
+			Symlinks: getOptions().Symlinks,
+		}, func(file string) error {
+			if err := unlockFile(file); err != nil {
+				failures = append(failures, LockError{Path: file, Err: err})
+			}
+			return nil
+		})
+		if ctx.Err() != nil {
+			return fmt.Errorf("unlocking %s interrupted: %w", realPath, ctx.Err())
+		}
 		if err != nil {
 			return fmt.Errorf("failed to collect files: %w", err)
 		}
-
-		var lastErr error
-		for _, file := range files {
-			if err := unlockFile(file); err != nil {
-				lastErr = err
-			}
+		if len(failures) > 0 {
+			return newMultiError(failures)
 		}
-		return lastErr
+		return nil
 	}
 
 	// For single files, unlock directly
 	return unlockFile(realPath)
 }
 
-// unlockFile unlocks a single file or directory
+// UnlockScope unlocks only the files of a directory that match one of
+// patterns (see fileutil.InScope), leaving the directory and other files locked
+func UnlockScope(ctx context.Context, path string, patterns []string) error {
+	reloadModes()
+	defer flushModes()
+
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		realPath = path
+	}
+
+	info, err := os.Stat(realPath)
+	if err != nil {
+		return fmt.Errorf("path does not exist: %s", realPath)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", realPath)
+	}
+	if backend, err := backendFor(realPath); err == nil && coversTree(backend) {
+		return fmt.Errorf("%s is locked with %s, which cannot unlock single files", realPath, backend.Name())
+	}
+
+	var failures []LockError
+	_, err = walkFiles(ctx, realPath, fileutil.CollectOptions{
+		Symlinks: getOptions().Symlinks,
+	}, func(file string) error {
+		if !fileutil.InScope(realPath, file, patterns) {
+			return nil
+		}
+		if err := unlockFile(file); err != nil {
+			failures = append(failures, LockError{Path: file, Err: err})
+		}
+		return nil
+	})
+	if ctx.Err() != nil {
+		return fmt.Errorf("unlocking %s interrupted: %w", realPath, ctx.Err())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to collect files: %w", err)
+	}
+	if len(failures) > 0 {
+		return newMultiError(failures)
+	}
+	return nil
+}
+
+// unlockFile unlocks a single file or directory with the backend chosen for it
 func unlockFile(path string) error {
-	switch runtime.GOOS {
-	case "linux":
-		return unlockLinux(path)
-	case "darwin":
-		return unlockDarwin(path)
-	default:
-		return fmt.Errorf("unsupported OS: %s", runtime.GOOS)
+	backend, err := backendFor(path)
+	if err != nil {
+		return err
 	}
+	return backend.Unlock(path)
 }
 
 // lockLinux applies immutable flag on Linux (for a single file)
 func lockLinux(path string) error {
-	cmd := exec.Command("chattr", "+i", path)
+	cmd := privilegedCommand("chattr", "+i", path)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		// Try fallback to chmod
 		if err := fallbackLock(path); err != nil {
-			return fmt.Errorf("chattr failed and fallback failed: %v, output: %s", err, string(output))
+			return fmt.Errorf("chattr failed and fallback failed: %w, output: %s", err, string(output))
+		}
+		getLogger().Infof("LOCK (fallback): made %s read-only (chattr +i failed: %v)", path, err)
+		return nil
+	}
+	// Read the flag back: some filesystems accept chattr +i and ignore it
+	if err := verifyFlag(path, linuxFlags, "i"); err != nil {
+		// Clear the flag in case it was set after all, or the fallback
+		// can't change the mode
+		privilegedCommand("chattr", "-i", path).Run()
+		if fallbackErr := fallbackLock(path); fallbackErr != nil {
+			return fmt.Errorf("%w, and fallback failed: %w", err, fallbackErr)
 		}
-		logger.GetLogger().Infof("LOCK (fallback): chmod 444 %s (chattr +i failed: %v)", path, err)
+		getLogger().Infof("LOCK (fallback): made %s read-only (%v)", path, err)
 		return nil
 	}
-	logger.GetLogger().Infof("LOCK: chattr +i %s", path)
+	getLogger().Debugf("LOCK: chattr +i %s", path)
 	return nil
 }
 
 // unlockLinux removes immutable flag on Linux (for a single file)
 func unlockLinux(path string) error {
-	cmd := exec.Command("chattr", "-i", path)
+	cmd := privilegedCommand("chattr", "-i", path)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		// Try fallback to chmod
 		if err := fallbackUnlock(path); err != nil {
 			return fmt.Errorf("chattr failed and fallback failed: %v, output: %s", err, string(output))
 		}
-		logger.GetLogger().Infof("UNLOCK (fallback): chmod 644 %s (chattr -i failed: %v)", path, err)
+		getLogger().Infof("UNLOCK (fallback): restored mode of %s (chattr -i failed: %v)", path, err)
 		return nil
 	}
-	logger.GetLogger().Infof("UNLOCK: chattr -i %s", path)
-	return nil
+	getLogger().Debugf("UNLOCK: chattr -i %s", path)
+	return restoreFallbackMode(path)
 }
 
 // lockDarwin applies immutable flag on macOS (for a single file)
 func lockDarwin(path string) error {
 	// Try uchg first (user immutable, doesn't require root)
-	cmd := exec.Command("chflags", "uchg", path)
+	cmd := command("chflags", "uchg", path)
 	output, err := cmd.CombinedOutput()
 	if err == nil {
-		logger.GetLogger().Infof("LOCK: chflags uchg %s", path)
+		err = verifyFlag(path, darwinFlags, "uchg")
+	}
+	if err == nil {
+		getLogger().Debugf("LOCK: chflags uchg %s", path)
 		return nil
 	}
 
 	// If uchg fails, try schg (system immutable, requires root)
-	cmd = exec.Command("chflags", "schg", path)
+	cmd = privilegedCommand("chflags", "schg", path)
 	output, err = cmd.CombinedOutput()
 	if err == nil {
-		logger.GetLogger().Infof("LOCK: chflags schg %s", path)
+		err = verifyFlag(path, darwinFlags, "schg")
+	}
+	if err == nil {
+		getLogger().Debugf("LOCK: chflags schg %s", path)
 		return nil
 	}
 
 	// If both fail, fall back to chmod
 	if err := fallbackLock(path); err != nil {
-		return fmt.Errorf("chflags uchg and schg failed, chmod fallback also failed: %v, output: %s", err, string(output))
+		return fmt.Errorf("chflags uchg and schg failed, chmod fallback also failed: %w, output: %s", err, string(output))
 	}
-	logger.GetLogger().Infof("LOCK (fallback): chmod 444 %s (chflags uchg and schg failed: %v)", path, err)
+	getLogger().Infof("LOCK (fallback): made %s read-only (chflags uchg and schg failed: %v)", path, err)
 	return nil
 }
 
 // unlockDarwin removes immutable flag on macOS (for a single file)
 func unlockDarwin(path string) error {
 	// Try removing uchg first (user immutable)
-	cmd := exec.Command("chflags", "nouchg", path)
+	cmd := command("chflags", "nouchg", path)
 	output, err := cmd.CombinedOutput()
 	if err == nil {
-		logger.GetLogger().Infof("UNLOCK: chflags nouchg %s", path)
-		return nil
+		getLogger().Debugf("UNLOCK: chflags nouchg %s", path)
+		return restoreFallbackMode(path)
 	}
 
 	// If that fails, try removing schg (system immutable)
-	cmd = exec.Command("chflags", "noschg", path)
+	cmd = privilegedCommand("chflags", "noschg", path)
 	output, err = cmd.CombinedOutput()
 	if err == nil {
-		logger.GetLogger().Infof("UNLOCK: chflags noschg %s", path)
-		return nil
+		getLogger().Debugf("UNLOCK: chflags noschg %s", path)
+		return restoreFallbackMode(path)
 	}
 
 	// If both fail, fall back to chmod
 	if err := fallbackUnlock(path); err != nil {
 		return fmt.Errorf("chflags nouchg and noschg failed, chmod fallback also failed: %v, output: %s", err, string(output))
 	}
-	logger.GetLogger().Infof("UNLOCK (fallback): chmod 644 %s (chflags nouchg and noschg failed: %v)", path, err)
+	getLogger().Infof("UNLOCK (fallback): restored mode of %s (chflags nouchg and noschg failed: %v)", path, err)
 	return nil
 }
 
-// fallbackLock sets read-only permissions as fallback (for a single file)
+// fallbackLock sets read-only permissions as fallback (for a single file),
+// recording the file's mode so fallbackUnlock can restore it
 func fallbackLock(path string) error {
-	return os.Chmod(path, 0444)
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	rememberMode(path, info.Mode())
+	if err := os.Chmod(path, lockedMode(path, info.Mode())); err != nil {
+		return err
+	}
+	// Read the mode back: some filesystems accept chmod and ignore it
+	if info, err = os.Stat(path); err != nil {
+		return err
+	}
+	if !hasLockedMode(path, info) {
+		return fmt.Errorf("%w: %s is still mode %v after chmod", ErrLockNotApplied, path, info.Mode().Perm())
+	}
+	return nil
 }
 
-// fallbackUnlock sets writable permissions as fallback (for a single file)
+// restoreFallbackMode undoes the chmod fallback on path after its flag was
+// cleared. A lock whose flag the filesystem ignored fell back to chmod, which
+// clearing the flag doesn't undo.
+func restoreFallbackMode(path string) error {
+	if !hasRecordedMode(path) {
+		return nil
+	}
+	if err := fallbackUnlock(path); err != nil {
+		return fmt.Errorf("failed to restore mode of %s: %w", path, err)
+	}
+	getLogger().Infof("UNLOCK (fallback): restored mode of %s", path)
+	return nil
+}
+
+// fallbackUnlock restores the permissions a file had before fallbackLock
+// (for a single file)
 func fallbackUnlock(path string) error {
-	return os.Chmod(path, 0644)
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	mode, ok := originalMode(path, info)
+	if !ok {
+		return nil
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		return err
+	}
+	forgetMode(path)
+	return nil
 }
 
 // IsLocked checks if a path has immutable flags set
@@ -224,21 +787,59 @@ func IsLocked(path string) (bool, error) {
 		return false, fmt.Errorf("path does not exist: %s", realPath)
 	}
 
-	switch runtime.GOOS {
-	case "linux":
-		return isLockedLinux(realPath)
-	case "darwin":
-		return isLockedDarwin(realPath)
-	default:
-		return false, fmt.Errorf("unsupported OS: %s", runtime.GOOS)
+	backend, err := backendFor(realPath)
+	if err != nil {
+		return false, err
 	}
+	return backend.IsLocked(realPath)
+}
+
+// UnlockEntry removes the immutable flag from path alone, not the contents
+// of a directory, and reports whether path was locked, so that the caller can
+// lock it again with LockEntry after changing it
+func UnlockEntry(path string) (bool, error) {
+	reloadModes()
+	defer flushModes()
+
+	backend, err := backendFor(path)
+	if err != nil {
+		return false, err
+	}
+	locked, err := backend.IsLocked(path)
+	if err != nil || !locked {
+		return false, err
+	}
+	if coversTree(backend) {
+		return true, fmt.Errorf("%s is locked by a backend that covers the whole tree", path)
+	}
+	if err := backend.Unlock(path); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// LockEntry applies the immutable flag to path alone, not the contents of a
+// directory
+func LockEntry(path string) error {
+	reloadModes()
+	defer flushModes()
+	return lockFile(path)
+}
+
+// isReadOnly checks if a path has the read-only permissions set by fallbackLock
+func isReadOnly(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return hasLockedMode(path, info), nil
 }
 
 // isLockedLinux checks if immutable flag is set on Linux
 func isLockedLinux(path string) (bool, error) {
-	// Use lsattr to check if immutable flag is set
-	cmd := exec.Command("lsattr", path)
-	output, err := cmd.CombinedOutput()
+	// Use lsattr to check if immutable flag is set, -d so a directory
+	// reports its own flags instead of its entries'
+	flags, err := linuxFlags(path)
 	if err != nil {
 		// If lsattr is not available or fails, check permissions
 		info, statErr := os.Stat(path)
@@ -246,24 +847,29 @@ func isLockedLinux(path string) (bool, error) {
 			return false, statErr
 		}
 		// Check if read-only (fallback check)
-		return info.Mode().Perm() == 0444, nil
+		return hasLockedMode(path, info), nil
 	}
 
-	// lsattr output format: "----i--------e----- /path/to/file"
-	// Check if 'i' flag is present (5th character)
-	outputStr := string(output)
-	if len(outputStr) >= 5 {
-		return strings.Contains(outputStr[:20], "i"), nil
-	}
+	// Check if 'i' flag is present in the flags field
+	return strings.Contains(flags, "i"), nil
+}
 
-	return false, nil
+// linuxFlags returns the attribute flags lsattr reports for path itself,
+// e.g. "----i--------e-----"
+func linuxFlags(path string) (string, error) {
+	output, err := command("lsattr", "-d", path).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("lsattr failed: %v, output: %s", err, string(output))
+	}
+	// lsattr output format: "----i--------e----- /path/to/file"
+	flags, _, _ := strings.Cut(string(output), " ")
+	return flags, nil
 }
 
 // isLockedDarwin checks if immutable flag is set on macOS
 func isLockedDarwin(path string) (bool, error) {
 	// Use stat command to check file flags
-	cmd := exec.Command("stat", "-f", "%Sf", path)
-	output, err := cmd.CombinedOutput()
+	flags, err := darwinFlags(path)
 	if err != nil {
 		// If stat fails, fallback to permission check
 		info, statErr := os.Stat(path)
@@ -271,10 +877,31 @@ func isLockedDarwin(path string) (bool, error) {
 			return false, statErr
 		}
 		// Fallback to permission check
-		return info.Mode().Perm() == 0444, nil
+		return hasLockedMode(path, info), nil
 	}
 
 	// Check if output contains "uchg" (user immutable) or "schg" (system immutable) flag
-	outputStr := string(output)
-	return strings.Contains(outputStr, "uchg") || strings.Contains(outputStr, "schg"), nil
+	return strings.Contains(flags, "uchg") || strings.Contains(flags, "schg"), nil
+}
+
+// darwinFlags returns the file flags stat reports for path, e.g. "uchg"
+func darwinFlags(path string) (string, error) {
+	output, err := command("stat", "-f", "%Sf", path).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("stat failed: %v, output: %s", err, string(output))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// verifyFlag reads the flags of path back with read and checks that flag was
+// set, returning ErrLockNotApplied when the filesystem ignored it
+func verifyFlag(path string, read func(string) (string, error), flag string) error {
+	flags, err := read(path)
+	if err != nil {
+		return fmt.Errorf("failed to verify lock: %w", err)
+	}
+	if !strings.Contains(flags, flag) {
+		return fmt.Errorf("%w: %s has flags %q without %s", ErrLockNotApplied, path, flags, flag)
+	}
+	return nil
 }
@@ -0,0 +1,231 @@
+package locker
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/baggiiiie/configlock/internal/pathutil"
+)
+
+// ValidStrategy reports whether method names a registered lock backend
+func ValidStrategy(method string) bool {
+	_, ok := getBackend(method)
+	return ok
+}
+
+// StrategyFor returns the lock method for path: the per-path override with
+// the longest matching prefix, then the configured default backend, then the
+// method detected from the filesystem
+func StrategyFor(path string) string {
+	opts := getOptions()
+	path = filepath.Clean(path)
+	best, method := "", ""
+	for prefix, m := range opts.Methods {
+		prefix = filepath.Clean(prefix)
+		if !pathutil.Within(path, prefix) {
+			continue
+		}
+		if len(prefix) > len(best) {
+			best, method = prefix, m
+		}
+	}
+	if method != "" {
+		if ValidStrategy(method) {
+			return method
+		}
+		getLogger().Warnf("Unknown lock method %q for %s, detecting from filesystem", method, best)
+	}
+	if opts.Backend != "" {
+		if ValidStrategy(opts.Backend) {
+			return opts.Backend
+		}
+		getLogger().Warnf("Unknown lock backend %q, detecting from filesystem", opts.Backend)
+	}
+	_, strategy := DetectStrategy(path)
+	return strategy
+}
+
+// lockACL adds an ACL entry that denies writes (for a single file). On Linux
+// that is the owner's entry, which is the owner's mode bits: the owner can
+// chmod them back, so there the method protects no more than the chmod
+// fallback. The mode is recorded like the fallback's so unlocking restores it.
+func lockACL(path string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux":
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		rememberMode(path, info.Mode())
+		// The owner's ACL entry is what governs the owner's access
+		cmd = command("setfacl", "-m", "u::r-X", path)
+	case "darwin":
+		cmd = command("chmod", "+a", "everyone deny write,delete,append,writeattr,writeextattr", path)
+	default:
+		return fmt.Errorf("acl lock method: %w: %s", ErrUnsupportedOS, runtime.GOOS)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("acl lock failed: %v, output: %s", err, string(output))
+	}
+	// Read the entry back: some filesystems accept ACLs and ignore them
+	locked, err := isLockedACL(path)
+	if err != nil {
+		return fmt.Errorf("failed to verify acl lock: %w", err)
+	}
+	if !locked {
+		return fmt.Errorf("%w: %s has no deny-write ACL entry", ErrLockNotApplied, path)
+	}
+	getLogger().Debugf("LOCK (acl): %s", path)
+	return nil
+}
+
+// unlockACL removes the ACL entry added by lockACL (for a single file)
+func unlockACL(path string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux":
+		// Restore the recorded mode rather than granting the owner write
+		// access the file may never have had
+		if hasRecordedMode(path) {
+			if err := fallbackUnlock(path); err != nil {
+				return fmt.Errorf("acl unlock failed: %w", err)
+			}
+			getLogger().Debugf("UNLOCK (acl): %s", path)
+			return nil
+		}
+		cmd = command("setfacl", "-m", "u::rwX", path)
+	case "darwin":
+		cmd = command("chmod", "-a", "everyone deny write,delete,append,writeattr,writeextattr", path)
+	default:
+		return fmt.Errorf("acl lock method: %w: %s", ErrUnsupportedOS, runtime.GOOS)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		// The entry may already be gone on macOS
+		if runtime.GOOS == "darwin" && strings.Contains(string(output), "No such") {
+			return nil
+		}
+		return fmt.Errorf("acl unlock failed: %v, output: %s", err, string(output))
+	}
+	getLogger().Debugf("UNLOCK (acl): %s", path)
+	return nil
+}
+
+// isLockedACL checks whether the deny-write ACL entry is present
+func isLockedACL(path string) (bool, error) {
+	switch runtime.GOOS {
+	case "linux":
+		output, err := command("getfacl", "-c", path).CombinedOutput()
+		if err != nil {
+			return false, fmt.Errorf("getfacl failed: %v, output: %s", err, string(output))
+		}
+		for _, line := range strings.Split(string(output), "\n") {
+			if strings.HasPrefix(line, "user::") {
+				return !strings.Contains(line, "w"), nil
+			}
+		}
+		return false, nil
+	case "darwin":
+		output, err := command("ls", "-led", path).CombinedOutput()
+		if err != nil {
+			return false, fmt.Errorf("ls failed: %v, output: %s", err, string(output))
+		}
+		return strings.Contains(string(output), "everyone deny write"), nil
+	default:
+		return false, fmt.Errorf("acl lock method: %w: %s", ErrUnsupportedOS, runtime.GOOS)
+	}
+}
+
+// lockBindReadOnly bind-mounts path read-only over itself. One mount covers
+// a whole directory tree, so this is applied to the locked path only.
+func lockBindReadOnly(path string) error {
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("bind-ro lock method is only supported on Linux: %w", ErrUnsupportedOS)
+	}
+	if locked, _ := isBindReadOnly(path); locked {
+		return nil
+	}
+	if output, err := command("mount", "--bind", path, path).CombinedOutput(); err != nil {
+		return fmt.Errorf("bind mount failed: %v, output: %s", err, string(output))
+	}
+	if output, err := command("mount", "-o", "remount,bind,ro", path).CombinedOutput(); err != nil {
+		// Don't leave a writable bind mount behind
+		command("umount", path).Run()
+		return fmt.Errorf("read-only remount failed: %v, output: %s", err, string(output))
+	}
+	if locked, _ := isBindReadOnly(path); !locked {
+		command("umount", path).Run()
+		return fmt.Errorf("%w: %s is not a read-only mount point after remounting", ErrLockNotApplied, path)
+	}
+	getLogger().Infof("LOCK (bind-ro): mount --bind -o ro %s", path)
+	return nil
+}
+
+// unlockBindReadOnly removes the read-only bind mount from path
+func unlockBindReadOnly(path string) error {
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("bind-ro lock method is only supported on Linux: %w", ErrUnsupportedOS)
+	}
+	if locked, _ := isBindReadOnly(path); !locked {
+		return nil
+	}
+	if output, err := command("umount", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("umount failed: %v, output: %s", err, string(output))
+	}
+	getLogger().Infof("UNLOCK (bind-ro): umount %s", path)
+	return nil
+}
+
+// isBindReadOnly checks whether path is itself a read-only mount point
+func isBindReadOnly(path string) (bool, error) {
+	mountPoint, readOnly, err := containingMount(path)
+	return mountPoint == path && readOnly, err
+}
+
+// isOnReadOnlyMount checks whether the mount containing path is read-only,
+// which covers files inside a directory locked with bind-ro
+func isOnReadOnlyMount(path string) (bool, error) {
+	_, readOnly, err := containingMount(path)
+	return readOnly, err
+}
+
+// containingMount returns the mount point that contains path and whether it
+// is mounted read-only
+func containingMount(path string) (string, bool, error) {
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", false, err
+	}
+	defer file.Close()
+
+	// mountinfo fields: id parent major:minor root mountpoint options ...
+	// Later entries shadow earlier ones mounted at the same point
+	best, readOnly := "", false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		mountPoint := unescapeMountPath(fields[4])
+		if path != mountPoint && mountPoint != "/" && !strings.HasPrefix(path, mountPoint+"/") {
+			continue
+		}
+		if len(mountPoint) >= len(best) {
+			best = mountPoint
+			readOnly = slices.Contains(strings.Split(fields[5], ","), "ro")
+		}
+	}
+	return best, readOnly, scanner.Err()
+}
+
+// unescapeMountPath decodes the octal escapes used in mountinfo paths
+func unescapeMountPath(path string) string {
+	return strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`).Replace(path)
+}
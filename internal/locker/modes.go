@@ -0,0 +1,201 @@
+package locker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/baggiiiie/configlock/internal/fileutil"
+)
+
+// readOnlyMode is the permission the chmod fallback locks files with
+const readOnlyMode fs.FileMode = 0o444
+
+// readOnlyDirMode is the permission the chmod fallback locks directories
+// with, keeping them searchable
+const readOnlyDirMode fs.FileMode = 0o555
+
+// writeBits are the permission bits the chmod fallback removes from
+// sensitive paths
+const writeBits fs.FileMode = 0o222
+
+// modeBits are the bits of a file mode that os.Chmod sets
+const modeBits = fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky
+
+// Original modes of files locked by the chmod fallback, keyed by path. They
+// are read from the mode file at the start of each operation and the changes
+// written back at its end, merged with what other processes recorded since.
+var (
+	modesMu    sync.Mutex
+	modes      map[string]fs.FileMode
+	modesDirty = make(map[string]bool) // paths recorded or restored since the last flush
+)
+
+// readModeFile reads the recorded modes, stored as octal strings
+func readModeFile(path string) (map[string]fs.FileMode, error) {
+	stored := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	result := make(map[string]fs.FileMode, len(stored))
+	for file, octal := range stored {
+		if mode, err := strconv.ParseUint(octal, 8, 32); err == nil {
+			result[file] = fs.FileMode(mode) & modeBits
+		}
+	}
+	return result, nil
+}
+
+// reloadModes reads the mode file, keeping changes not yet flushed
+func reloadModes() {
+	path := getOptions().ModeFile
+	if path == "" {
+		return
+	}
+	onDisk, err := readModeFile(path)
+	if err != nil {
+		getLogger().Warnf("Failed to read original file modes: %v", err)
+		return
+	}
+
+	modesMu.Lock()
+	defer modesMu.Unlock()
+	for file := range modesDirty {
+		if mode, ok := modes[file]; ok {
+			onDisk[file] = mode
+		} else {
+			delete(onDisk, file)
+		}
+	}
+	modes = onDisk
+}
+
+// flushModes writes the recorded modes back, merged with the mode file
+func flushModes() {
+	path := getOptions().ModeFile
+	modesMu.Lock()
+	dirty := len(modesDirty) > 0
+	modesMu.Unlock()
+	if path == "" || !dirty {
+		return
+	}
+
+	reloadModes()
+	modesMu.Lock()
+	defer modesMu.Unlock()
+	stored := make(map[string]string, len(modes))
+	for file, mode := range modes {
+		stored[file] = fmt.Sprintf("%04o", uint32(mode))
+	}
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(path, data, 0o600)
+	}
+	if err != nil {
+		getLogger().Warnf("Failed to save original file modes: %v", err)
+		return
+	}
+	clear(modesDirty)
+}
+
+// rememberMode records the mode of path before the chmod fallback first
+// makes it read-only. A recorded mode is kept, since re-locking a file that
+// is still read-only would otherwise record the lock itself.
+func rememberMode(path string, mode fs.FileMode) {
+	modesMu.Lock()
+	defer modesMu.Unlock()
+	if modes == nil {
+		modes = make(map[string]fs.FileMode)
+	}
+	if _, ok := modes[path]; ok {
+		return
+	}
+	modes[path] = mode & modeBits
+	modesDirty[path] = true
+}
+
+// lockedMode returns the mode the chmod fallback gives a file with mode:
+// read-only for everyone, or for sensitive paths such as ~/.ssh the same
+// mode without write permission, so private keys aren't made readable
+func lockedMode(path string, mode fs.FileMode) fs.FileMode {
+	if _, ok := fileutil.SensitivePath(path); ok {
+		return mode & modeBits &^ writeBits
+	}
+	return readOnlyFor(mode)
+}
+
+// readOnlyFor returns readOnlyDirMode for a directory and readOnlyMode for
+// anything else
+func readOnlyFor(mode fs.FileMode) fs.FileMode {
+	if mode.IsDir() {
+		return readOnlyDirMode
+	}
+	return readOnlyMode
+}
+
+// hasLockedMode reports whether info has a mode the chmod fallback sets
+func hasLockedMode(path string, info fs.FileInfo) bool {
+	if _, ok := fileutil.SensitivePath(path); ok {
+		return info.Mode().Perm()&writeBits == 0
+	}
+	return info.Mode().Perm() == readOnlyFor(info.Mode())
+}
+
+// originalMode returns the mode to restore when unlocking path: the recorded
+// one, or for a file locked before modes were recorded, 0644 for files and
+// 0755 for directories (0600 and 0700 for sensitive paths). ok is false when
+// path was not locked by the fallback and its mode should be left alone.
+func originalMode(path string, info fs.FileInfo) (mode fs.FileMode, ok bool) {
+	modesMu.Lock()
+	mode, recorded := modes[path]
+	modesMu.Unlock()
+	_, sensitive := fileutil.SensitivePath(path)
+	switch {
+	case recorded:
+		return mode, true
+	case info.Mode().Perm() != readOnlyMode && info.Mode().Perm() != readOnlyFor(info.Mode()):
+		return 0, false
+	case sensitive && info.IsDir():
+		return 0o700, true
+	case sensitive:
+		return 0o600, true
+	case info.IsDir():
+		return 0o755, true
+	default:
+		return 0o644, true
+	}
+}
+
+// hasRecordedMode reports whether the chmod fallback recorded a mode for path,
+// i.e. it is still locked by the fallback
+func hasRecordedMode(path string) bool {
+	modesMu.Lock()
+	defer modesMu.Unlock()
+	_, ok := modes[path]
+	return ok
+}
+
+// forgetMode drops the recorded mode of path once it has been restored
+func forgetMode(path string) {
+	modesMu.Lock()
+	defer modesMu.Unlock()
+	if _, ok := modes[path]; ok {
+		delete(modes, path)
+		modesDirty[path] = true
+	}
+}
@@ -0,0 +1,34 @@
+package locker
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// IsOpen reports whether any process currently has the file (or any file inside
+// the directory) open, using lsof and falling back to fuser. Like the lock
+// tools, both are only run from root-owned system directories.
+func IsOpen(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := toolPath("lsof"); err == nil {
+		args := []string{"-t", "--", path}
+		if info.IsDir() {
+			args = []string{"-t", "+D", path}
+		}
+		output, err := command("lsof", args...).Output()
+		// lsof exits 1 when nothing has the file open
+		return err == nil && len(strings.TrimSpace(string(output))) > 0, nil
+	}
+
+	if _, err := toolPath("fuser"); err == nil && !info.IsDir() {
+		// fuser -s exits 0 only when some process uses the file
+		return command("fuser", "-s", path).Run() == nil, nil
+	}
+
+	return false, fmt.Errorf("neither lsof nor fuser is available from a trusted location")
+}
@@ -0,0 +1,45 @@
+package locker
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// openForWriting returns the files at or below root that another process
+// has open for writing, as listed by lsof
+func openForWriting(root string) (map[string]bool, error) {
+	args := []string{"-w", "-F", "pan"}
+	if info, err := os.Stat(root); err == nil && info.IsDir() {
+		args = append(args, "+D", root)
+	} else {
+		args = append(args, "--", root)
+	}
+	// lsof exits with 1 when no process has any of the files open
+	output, err := command("lsof", args...).Output()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, err
+	}
+
+	// One field per line: p<pid> per process, then f<fd>, a<access> and
+	// n<name> per file descriptor
+	self := "p" + strconv.Itoa(os.Getpid())
+	open := make(map[string]bool)
+	var ownProcess, writing bool
+	for _, line := range strings.Split(string(output), "\n") {
+		switch {
+		case strings.HasPrefix(line, "p"):
+			ownProcess = line == self
+		case strings.HasPrefix(line, "f"):
+			writing = false
+		case strings.HasPrefix(line, "a"):
+			writing = strings.ContainsAny(line[1:], "wu")
+		case strings.HasPrefix(line, "n") && writing && !ownProcess:
+			open[line[1:]] = true
+		}
+	}
+	return open, nil
+}
@@ -0,0 +1,60 @@
+package locker
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/baggiiiie/configlock/internal/pathutil"
+)
+
+// openForWriting returns the files at or below root that another process
+// has open for writing, read from /proc. Only processes of the same user can
+// be inspected without root, which are the ones expected to write its files.
+func openForWriting(root string) (map[string]bool, error) {
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	self := strconv.Itoa(os.Getpid())
+	open := make(map[string]bool)
+	for _, proc := range procs {
+		pid := proc.Name()
+		if _, err := strconv.Atoi(pid); err != nil || pid == self {
+			continue
+		}
+		fdDir := filepath.Join("/proc", pid, "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue // exited, or another user's
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !pathutil.Within(target, root) {
+				continue
+			}
+			if fdWritable(filepath.Join("/proc", pid, "fdinfo", fd.Name())) {
+				open[target] = true
+			}
+		}
+	}
+	return open, nil
+}
+
+// fdWritable reports whether the fdinfo of a file descriptor shows it open
+// for writing
+func fdWritable(fdinfo string) bool {
+	data, err := os.ReadFile(fdinfo)
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "flags:"); ok {
+			flags, err := strconv.ParseUint(strings.TrimSpace(value), 8, 32)
+			return err == nil && flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0
+		}
+	}
+	return false
+}
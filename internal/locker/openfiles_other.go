@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package locker
+
+// openForWriting is not supported on this platform
+func openForWriting(root string) (map[string]bool, error) {
+	return nil, nil
+}
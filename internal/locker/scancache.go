@@ -0,0 +1,112 @@
+package locker
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+
+	"github.com/baggiiiie/configlock/internal/fileutil"
+	"github.com/baggiiiie/configlock/internal/pathutil"
+)
+
+// maxCachedFiles is the largest tree whose file list is kept between walks;
+// bigger ones are walked every time rather than held in memory
+const maxCachedFiles = 20000
+
+// scanKey identifies a cached directory scan: the same directory walked with
+// different filters yields different files
+type scanKey struct {
+	dir  string
+	opts fileutil.CollectOptions
+}
+
+var (
+	scanCacheMu   sync.Mutex
+	scanCache     = make(map[scanKey]*fileutil.Scan)
+	loggedSpecial = make(map[string]bool) // special files already logged as skipped
+)
+
+// walkFiles calls fn for each file of dir under opts and returns how many
+// were skipped. While none of the directories of the previous walk have
+// changed its files are replayed, otherwise files are passed to fn as the
+// walk finds them.
+func walkFiles(ctx context.Context, dir string, opts fileutil.CollectOptions, fn func(path string) error) (int, error) {
+	key := scanKey{dir: dir, opts: opts}
+
+	scanCacheMu.Lock()
+	cached, ok := scanCache[key]
+	scanCacheMu.Unlock()
+	if ok && !cached.Stale() {
+		for _, file := range cached.Files {
+			if err := ctx.Err(); err != nil {
+				return cached.Skipped, err
+			}
+			if err := fn(file); err != nil {
+				return cached.Skipped, err
+			}
+		}
+		return cached.Skipped, nil
+	}
+
+	var files []string
+	cacheable := true
+	scan, err := fileutil.WalkFiles(ctx, dir, opts, func(path string) error {
+		if cacheable {
+			files = append(files, path)
+			if len(files) > maxCachedFiles {
+				files, cacheable = nil, false
+			}
+		}
+		return fn(path)
+	})
+	logSpecial(scan.Special)
+	// An interrupted walk is incomplete, don't keep it
+	if err == nil && cacheable {
+		scan.Files = files
+		scanCacheMu.Lock()
+		scanCache[key] = scan
+		scanCacheMu.Unlock()
+	}
+	return scan.Skipped, err
+}
+
+// logSpecial logs the special files a walk skipped, each once per process
+func logSpecial(special map[string]string) {
+	scanCacheMu.Lock()
+	defer scanCacheMu.Unlock()
+	for path, kind := range special {
+		if !loggedSpecial[path] {
+			loggedSpecial[path] = true
+			getLogger().Infof("Skipping %s inside a locked directory: %s", kind, path)
+		}
+	}
+}
+
+// InvalidateScan drops cached scans that path belongs to, for changes that
+// leave directory modification times alone such as a file growing past the
+// size limit. path may be a file or directory, inside or above a scanned one.
+func InvalidateScan(path string) {
+	// Scans are keyed by resolved paths; path itself may be gone already
+	paths := []string{path}
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		paths = append(paths, filepath.Join(dir, filepath.Base(path)))
+	}
+
+	scanCacheMu.Lock()
+	defer scanCacheMu.Unlock()
+	for key := range scanCache {
+		for _, p := range paths {
+			if pathutil.Within(p, key.dir) || pathutil.Within(key.dir, p) {
+				delete(scanCache, key)
+				break
+			}
+		}
+	}
+}
+
+// ClearScanCache drops every cached scan
+func ClearScanCache() {
+	scanCacheMu.Lock()
+	defer scanCacheMu.Unlock()
+	clear(scanCache)
+}
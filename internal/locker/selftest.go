@@ -0,0 +1,110 @@
+package locker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// ProbeResult describes how locking behaves on the filesystem of a path
+type ProbeResult struct {
+	FSType    string
+	Backend   string // backend chosen for the path
+	Effective string // backend the scratch file ended up locked with
+}
+
+// Fallback reports whether locks silently degrade to read-only permissions,
+// e.g. because chattr lacks CAP_LINUX_IMMUTABLE
+func (r ProbeResult) Fallback() bool {
+	return r.Effective != r.Backend
+}
+
+// backendTools lists the system tools a backend runs on this platform
+func backendTools(backend string) []string {
+	switch {
+	case backend == StrategyImmutable && runtime.GOOS == "linux":
+		return []string{"chattr", "lsattr"}
+	case backend == StrategyImmutable && runtime.GOOS == "darwin":
+		return []string{"chflags", "stat"}
+	case backend == StrategyACL && runtime.GOOS == "linux":
+		return []string{"setfacl", "getfacl"}
+	case backend == StrategyACL && runtime.GOOS == "darwin":
+		return []string{"chmod", "ls"}
+	case backend == StrategyBindRO:
+		return []string{"mount", "umount"}
+	}
+	return nil
+}
+
+// Probe checks that the backend chosen for path works by locking, verifying
+// and unlocking a scratch file on the same filesystem
+func Probe(path string) (ProbeResult, error) {
+	fsType, _ := DetectStrategy(path)
+	result := ProbeResult{FSType: fsType, Backend: StrategyFor(path)}
+	result.Effective = result.Backend
+
+	for _, tool := range backendTools(result.Backend) {
+		if _, err := toolPath(tool); err != nil {
+			return result, fmt.Errorf("%s backend needs %s: %w", result.Backend, tool, err)
+		}
+	}
+
+	backend, ok := getBackend(result.Backend)
+	if !ok {
+		return result, fmt.Errorf("lock backend %q is not available", result.Backend)
+	}
+	// Mounting over a scratch file needs root and proves little
+	if coversTree(backend) {
+		return result, nil
+	}
+
+	scratch, err := createScratch(path)
+	if err != nil {
+		return result, fmt.Errorf("failed to create scratch file: %w", err)
+	}
+	defer os.Remove(scratch)
+
+	if err := backend.Lock(scratch); err != nil {
+		return result, fmt.Errorf("lock failed: %w", err)
+	}
+	defer backend.Unlock(scratch)
+
+	locked, err := backend.IsLocked(scratch)
+	if err != nil {
+		return result, fmt.Errorf("failed to verify lock: %w", err)
+	}
+	if !locked {
+		// Immutable flags fall back to chmod when the flag can't be set
+		if readOnly, _ := isReadOnly(scratch); readOnly {
+			result.Effective = StrategyChmod
+			return result, nil
+		}
+		return result, fmt.Errorf("lock did not take effect")
+	}
+	return result, nil
+}
+
+// createScratch creates an empty file next to path, moving up the directory
+// tree past directories that are locked themselves
+func createScratch(path string) (string, error) {
+	dir := path
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+
+	var lastErr error
+	for {
+		file, err := os.CreateTemp(dir, ".configlock-probe-*")
+		if err == nil {
+			file.Close()
+			return file.Name(), nil
+		}
+		lastErr = err
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", lastErr
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,67 @@
+package locker
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// SudoersRules returns a sudoers snippet that lets user run, without a
+// password, exactly the lock tool invocations elevation needs: setting and
+// clearing the immutable flag of each of files (schg on macOS) and, on Linux,
+// the append-only flag of each of appendOnly. Paths containing whitespace
+// can't be matched by a sudoers rule and are returned as skipped.
+func SudoersRules(user string, files, appendOnly []string) (rules string, skipped []string, err error) {
+	var tool string
+	var flags [2]string
+	switch runtime.GOOS {
+	case "linux":
+		tool, flags = "chattr", [2]string{"+i", "-i"}
+	case "darwin":
+		// uchg needs no root, only schg does
+		tool, flags = "chflags", [2]string{"schg", "noschg"}
+		appendOnly = nil
+	default:
+		return "", nil, fmt.Errorf("sudoers rules: %w: %s", ErrUnsupportedOS, runtime.GOOS)
+	}
+	// The rules must name the tool as privilegedCommand runs it
+	path, err := toolPath(tool)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var commands []string
+	add := func(file string, flags [2]string) {
+		if strings.ContainsAny(file, " \t\n") {
+			skipped = append(skipped, file)
+			return
+		}
+		for _, flag := range flags {
+			commands = append(commands, path+" "+flag+" "+sudoersEscape(file))
+		}
+	}
+	for _, file := range files {
+		add(file, flags)
+	}
+	for _, file := range appendOnly {
+		add(file, [2]string{"+a", "-a"})
+	}
+	if len(commands) == 0 {
+		return "", skipped, fmt.Errorf("no paths to allow")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Lock tool invocations for configlock, generated by 'configlock sudoers generate'.\n")
+	fmt.Fprintf(&b, "# Files added to locked paths later need the rules regenerated.\n")
+	fmt.Fprintf(&b, "Cmnd_Alias CONFIGLOCK = %s\n", strings.Join(commands, ", \\\n    "))
+	fmt.Fprintf(&b, "%s ALL=(root) NOPASSWD: CONFIGLOCK\n", sudoersEscape(user))
+	return b.String(), skipped, nil
+}
+
+// sudoersEscape escapes the characters sudoers treats specially in command
+// arguments and user names, including the wildcards arguments are matched
+// with, so a rule for a file named "*" allows only that file
+func sudoersEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `,`, `\,`, `:`, `\:`, `=`, `\=`, `#`, `\#`,
+		`*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`).Replace(s)
+}
@@ -0,0 +1,113 @@
+package locker
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// toolDirs are the only places lock tools are run from. PATH is not
+// consulted: a shim in a user-writable PATH entry (~/bin, a Homebrew prefix)
+// could otherwise report success without touching any flags.
+var toolDirs = []string{
+	"/usr/bin", "/bin", "/usr/sbin", "/sbin",
+	"/run/current-system/sw/bin", // NixOS
+	"/usr/local/bin", "/usr/local/sbin",
+}
+
+var (
+	toolsMu   sync.Mutex
+	toolPaths = make(map[string]string) // tool name -> trusted absolute path
+)
+
+// ToolPath is toolPath for tools other packages run
+func ToolPath(name string) (string, error) {
+	return toolPath(name)
+}
+
+// toolPath returns the absolute path of a system tool, refusing copies that
+// the user could have replaced
+func toolPath(name string) (string, error) {
+	toolsMu.Lock()
+	defer toolsMu.Unlock()
+	if path, ok := toolPaths[name]; ok {
+		return path, nil
+	}
+
+	var untrusted []string
+	for _, dir := range toolDirs {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if err := checkTrusted(path); err != nil {
+			untrusted = append(untrusted, err.Error())
+			continue
+		}
+		toolPaths[name] = path
+		return path, nil
+	}
+	if len(untrusted) > 0 {
+		return "", fmt.Errorf("refusing to run %s: %s", name, strings.Join(untrusted, "; "))
+	}
+	return "", fmt.Errorf("%s not found in %s", name, strings.Join(toolDirs, ", "))
+}
+
+// checkTrusted verifies that a tool and the directory holding it can only be
+// changed by root
+func checkTrusted(path string) error {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	for _, p := range []string{realPath, filepath.Dir(realPath)} {
+		info, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+		if !ownedByRoot(info) {
+			return fmt.Errorf("%s is not owned by root", p)
+		}
+		if info.Mode().Perm()&0o022 != 0 {
+			return fmt.Errorf("%s is writable by other users", p)
+		}
+	}
+	return nil
+}
+
+// command returns a command running a trusted system tool. If the tool can't
+// be resolved, running the command fails with the reason.
+func command(name string, args ...string) *exec.Cmd {
+	path, err := toolPath(name)
+	if err != nil {
+		cmd := exec.Command(name, args...)
+		cmd.Err = err
+		return cmd
+	}
+	return exec.Command(path, args...)
+}
+
+// ResolveTools resolves the tools the default lock backend and elevation
+// need up front, so problems show up when the daemon starts rather than at
+// the first lock. Returns one error per tool that can't be used.
+func ResolveTools() []error {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		return nil
+	}
+	tools := backendTools(StrategyImmutable)
+	if getOptions().Elevation == ElevationSudo {
+		tools = append(tools, "sudo")
+	}
+
+	var errs []error
+	for _, tool := range tools {
+		if _, err := toolPath(tool); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package locker
+
+import (
+	"os"
+	"syscall"
+)
+
+// ownedByRoot reports whether a file belongs to root
+func ownedByRoot(info os.FileInfo) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && st.Uid == 0
+}
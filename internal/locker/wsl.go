@@ -0,0 +1,75 @@
+package locker
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+var (
+	wslOnce sync.Once
+	wsl     bool
+)
+
+// IsWSL reports whether configlock runs inside Windows Subsystem for Linux
+func IsWSL() bool {
+	wslOnce.Do(func() {
+		if runtime.GOOS != "linux" {
+			return
+		}
+		if os.Getenv("WSL_DISTRO_NAME") != "" || os.Getenv("WSL_INTEROP") != "" {
+			wsl = true
+			return
+		}
+		release, err := os.ReadFile("/proc/sys/kernel/osrelease")
+		wsl = err == nil && strings.Contains(strings.ToLower(string(release)), "microsoft")
+	})
+	return wsl
+}
+
+// IsWindowsDrive reports whether path is on a Windows drive mounted into
+// WSL (such as /mnt/c), where Linux immutable flags cannot be set and locks
+// are read-only permissions that Windows programs ignore
+func IsWindowsDrive(path string) bool {
+	if !IsWSL() {
+		return false
+	}
+	fsType, _ := DetectStrategy(path)
+	return fsType == "drvfs" || fsType == "9p"
+}
+
+// mountFSType returns the filesystem type of the mount containing path, as
+// listed in /proc/self/mountinfo
+func mountFSType(path string) string {
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	// mountinfo fields: id parent major:minor root mountpoint options
+	// [optional...] - fstype source superoptions
+	best, fsType := "", ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		sep := strings.Index(line, " - ")
+		if len(fields) < 6 || sep < 0 {
+			continue
+		}
+		mountPoint := unescapeMountPath(fields[4])
+		if path != mountPoint && mountPoint != "/" && !strings.HasPrefix(path, mountPoint+"/") {
+			continue
+		}
+		if len(mountPoint) >= len(best) {
+			best = mountPoint
+			if after := strings.Fields(line[sep+3:]); len(after) > 0 {
+				fsType = after[0]
+			}
+		}
+	}
+	return fsType
+}
@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level is the severity of a log entry. The zero value is LevelInfo, the
+// default threshold.
+type Level int
+
+const (
+	LevelDebug Level = iota - 1
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// levelNames are the level names as written in the log, from LevelDebug up
+var levelNames = []string{"DEBUG", "INFO", "WARN", "ERROR"}
+
+// String returns the name of the level as written in the log
+func (l Level) String() string {
+	if i := int(l - LevelDebug); i >= 0 && i < len(levelNames) {
+		return levelNames[i]
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(l))
+}
+
+// ParseLevel parses a level name such as "warn", ignoring case. An empty name
+// is LevelInfo.
+func ParseLevel(name string) (Level, error) {
+	if name == "" {
+		return LevelInfo, nil
+	}
+	for i, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return LevelDebug + Level(i), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q (must be one of debug, info, warn, error)", name)
+}
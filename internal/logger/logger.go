@@ -7,32 +7,75 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sync"
 	"time"
 )
 
 const maxLogSize = 10 * 1024 * 1024 // 10MB
 
+// rotateRetry is how long to keep appending to an oversized log after a
+// failed rotation before trying again
+const rotateRetry = time.Hour
+
+// RotateGuard lifts whatever keeps the log file and its backup from being
+// renamed or removed, such as an append-only flag or the lock of a locked
+// path, and returns a function that puts it back after rotation
+type RotateGuard func(logPath, backupPath string) (restore func(), err error)
+
 type Logger struct {
-	mu       sync.Mutex
-	file     *os.File
-	logger   *log.Logger
-	logPath  string
-	disabled bool
+	mu      sync.Mutex
+	file    *os.File
+	logger  *log.Logger
+	logPath string
+
+	// Set when the log file can't be used: why, and the system log that
+	// entries also go to besides stderr (nil where there is none)
+	fallback string
+	syslog   sysLogger
+
+	guard         RotateGuard
+	rotating      bool      // a rotation is in progress, possibly waiting on guard
+	rotateFailure time.Time // when rotation last failed
+	writeErr      string    // why the last entry couldn't be appended to the file
+
+	level Level        // entries below this level are dropped
+	subs  []chan Entry // frontends receiving entries, see Subscribe
+}
+
+// Entry is a log entry as delivered to subscribers
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Message string
 }
 
+// subscriberBuffer is how many entries a subscriber may fall behind before
+// further entries are dropped for it
+const subscriberBuffer = 64
+
 var (
 	defaultLogger *Logger
 	once          sync.Once
 )
 
+// New returns a logger that writes entries to w rather than to the log file,
+// for tests that capture entries and frontends that show them. Its log path
+// is empty.
+func New(w io.Writer) *Logger {
+	return &Logger{logger: log.New(w, "", 0)}
+}
+
 // GetLogger returns the default logger instance
 func GetLogger() *Logger {
 	once.Do(func() {
 		defaultLogger = &Logger{}
 		if err := defaultLogger.Init(); err != nil {
-			// If we can't initialize the logger, just disable it
-			defaultLogger.disabled = true
+			// Log to stderr rather than not at all, so daemon failures
+			// still show up, e.g. in the systemd journal
+			defaultLogger.mu.Lock()
+			defaultLogger.fallBack(err)
+			defaultLogger.mu.Unlock()
 		}
 	})
 	return defaultLogger
@@ -93,69 +136,255 @@ func (l *Logger) Close() error {
 	return nil
 }
 
+// fallBack switches to stderr and the system log after the log file failed
+// with err, recording why; l.mu must be held
+func (l *Logger) fallBack(err error) {
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+	l.logger = log.New(os.Stderr, "", 0)
+	l.fallback = err.Error()
+	if l.syslog == nil {
+		l.syslog, _ = openSyslog()
+	}
+	destination := "stderr"
+	if l.syslog != nil {
+		destination = "stderr and syslog"
+	}
+	l.write(LevelWarn, fmt.Sprintf("Log file unavailable (%s), logging to %s instead", l.fallback, destination))
+}
+
+// FallbackReason returns why the log file isn't used, or "" while it is
+func (l *Logger) FallbackReason() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.fallback
+}
+
+// SetRotateGuard sets the guard called around log rotation. It is called
+// without l.mu held, so it may log itself.
+func (l *Logger) SetRotateGuard(guard RotateGuard) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.guard = guard
+}
+
+// SetLevel sets the lowest level of entries that are logged
+func (l *Logger) SetLevel(level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = level
+}
+
+// Level returns the lowest level of entries that are logged
+func (l *Logger) Level() Level {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.level
+}
+
+// Subscribe returns a channel that receives every subsequent entry that is
+// logged. Entries are dropped for a subscriber that falls behind, rather than
+// holding up logging.
+func (l *Logger) Subscribe() <-chan Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ch := make(chan Entry, subscriberBuffer)
+	l.subs = append(l.subs, ch)
+	return ch
+}
+
+// Unsubscribe stops delivering entries to ch and closes it
+func (l *Logger) Unsubscribe(ch <-chan Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, sub := range l.subs {
+		if sub == ch {
+			close(sub)
+			l.subs = slices.Delete(l.subs, i, i+1)
+			return
+		}
+	}
+}
+
 // log writes a log entry with timestamp and level
-func (l *Logger) log(level, message string) {
-	if l.disabled {
+func (l *Logger) log(level Level, message string) {
+	if level < l.Level() {
 		return
 	}
+	if l.needsRotation() {
+		l.rotateGuarded()
+	}
 
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	l.write(level, message)
+}
 
-	// Check if log rotation is needed
-	if l.file != nil {
-		info, err := l.file.Stat()
-		if err == nil && info.Size() > maxLogSize {
-			l.rotate()
+// needsRotation reports whether the log file exceeds maxLogSize and should be
+// rotated now, marking the rotation as started if so
+func (l *Logger) needsRotation() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil || l.rotating || time.Since(l.rotateFailure) < rotateRetry {
+		return false
+	}
+	info, err := l.file.Stat()
+	if err != nil || info.Size() <= maxLogSize {
+		return false
+	}
+	l.rotating = true
+	return true
+}
+
+// rotateGuarded rotates the log file with the guard's protection lifted. A
+// failure is logged and retried after rotateRetry, and entries keep going to
+// the current file meanwhile.
+func (l *Logger) rotateGuarded() {
+	l.mu.Lock()
+	guard, logPath := l.guard, l.logPath
+	l.mu.Unlock()
+
+	var restore func()
+	var guardErr error
+	if guard != nil {
+		restore, guardErr = guard(logPath, logPath+".old")
+	}
+
+	l.mu.Lock()
+	err := l.rotate()
+	l.rotating = false
+	if err != nil {
+		l.rotateFailure = time.Now()
+		if guardErr != nil {
+			err = fmt.Errorf("%w (after %v)", err, guardErr)
 		}
+		l.write(LevelWarn, fmt.Sprintf("Failed to rotate log file, retrying in %s: %v", rotateRetry, err))
 	}
+	l.mu.Unlock()
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	if restore != nil {
+		restore()
+	}
+}
+
+// write writes an entry to the log file or, after a fallback, to stderr and
+// the system log, and passes it to subscribers; l.mu must be held
+func (l *Logger) write(level Level, message string) {
+	now := time.Now()
+	for _, sub := range l.subs {
+		select {
+		case sub <- Entry{Time: now, Level: level, Message: message}:
+		default:
+		}
+	}
+
+	timestamp := now.Format("2006-01-02 15:04:05")
 	entry := fmt.Sprintf("[%s] [%s] %s\n", timestamp, level, message)
 
 	if l.logger != nil {
-		l.logger.Print(entry)
+		if err := l.logger.Output(2, entry); err != nil && l.file != nil {
+			l.writeFailed(err, level, message)
+			return
+		}
+		l.writeErr = ""
+	}
+	if l.fallback != "" {
+		l.sendSyslog(level, message)
 	}
 }
 
-// rotate rotates the log file when it exceeds maxLogSize
-func (l *Logger) rotate() {
-	if l.file == nil {
+// writeFailed writes an entry that couldn't be appended to the log file,
+// e.g. because a locked path covers it, to stderr and the system log
+// instead; l.mu must be held. Later entries are tried on the file again.
+func (l *Logger) writeFailed(err error, level Level, message string) {
+	if l.syslog == nil {
+		l.syslog, _ = openSyslog()
+	}
+	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	if l.writeErr == "" {
+		l.writeErr = err.Error()
+		warning := fmt.Sprintf("Failed to write log file (%s), logging to stderr until it can be written again", l.writeErr)
+		fmt.Fprintf(os.Stderr, "[%s] [WARN] %s\n", timestamp, warning)
+		l.sendSyslog(LevelWarn, warning)
+	}
+	fmt.Fprintf(os.Stderr, "[%s] [%s] %s\n", timestamp, level, message)
+	l.sendSyslog(level, message)
+}
+
+// sendSyslog sends an entry to the system log, if one is open; l.mu must be
+// held
+func (l *Logger) sendSyslog(level Level, message string) {
+	if l.syslog == nil {
 		return
 	}
+	switch level {
+	case LevelDebug:
+		l.syslog.Debug(message)
+	case LevelError:
+		l.syslog.Err(message)
+	case LevelWarn:
+		l.syslog.Warning(message)
+	default:
+		l.syslog.Info(message)
+	}
+}
 
-	// Close current file
-	l.file.Close()
+// rotate moves the log file to a backup, replacing the previous one, and
+// starts a new one; l.mu must be held. The current file stays in use if it
+// can't be moved.
+func (l *Logger) rotate() error {
+	if l.file == nil {
+		return nil
+	}
 
-	// Rename old log file
 	backupPath := l.logPath + ".old"
-	os.Remove(backupPath) // Remove old backup if exists
-	os.Rename(l.logPath, backupPath)
+	if err := os.Remove(backupPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", backupPath, err)
+	}
+	if err := os.Rename(l.logPath, backupPath); err != nil {
+		return fmt.Errorf("failed to rename log file: %w", err)
+	}
+	l.file.Close()
 
 	// Open new log file
 	file, err := os.OpenFile(l.logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
 	if err != nil {
-		l.disabled = true
-		return
+		l.file = nil
+		l.fallBack(fmt.Errorf("failed to reopen log file after rotation: %w", err))
+		return nil
 	}
 
 	l.file = file
 	l.logger = log.New(file, "", 0)
+	return nil
+}
+
+// Debug logs a debug message, dropped unless the level is LevelDebug
+func (l *Logger) Debug(message string) {
+	l.log(LevelDebug, message)
 }
 
 // Info logs an info message
 func (l *Logger) Info(message string) {
-	l.log("INFO", message)
+	l.log(LevelInfo, message)
 }
 
 // Warn logs a warning message
 func (l *Logger) Warn(message string) {
-	l.log("WARN", message)
+	l.log(LevelWarn, message)
 }
 
 // Error logs an error message
 func (l *Logger) Error(message string) {
-	l.log("ERROR", message)
+	l.log(LevelError, message)
+}
+
+// Debugf logs a formatted debug message
+func (l *Logger) Debugf(format string, args ...any) {
+	l.Debug(fmt.Sprintf(format, args...))
 }
 
 // Infof logs a formatted info message
@@ -178,12 +407,17 @@ func (l *Logger) SetOutput(w io.Writer) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	if l.logger != nil && l.file != nil {
-		l.logger = log.New(io.MultiWriter(l.file, w), "", 0)
+	if l.logger == nil {
+		return
+	}
+	var base io.Writer = os.Stderr
+	if l.file != nil {
+		base = l.file
 	}
+	l.logger = log.New(io.MultiWriter(base, w), "", 0)
 }
 
-// GetLogPath returns the path to the log file
+// GetLogPath returns the path to the log file, or "" for a logger from New
 func (l *Logger) GetLogPath() string {
 	l.mu.Lock()
 	defer l.mu.Unlock()
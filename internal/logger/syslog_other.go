@@ -0,0 +1,18 @@
+//go:build !unix
+
+package logger
+
+import "errors"
+
+// sysLogger is the part of syslog.Writer used for fallback logging
+type sysLogger interface {
+	Debug(message string) error
+	Info(message string) error
+	Warning(message string) error
+	Err(message string) error
+}
+
+// openSyslog fails: log/syslog is not available on this platform
+func openSyslog() (sysLogger, error) {
+	return nil, errors.New("syslog is not available")
+}
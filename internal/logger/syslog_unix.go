@@ -0,0 +1,23 @@
+//go:build unix
+
+package logger
+
+import "log/syslog"
+
+// sysLogger is the part of syslog.Writer used for fallback logging
+type sysLogger interface {
+	Debug(message string) error
+	Info(message string) error
+	Warning(message string) error
+	Err(message string) error
+}
+
+// openSyslog connects to the local syslog daemon (the journal on systemd
+// systems, the unified log on macOS)
+func openSyslog() (sysLogger, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_USER, "configlock")
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
@@ -0,0 +1,150 @@
+package mqtt
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	dialTimeout = 10 * time.Second
+	keepAlive   = 60 // seconds, sent in CONNECT
+)
+
+// MQTT 3.1.1 control packet types (upper nibble of the fixed header)
+const (
+	packetConnect    = 0x10
+	packetConnAck    = 0x20
+	packetPublish    = 0x30
+	packetDisconnect = 0xE0
+)
+
+// Publish connects to the broker at brokerURL (mqtt://, mqtts:// or tcp://,
+// with optional user:password), publishes payload to topic at QoS 0 and
+// disconnects. Events are rare, so no connection is kept open.
+func Publish(brokerURL, topic string, payload []byte, retain bool) error {
+	u, err := url.Parse(brokerURL)
+	if err != nil {
+		return fmt.Errorf("invalid MQTT URL: %w", err)
+	}
+
+	conn, err := dial(u)
+	if err != nil {
+		return fmt.Errorf("failed to connect to MQTT broker: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+
+	if err := connect(conn, u.User); err != nil {
+		return err
+	}
+
+	flags := byte(0)
+	if retain {
+		flags = 0x01
+	}
+	var body bytes.Buffer
+	writeString(&body, topic)
+	body.Write(payload)
+	if err := writePacket(conn, packetPublish|flags, body.Bytes()); err != nil {
+		return fmt.Errorf("failed to publish: %w", err)
+	}
+
+	// Errors here don't matter: the message has been sent
+	writePacket(conn, packetDisconnect, nil)
+	return nil
+}
+
+// dial opens a TCP or TLS connection to the broker
+func dial(u *url.URL) (net.Conn, error) {
+	host := u.Host
+	switch u.Scheme {
+	case "mqtt", "tcp":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "1883")
+		}
+		return net.DialTimeout("tcp", host, dialTimeout)
+	case "mqtts", "ssl", "tls":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "8883")
+		}
+		dialer := &net.Dialer{Timeout: dialTimeout}
+		return tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: u.Hostname()})
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q (use mqtt:// or mqtts://)", u.Scheme)
+	}
+}
+
+// connect sends CONNECT and waits for a successful CONNACK
+func connect(conn net.Conn, user *url.Userinfo) error {
+	var body bytes.Buffer
+	writeString(&body, "MQTT")
+	body.WriteByte(4) // protocol level 3.1.1
+
+	flags := byte(0x02) // clean session
+	if user != nil {
+		flags |= 0x80
+		if _, ok := user.Password(); ok {
+			flags |= 0x40
+		}
+	}
+	body.WriteByte(flags)
+	binary.Write(&body, binary.BigEndian, uint16(keepAlive))
+
+	writeString(&body, fmt.Sprintf("configlock-%d", os.Getpid()))
+	if user != nil {
+		writeString(&body, user.Username())
+		if password, ok := user.Password(); ok {
+			writeString(&body, password)
+		}
+	}
+
+	if err := writePacket(conn, packetConnect, body.Bytes()); err != nil {
+		return fmt.Errorf("failed to send MQTT connect: %w", err)
+	}
+
+	ack := make([]byte, 4)
+	if _, err := io.ReadFull(conn, ack); err != nil {
+		return fmt.Errorf("failed to read MQTT connack: %w", err)
+	}
+	if ack[0] != packetConnAck || ack[1] != 2 {
+		return fmt.Errorf("unexpected MQTT response from broker")
+	}
+	if ack[3] != 0 {
+		return fmt.Errorf("MQTT broker refused connection (code %d)", ack[3])
+	}
+	return nil
+}
+
+// writePacket writes a control packet with its remaining length
+func writePacket(w io.Writer, header byte, body []byte) error {
+	packet := []byte{header}
+	// Remaining length is a base-128 varint
+	length := len(body)
+	for {
+		b := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if length == 0 {
+			break
+		}
+	}
+	packet = append(packet, body...)
+	_, err := w.Write(packet)
+	return err
+}
+
+// writeString writes a length-prefixed UTF-8 string
+func writeString(buf *bytes.Buffer, s string) {
+	binary.Write(buf, binary.BigEndian, uint16(len(s)))
+	buf.WriteString(s)
+}
@@ -0,0 +1,39 @@
+package notifier
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// alertTimeout is how long an alert waits for a click before it goes away
+const alertTimeout = 60 // seconds
+
+// Ask shows an alert with buttons and returns the label of the clicked one,
+// or "" if the alert timed out. The last button is the default.
+func (n *Notifier) Ask(title, message string, buttons []string) (string, error) {
+	quoted := make([]string, len(buttons))
+	for i, button := range buttons {
+		quoted[i] = appleScriptString(button)
+	}
+	script := fmt.Sprintf("display alert %s message %s buttons {%s} giving up after %d",
+		appleScriptString(title), appleScriptString(message), strings.Join(quoted, ", "), alertTimeout)
+
+	output, err := exec.Command("osascript", "-e", script).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to show alert: %w", err)
+	}
+
+	// Output looks like "button returned:Dismiss, gave up:false"
+	result := strings.TrimSpace(string(output))
+	if strings.HasSuffix(result, "gave up:true") {
+		return "", nil
+	}
+	button, _, _ := strings.Cut(strings.TrimPrefix(result, "button returned:"), ", gave up:")
+	return button, nil
+}
+
+// appleScriptString quotes s as an AppleScript string literal
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,13 @@
+//go:build !darwin
+
+package notifier
+
+import "errors"
+
+// ErrAlertsUnsupported is returned by Ask on platforms without alerts
+var ErrAlertsUnsupported = errors.New("alerts with buttons are only supported on macOS")
+
+// Ask is only supported on macOS
+func (n *Notifier) Ask(title, message string, buttons []string) (string, error) {
+	return "", ErrAlertsUnsupported
+}
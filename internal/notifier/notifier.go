@@ -15,6 +15,10 @@ func New(appName string) *Notifier {
 
 // Notify sends a system notification using the beeep library
 // which provides cross-platform support for macOS, Linux, Windows, and FreeBSD
+// On Linux beeep falls back to notify-send and kdialog from PATH when D-Bus
+// is unavailable. Unlike the lock tools, these are not resolved from trusted
+// directories: a replaced one can only hide notifications, which the
+// notifications setting turns off anyway, and never affects a lock.
 func (n *Notifier) Notify(title, message string) error {
 	// beeep.Notify sends a system notification with title, message, and optional icon
 	// The empty string means no custom icon will be used
@@ -0,0 +1,29 @@
+package notifier
+
+import (
+	"sync"
+	"time"
+)
+
+// Throttle limits how often notifications with the same key are sent
+type Throttle struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// NewThrottle creates an empty throttle
+func NewThrottle() *Throttle {
+	return &Throttle{last: make(map[string]time.Time)}
+}
+
+// Allow reports whether a notification for key may be sent at now, given
+// that at most one is sent per interval, and records it if so
+func (t *Throttle) Allow(key string, now time.Time, interval time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if last, ok := t.last[key]; ok && now.Sub(last) < interval {
+		return false
+	}
+	t.last[key] = now
+	return true
+}
@@ -0,0 +1,77 @@
+// Package pathutil normalizes locked paths and matches paths against them,
+// so a trailing slash or a ".." segment can't make the same path look like
+// a different one
+package pathutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExpandHome expands a leading ~ to the home directory
+func ExpandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, path[1:])
+	}
+	return path
+}
+
+// Abs returns path absolute and cleaned, with a leading ~ expanded.
+// Relative paths are taken relative to the working directory, as for
+// command line arguments.
+func Abs(path string) (string, error) {
+	return filepath.Abs(ExpandHome(path))
+}
+
+// Normalize returns a path from the config absolute and cleaned, with a
+// leading ~ expanded. Relative paths are taken relative to the home
+// directory, since the daemon's working directory means nothing to whoever
+// wrote the config.
+func Normalize(path string) string {
+	if path == "" {
+		return ""
+	}
+	path = ExpandHome(path)
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, path)
+}
+
+// Dedupe normalizes paths and drops repeats, keeping the first occurrence
+func Dedupe(paths []string) []string {
+	seen := make(map[string]bool, len(paths))
+	result := make([]string, 0, len(paths))
+	for _, path := range paths {
+		path = Normalize(path)
+		if path == "" || seen[path] {
+			continue
+		}
+		seen[path] = true
+		result = append(result, path)
+	}
+	return result
+}
+
+// Within reports whether path is dir or inside it. Both are cleaned first.
+func Within(path, dir string) bool {
+	path, dir = filepath.Clean(path), filepath.Clean(dir)
+	return path == dir || inside(path, dir)
+}
+
+// Inside reports whether path is strictly inside dir. Both are cleaned first.
+func Inside(path, dir string) bool {
+	return inside(filepath.Clean(path), filepath.Clean(dir))
+}
+
+// inside reports whether the cleaned path is strictly inside the cleaned dir
+func inside(path, dir string) bool {
+	// The root directory already ends in a separator
+	if strings.HasSuffix(dir, string(filepath.Separator)) {
+		return path != dir && strings.HasPrefix(path, dir)
+	}
+	return strings.HasPrefix(path, dir+string(filepath.Separator))
+}
@@ -0,0 +1,75 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/stats"
+)
+
+// Export formats, which list every recorded event instead of summarizing
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// ExportFormats lists the export formats
+var ExportFormats = []string{FormatJSON, FormatCSV}
+
+// IsExport reports whether format is an export format
+func IsExport(format string) bool {
+	return format == FormatJSON || format == FormatCSV
+}
+
+// csvHeader names the columns of a CSV export, one per stats.Event field
+var csvHeader = []string{"time", "event", "path", "reason", "command", "kind", "outcome", "retries", "seconds"}
+
+// Export formats events, oldest first, as a JSON array or CSV with a header
+// row, for analysis in other tools
+func Export(events []stats.Event, format string) (string, error) {
+	switch format {
+	case FormatJSON:
+		if events == nil {
+			events = []stats.Event{}
+		}
+		data, err := json.MarshalIndent(events, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal events: %w", err)
+		}
+		return string(data) + "\n", nil
+	case FormatCSV:
+		var b bytes.Buffer
+		w := csv.NewWriter(&b)
+		w.Write(csvHeader)
+		for _, e := range events {
+			// Retries and seconds are only recorded for challenges
+			retries, seconds := "", ""
+			if e.Name == stats.EventChallenge {
+				retries, seconds = strconv.Itoa(e.Retries), strconv.Itoa(e.Seconds)
+			}
+			w.Write([]string{
+				e.Time.Local().Format(time.RFC3339),
+				e.Name,
+				e.Path,
+				e.Reason,
+				e.Command,
+				e.Kind,
+				e.Outcome,
+				retries,
+				seconds,
+			})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return "", fmt.Errorf("failed to write CSV: %w", err)
+		}
+		return b.String(), nil
+	default:
+		return "", fmt.Errorf("unknown export format %q (must be one of %s)", format, strings.Join(ExportFormats, ", "))
+	}
+}
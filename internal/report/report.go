@@ -0,0 +1,341 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/challenge"
+	"github.com/baggiiiie/configlock/internal/hooks"
+	"github.com/baggiiiie/configlock/internal/stats"
+)
+
+// Output formats
+const (
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// Formats lists the supported output formats
+var Formats = []string{FormatText, FormatMarkdown, FormatHTML}
+
+// PathCount counts the events for one path
+type PathCount struct {
+	Path        string
+	Violations  int
+	TempUnlocks int
+}
+
+// DayCount counts the events on one day
+type DayCount struct {
+	Day         time.Time // local midnight
+	Violations  int
+	TempUnlocks int
+}
+
+// Reason is the reason given for one temp unlock, or the old and new
+// settings of one lock hours change (without Path)
+type Reason struct {
+	Time   time.Time
+	Path   string
+	Reason string
+}
+
+// Report summarizes the recorded events of a period
+type Report struct {
+	From, To    time.Time
+	LockPeriods int
+	LockedTime  time.Duration
+	Violations  int
+	TempUnlocks int
+	Paths       []PathCount // most events first
+	Days        []DayCount  // one per day of the period
+	Reasons     []Reason    // given for temp unlocks, oldest first
+
+	LockHoursChanges []Reason // made with 'configlock edit time', oldest first
+
+	// Typing challenges and passphrase checks, by outcome
+	ChallengesPassed    int
+	ChallengesAbandoned int // failed or aborted: the times unlocking almost happened
+	ChallengeCommands   map[string]int
+}
+
+// Build summarizes events in [from, to); now bounds a lock period that is
+// still running
+func Build(events []stats.Event, from, to, now time.Time) *Report {
+	r := &Report{From: from, To: to, ChallengeCommands: map[string]int{}}
+	for day := startOfDay(from); day.Before(to); day = day.AddDate(0, 0, 1) {
+		r.Days = append(r.Days, DayCount{Day: day})
+	}
+
+	paths := map[string]*PathCount{}
+	countPath := func(path string) *PathCount {
+		if paths[path] == nil {
+			paths[path] = &PathCount{Path: path}
+		}
+		return paths[path]
+	}
+	countDay := func(t time.Time) *DayCount {
+		for i := range r.Days {
+			if !t.Before(r.Days[i].Day) && t.Before(r.Days[i].Day.AddDate(0, 0, 1)) {
+				return &r.Days[i]
+			}
+		}
+		return &DayCount{}
+	}
+
+	// A deactivate without a preceding activate closes a period that began
+	// before the report
+	var lockedSince time.Time
+	for _, e := range events {
+		switch e.Name {
+		case hooks.EventActivate:
+			if lockedSince.IsZero() {
+				lockedSince = e.Time
+				r.LockPeriods++
+			}
+		case hooks.EventDeactivate:
+			if lockedSince.IsZero() {
+				lockedSince = from
+			}
+			r.LockedTime += e.Time.Sub(lockedSince)
+			lockedSince = time.Time{}
+		case hooks.EventViolation:
+			r.Violations++
+			countPath(e.Path).Violations++
+			countDay(e.Time).Violations++
+		case hooks.EventTempUnlock:
+			r.TempUnlocks++
+			countPath(e.Path).TempUnlocks++
+			countDay(e.Time).TempUnlocks++
+			if e.Reason != "" {
+				r.Reasons = append(r.Reasons, Reason{Time: e.Time, Path: e.Path, Reason: e.Reason})
+			}
+		case stats.EventChallenge:
+			if e.Outcome == challenge.OutcomePassed {
+				r.ChallengesPassed++
+			} else {
+				r.ChallengesAbandoned++
+			}
+			r.ChallengeCommands[e.Command]++
+		case stats.EventLockHoursChange:
+			r.LockHoursChanges = append(r.LockHoursChanges, Reason{Time: e.Time, Reason: e.Reason})
+		}
+	}
+	if !lockedSince.IsZero() {
+		end := to
+		if now.Before(end) {
+			end = now
+		}
+		r.LockedTime += end.Sub(lockedSince)
+	}
+
+	for _, count := range paths {
+		r.Paths = append(r.Paths, *count)
+	}
+	slices.SortFunc(r.Paths, func(a, b PathCount) int {
+		if n := (b.Violations + b.TempUnlocks) - (a.Violations + a.TempUnlocks); n != 0 {
+			return n
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+	return r
+}
+
+// commands returns the commands that ran challenges, most frequent first
+func (r *Report) commands() []string {
+	commands := slices.Collect(maps.Keys(r.ChallengeCommands))
+	slices.SortFunc(commands, func(a, b string) int {
+		if n := r.ChallengeCommands[b] - r.ChallengeCommands[a]; n != 0 {
+			return n
+		}
+		return strings.Compare(a, b)
+	})
+	return commands
+}
+
+// startOfDay returns local midnight of t's day
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// Title returns the report heading
+func (r *Report) Title() string {
+	last := r.To.Add(-time.Nanosecond)
+	kind := "weekly report"
+	if len(r.Days) != 7 {
+		kind = "report"
+	}
+	return fmt.Sprintf("ConfigLock %s: %s - %s", kind, r.From.Format("Mon Jan 2"), last.Format("Mon Jan 2"))
+}
+
+// Render formats the report as text, markdown or html
+func (r *Report) Render(format string) (string, error) {
+	switch format {
+	case FormatText, "":
+		return r.text(), nil
+	case FormatMarkdown:
+		return r.markdown(), nil
+	case FormatHTML:
+		return r.html(), nil
+	default:
+		return "", fmt.Errorf("unknown report format %q (must be one of %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// summary returns the headline figures as label/value pairs
+func (r *Report) summary() [][2]string {
+	return [][2]string{
+		{"Lock periods", fmt.Sprintf("%d", r.LockPeriods)},
+		{"Time locked", formatDuration(r.LockedTime)},
+		{"Changes blocked", fmt.Sprintf("%d", r.Violations)},
+		{"Temp unlocks", fmt.Sprintf("%d", r.TempUnlocks)},
+		{"Challenges", fmt.Sprintf("%d passed, %d failed or abandoned", r.ChallengesPassed, r.ChallengesAbandoned)},
+	}
+}
+
+func (r *Report) text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", r.Title())
+	for _, line := range r.summary() {
+		fmt.Fprintf(&b, "%-16s %s\n", line[0]+":", line[1])
+	}
+
+	b.WriteString("\nBy day:\n")
+	for _, day := range r.Days {
+		fmt.Fprintf(&b, "  %s  %3d blocked  %3d temp unlocks\n", day.Day.Format("Mon Jan 02"), day.Violations, day.TempUnlocks)
+	}
+
+	if len(r.Paths) > 0 {
+		b.WriteString("\nMost touched paths:\n")
+		for _, path := range r.Paths {
+			fmt.Fprintf(&b, "  %3d blocked  %3d temp unlocks  %s\n", path.Violations, path.TempUnlocks, path.Path)
+		}
+	}
+
+	if len(r.Reasons) > 0 {
+		b.WriteString("\nTemp unlock reasons:\n")
+		for _, reason := range r.Reasons {
+			fmt.Fprintf(&b, "  %s  %s: %s\n", reason.Time.Local().Format("Mon 15:04"), reason.Path, reason.Reason)
+		}
+	}
+
+	if len(r.LockHoursChanges) > 0 {
+		b.WriteString("\nLock hours changes:\n")
+		for _, change := range r.LockHoursChanges {
+			fmt.Fprintf(&b, "  %s  %s\n", change.Time.Local().Format("Mon 15:04"), change.Reason)
+		}
+	}
+
+	if len(r.ChallengeCommands) > 0 {
+		b.WriteString("\nChallenges by command:\n")
+		for _, command := range r.commands() {
+			fmt.Fprintf(&b, "  %3d  %s\n", r.ChallengeCommands[command], command)
+		}
+	}
+	return b.String()
+}
+
+func (r *Report) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", r.Title())
+	for _, line := range r.summary() {
+		fmt.Fprintf(&b, "- **%s:** %s\n", line[0], line[1])
+	}
+
+	b.WriteString("\n## By day\n\n| Day | Blocked | Temp unlocks |\n| --- | ---: | ---: |\n")
+	for _, day := range r.Days {
+		fmt.Fprintf(&b, "| %s | %d | %d |\n", day.Day.Format("Mon Jan 2"), day.Violations, day.TempUnlocks)
+	}
+
+	if len(r.Paths) > 0 {
+		b.WriteString("\n## Most touched paths\n\n| Path | Blocked | Temp unlocks |\n| --- | ---: | ---: |\n")
+		for _, path := range r.Paths {
+			fmt.Fprintf(&b, "| `%s` | %d | %d |\n", path.Path, path.Violations, path.TempUnlocks)
+		}
+	}
+
+	if len(r.Reasons) > 0 {
+		b.WriteString("\n## Temp unlock reasons\n\n")
+		for _, reason := range r.Reasons {
+			fmt.Fprintf(&b, "- %s `%s`: %s\n", reason.Time.Local().Format("Mon 15:04"), reason.Path, reason.Reason)
+		}
+	}
+
+	if len(r.LockHoursChanges) > 0 {
+		b.WriteString("\n## Lock hours changes\n\n")
+		for _, change := range r.LockHoursChanges {
+			fmt.Fprintf(&b, "- %s: %s\n", change.Time.Local().Format("Mon 15:04"), change.Reason)
+		}
+	}
+
+	if len(r.ChallengeCommands) > 0 {
+		b.WriteString("\n## Challenges by command\n\n| Command | Challenges |\n| --- | ---: |\n")
+		for _, command := range r.commands() {
+			fmt.Fprintf(&b, "| `%s` | %d |\n", command, r.ChallengeCommands[command])
+		}
+	}
+	return b.String()
+}
+
+func (r *Report) html() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>%s</title></head>\n<body>\n", html.EscapeString(r.Title()))
+	fmt.Fprintf(&b, "<h1>%s</h1>\n<ul>\n", html.EscapeString(r.Title()))
+	for _, line := range r.summary() {
+		fmt.Fprintf(&b, "<li><strong>%s:</strong> %s</li>\n", line[0], html.EscapeString(line[1]))
+	}
+	b.WriteString("</ul>\n")
+
+	b.WriteString("<h2>By day</h2>\n<table>\n<tr><th>Day</th><th>Blocked</th><th>Temp unlocks</th></tr>\n")
+	for _, day := range r.Days {
+		fmt.Fprintf(&b, "<tr><td>%s</td><td>%d</td><td>%d</td></tr>\n", day.Day.Format("Mon Jan 2"), day.Violations, day.TempUnlocks)
+	}
+	b.WriteString("</table>\n")
+
+	if len(r.Paths) > 0 {
+		b.WriteString("<h2>Most touched paths</h2>\n<table>\n<tr><th>Path</th><th>Blocked</th><th>Temp unlocks</th></tr>\n")
+		for _, path := range r.Paths {
+			fmt.Fprintf(&b, "<tr><td><code>%s</code></td><td>%d</td><td>%d</td></tr>\n", html.EscapeString(path.Path), path.Violations, path.TempUnlocks)
+		}
+		b.WriteString("</table>\n")
+	}
+
+	if len(r.Reasons) > 0 {
+		b.WriteString("<h2>Temp unlock reasons</h2>\n<ul>\n")
+		for _, reason := range r.Reasons {
+			fmt.Fprintf(&b, "<li>%s <code>%s</code>: %s</li>\n", reason.Time.Local().Format("Mon 15:04"),
+				html.EscapeString(reason.Path), html.EscapeString(reason.Reason))
+		}
+		b.WriteString("</ul>\n")
+	}
+
+	if len(r.LockHoursChanges) > 0 {
+		b.WriteString("<h2>Lock hours changes</h2>\n<ul>\n")
+		for _, change := range r.LockHoursChanges {
+			fmt.Fprintf(&b, "<li>%s: %s</li>\n", change.Time.Local().Format("Mon 15:04"), html.EscapeString(change.Reason))
+		}
+		b.WriteString("</ul>\n")
+	}
+
+	if len(r.ChallengeCommands) > 0 {
+		b.WriteString("<h2>Challenges by command</h2>\n<table>\n<tr><th>Command</th><th>Challenges</th></tr>\n")
+		for _, command := range r.commands() {
+			fmt.Fprintf(&b, "<tr><td><code>%s</code></td><td>%d</td></tr>\n", html.EscapeString(command), r.ChallengeCommands[command])
+		}
+		b.WriteString("</table>\n")
+	}
+	b.WriteString("</body>\n</html>\n")
+	return b.String()
+}
+
+// formatDuration formats d as hours and minutes
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%dh %02dm", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -0,0 +1,76 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Block is a one-off lock period, such as a calendar focus event
+type Block struct {
+	Title string    `json:"title"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Blocks is a set of one-off lock periods
+type Blocks []Block
+
+// Active reports whether t falls inside any block (start inclusive, end exclusive)
+func (b Blocks) Active(t time.Time) bool {
+	_, ok := b.At(t)
+	return ok
+}
+
+// At returns the block t falls inside, if any
+func (b Blocks) At(t time.Time) (Block, bool) {
+	for _, block := range b {
+		if !t.Before(block.Start) && t.Before(block.End) {
+			return block, true
+		}
+	}
+	return Block{}, false
+}
+
+// NextStart returns the first block start strictly after t
+func (b Blocks) NextStart(t time.Time) (time.Time, bool) {
+	var next time.Time
+	for _, block := range b {
+		if block.Start.After(t) && (next.IsZero() || block.Start.Before(next)) {
+			next = block.Start
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// LoadBlocks reads blocks saved by SaveBlocks. A missing file means no blocks.
+func LoadBlocks(path string) (Blocks, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read blocks: %w", err)
+	}
+
+	var blocks Blocks
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return nil, fmt.Errorf("failed to parse blocks: %w", err)
+	}
+	return blocks, nil
+}
+
+// SaveBlocks writes blocks to path, creating its directory if needed
+func SaveBlocks(path string, blocks Blocks) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(blocks, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal blocks: %w", err)
+	}
+	return os.WriteFile(path, data, 0o600)
+}
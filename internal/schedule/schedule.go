@@ -0,0 +1,188 @@
+package schedule
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// dayNames maps day names and their common abbreviations to ISO weekday
+// numbers (Monday = 1)
+var dayNames = map[string]int{
+	"MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6, "SUN": 7,
+	"MONDAY": 1, "TUESDAY": 2, "WEDNESDAY": 3, "THURSDAY": 4, "FRIDAY": 5, "SATURDAY": 6, "SUNDAY": 7,
+	"MO": 1, "TU": 2, "WE": 3, "TH": 4, "FR": 5, "SA": 6, "SU": 7,
+	"TUES": 2, "WEDS": 3, "THUR": 4, "THURS": 4,
+}
+
+// DayNumber returns the ISO weekday number (Monday = 1) of a day name such
+// as "mon", "Tues" or "Thursday", ignoring case
+func DayNumber(name string) (int, bool) {
+	day, ok := dayNames[strings.ToUpper(strings.TrimSpace(name))]
+	return day, ok
+}
+
+// Window is a recurring lock window, e.g. "08:00-17:30 MON-FRI". A window
+// whose end is before its start runs past midnight into the next day.
+type Window struct {
+	Start int   // minutes after midnight
+	End   int   // minutes after midnight
+	Days  []int // days the window starts on, 1 (Monday) to 7 (Sunday)
+}
+
+// Schedule is a set of windows; it is active while any window is
+type Schedule []Window
+
+// Parse parses a window entry of the form "HH:MM-HH:MM [DAYS]", where DAYS
+// is a comma-separated list of days or day ranges (e.g. "MON-FRI", "SAT,SUN").
+// Without DAYS the window applies every day.
+func Parse(entry string) (Window, error) {
+	fields := strings.Fields(entry)
+	if len(fields) == 0 || len(fields) > 2 {
+		return Window{}, fmt.Errorf("invalid window %q: expected \"HH:MM-HH:MM [DAYS]\"", entry)
+	}
+
+	startStr, endStr, ok := strings.Cut(fields[0], "-")
+	if !ok {
+		return Window{}, fmt.Errorf("invalid window %q: expected a time range like 08:00-17:30", entry)
+	}
+	start, err := parseClock(startStr)
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid window %q: %w", entry, err)
+	}
+	end, err := parseClock(endStr)
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid window %q: %w", entry, err)
+	}
+	if start == end {
+		return Window{}, fmt.Errorf("invalid window %q: start and end are the same", entry)
+	}
+
+	days := []int{1, 2, 3, 4, 5, 6, 7}
+	if len(fields) == 2 {
+		if days, err = parseDays(fields[1]); err != nil {
+			return Window{}, fmt.Errorf("invalid window %q: %w", entry, err)
+		}
+	}
+
+	return Window{Start: start, End: end, Days: days}, nil
+}
+
+// ParseAll parses every entry into a Schedule
+func ParseAll(entries []string) (Schedule, error) {
+	s := make(Schedule, 0, len(entries))
+	for _, entry := range entries {
+		w, err := Parse(entry)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, w)
+	}
+	return s, nil
+}
+
+// parseClock parses "HH:MM" into minutes after midnight; "24:00" is accepted
+// as the end of the day
+func parseClock(s string) (int, error) {
+	if s == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (use HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseDays parses day lists such as "MON-FRI" or "MON,WED,SAT-SUN"
+func parseDays(s string) ([]int, error) {
+	var days []int
+	for part := range strings.SplitSeq(strings.ToUpper(s), ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, ok := dayNames[from]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = dayNames[to]; !ok {
+				return nil, fmt.Errorf("invalid day %q", to)
+			}
+		}
+		// Ranges may wrap around the week, e.g. FRI-MON
+		for d := first; ; d = d%7 + 1 {
+			if !slices.Contains(days, d) {
+				days = append(days, d)
+			}
+			if d == last {
+				break
+			}
+		}
+	}
+	slices.Sort(days)
+	return days, nil
+}
+
+// isoWeekday returns the weekday of t with Monday = 1 and Sunday = 7
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
+
+// occurrence returns the start and end of the window's occurrence that
+// starts on the day of t, if the window runs that day
+func (w Window) occurrence(t time.Time) (time.Time, time.Time, bool) {
+	if !slices.Contains(w.Days, isoWeekday(t)) {
+		return time.Time{}, time.Time{}, false
+	}
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	start := midnight.Add(time.Duration(w.Start) * time.Minute)
+	end := midnight.Add(time.Duration(w.End) * time.Minute)
+	if w.End < w.Start {
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end, true
+}
+
+// Active reports whether t falls inside the window (start inclusive, end exclusive)
+func (w Window) Active(t time.Time) bool {
+	// An overnight window that started yesterday may still be running
+	for _, day := range []time.Time{t, t.AddDate(0, 0, -1)} {
+		if start, end, ok := w.occurrence(day); ok && !t.Before(start) && t.Before(end) {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether t falls inside any window
+func (s Schedule) Active(t time.Time) bool {
+	for _, w := range s {
+		if w.Active(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextStart returns the first window start strictly after t, looking up to
+// a week ahead
+func (s Schedule) NextStart(t time.Time) (time.Time, bool) {
+	var next time.Time
+	for _, w := range s {
+		for i := range 8 {
+			start, _, ok := w.occurrence(t.AddDate(0, 0, i))
+			if !ok || !start.After(t) {
+				continue
+			}
+			if next.IsZero() || start.Before(next) {
+				next = start
+			}
+			break
+		}
+	}
+	return next, !next.IsZero()
+}
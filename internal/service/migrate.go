@@ -0,0 +1,222 @@
+package service
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/baggiiiie/configlock/internal/config"
+)
+
+// Installation is a service definition that runs the configlock daemon but
+// isn't the one this binary manages: written by hand, left by an older
+// version, or installed under another name. Both would enforce the same
+// config, fighting over the locked paths.
+type Installation struct {
+	Name   string   // systemd unit name or launchd label
+	Path   string   // unit file or plist
+	Exec   []string // command line it runs
+	Reason string   // why it needs migrating
+	System bool     // in a system-wide location, which needs root to change
+}
+
+// definitionDirs returns the directories searched for service definitions,
+// and whether each is system-wide
+func definitionDirs() map[string]bool {
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "linux":
+		userDir := filepath.Join(home, ".config", "systemd", "user")
+		if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+			userDir = filepath.Join(xdg, "systemd", "user")
+		}
+		return map[string]bool{userDir: false, "/etc/systemd/user": true, "/etc/systemd/system": true}
+	case "darwin":
+		return map[string]bool{
+			filepath.Join(home, "Library", "LaunchAgents"): false,
+			"/Library/LaunchAgents":                        true,
+			"/Library/LaunchDaemons":                       true,
+		}
+	default:
+		return nil
+	}
+}
+
+// FindInstallations returns the service definitions that run the configlock
+// daemon other than the current one: definitions under another name, and the
+// current one if it runs another executable or predates the current unit.
+// Only systemd and launchd definitions are searched; on Windows, Install
+// already replaces a service with a scheduled task and vice versa.
+func (s *Service) FindInstallations() ([]Installation, error) {
+	var found []Installation
+	for dir, system := range definitionDirs() {
+		pattern := "*.service"
+		if runtime.GOOS == "darwin" {
+			pattern = "*.plist"
+		}
+		files, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				continue
+			}
+			inst, ok := parseDefinition(file, string(data))
+			if !ok {
+				continue
+			}
+			inst.System = system
+			if inst.Reason = s.migrationReason(inst, string(data)); inst.Reason != "" {
+				found = append(found, inst)
+			}
+		}
+	}
+	slices.SortFunc(found, func(a, b Installation) int { return strings.Compare(a.Path, b.Path) })
+	return found, nil
+}
+
+var (
+	plistLabel   = regexp.MustCompile(`<key>Label</key>\s*<string>([^<]*)</string>`)
+	plistProgram = regexp.MustCompile(`(?s)<key>ProgramArguments</key>\s*<array>(.*?)</array>`)
+	plistString  = regexp.MustCompile(`<string>([^<]*)</string>`)
+)
+
+// parseDefinition reads the name and command line of a unit file or plist,
+// reporting whether it runs the configlock daemon
+func parseDefinition(file, data string) (Installation, bool) {
+	inst := Installation{Path: file}
+	if strings.HasSuffix(file, ".plist") {
+		inst.Name = strings.TrimSuffix(filepath.Base(file), ".plist")
+		if m := plistLabel.FindStringSubmatch(data); m != nil {
+			inst.Name = html.UnescapeString(m[1])
+		}
+		if m := plistProgram.FindStringSubmatch(data); m != nil {
+			for _, arg := range plistString.FindAllStringSubmatch(m[1], -1) {
+				inst.Exec = append(inst.Exec, html.UnescapeString(arg[1]))
+			}
+		}
+	} else {
+		inst.Name = strings.TrimSuffix(filepath.Base(file), ".service")
+		for _, line := range strings.Split(data, "\n") {
+			value, ok := strings.CutPrefix(strings.TrimSpace(line), "ExecStart=")
+			if !ok {
+				continue
+			}
+			// Prefixes such as "-" change how systemd runs the command
+			value = strings.TrimLeft(value, "-@:+!")
+			for _, field := range strings.Fields(value) {
+				inst.Exec = append(inst.Exec, strings.Trim(field, `"'`))
+			}
+		}
+	}
+
+	if len(inst.Exec) == 0 || !strings.HasPrefix(filepath.Base(inst.Exec[0]), "configlock") {
+		return inst, false
+	}
+	return inst, slices.Contains(inst.Exec[1:], "daemon")
+}
+
+// migrationReason returns why inst should be migrated, or "" if it is the
+// current definition
+func (s *Service) migrationReason(inst Installation, data string) string {
+	if inst.System {
+		return "is installed system-wide"
+	}
+	if inst.Name != s.name {
+		return fmt.Sprintf("runs the daemon under the name %s", inst.Name)
+	}
+	if !sameFile(inst.Exec[0], s.execPath) {
+		return fmt.Sprintf("runs %s instead of %s", inst.Exec[0], s.execPath)
+	}
+	// Units written before the daemon reported readiness restart it after
+	// two minutes and never start it in a user session
+	if runtime.GOOS == "linux" && !strings.Contains(data, "Type=notify") {
+		return "was written by an older version of configlock"
+	}
+	return ""
+}
+
+// sameFile reports whether a and b are the same file, possibly through
+// symlinks
+func sameFile(a, b string) bool {
+	if a == b {
+		return true
+	}
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
+
+// migratedDir is where replaced definitions are kept, in case they had
+// settings worth carrying over by hand
+func migratedDir() string {
+	return filepath.Join(config.GetConfigDir(), "migrated")
+}
+
+// RemoveInstallation stops inst and moves its definition out of the way, to
+// the migrated directory in the config directory, returning where it went.
+// The config is left alone. System-wide definitions are refused, since
+// removing them needs root.
+func RemoveInstallation(inst Installation) (string, error) {
+	if inst.System {
+		return "", fmt.Errorf("%s is system-wide, remove it as root", inst.Path)
+	}
+
+	switch runtime.GOOS {
+	case "linux":
+		// Fails if the unit isn't loaded, which is fine
+		exec.Command("systemctl", "--user", "disable", "--now", inst.Name+".service").Run()
+	case "darwin":
+		exec.Command("launchctl", "bootout", "gui/"+strconv.Itoa(os.Getuid()), inst.Path).Run()
+	}
+
+	if err := os.MkdirAll(migratedDir(), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", migratedDir(), err)
+	}
+	saved := filepath.Join(migratedDir(), filepath.Base(inst.Path))
+	if err := os.Rename(inst.Path, saved); err != nil {
+		// Across filesystems, copy instead
+		data, readErr := os.ReadFile(inst.Path)
+		if readErr != nil {
+			return "", fmt.Errorf("failed to move %s: %w", inst.Path, err)
+		}
+		if err := os.WriteFile(saved, data, 0o644); err != nil {
+			return "", fmt.Errorf("failed to save %s: %w", inst.Path, err)
+		}
+		if err := os.Remove(inst.Path); err != nil {
+			return "", fmt.Errorf("failed to remove %s: %w", inst.Path, err)
+		}
+	}
+
+	if runtime.GOOS == "linux" {
+		exec.Command("systemctl", "--user", "daemon-reload").Run()
+	}
+	return saved, nil
+}
+
+// RemovalCommand returns the commands that remove a system-wide inst as root
+func RemovalCommand(inst Installation) string {
+	unit := inst.Name + ".service"
+	switch {
+	case strings.HasPrefix(inst.Path, "/etc/systemd/user/"):
+		// Enabled for every user, but running in each user's manager
+		return fmt.Sprintf("sudo systemctl --global disable %s && systemctl --user stop %s && sudo rm %s", unit, unit, inst.Path)
+	case runtime.GOOS == "linux":
+		return fmt.Sprintf("sudo systemctl disable --now %s && sudo rm %s", unit, inst.Path)
+	case strings.HasPrefix(inst.Path, "/Library/LaunchAgents/"):
+		return fmt.Sprintf("launchctl bootout gui/%d %s; sudo rm %s", os.Getuid(), inst.Path, inst.Path)
+	case runtime.GOOS == "darwin":
+		return fmt.Sprintf("sudo launchctl bootout system %s; sudo rm %s", inst.Path, inst.Path)
+	default:
+		return ""
+	}
+}
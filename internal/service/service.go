@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"strings"
 
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/locker"
 	"github.com/kardianos/service"
 )
 
@@ -21,9 +25,16 @@ func (p *program) Stop(s service.Service) error {
 	return nil
 }
 
+// defaultName is the service name outside Homebrew installs
+const defaultName = "configlock"
+
 // Service represents the configlock service
 type Service struct {
-	svc service.Service
+	svc      controller
+	name     string // service name, launchd label or task name
+	execPath string // executable the service runs
+	homebrew bool
+	task     bool // Windows scheduled task instead of a service
 }
 
 // New creates a new service instance
@@ -33,9 +44,13 @@ func New() (*Service, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get executable path: %w", err)
 	}
+	name := defaultName
+	if optPath, ok := homebrewOptPath(execPath); ok {
+		name, execPath = homebrewName(), optPath
+	}
 
 	svcConfig := &service.Config{
-		Name:        "configlock",
+		Name:        name,
 		DisplayName: "ConfigLock Daemon",
 		Description: "Enforces file locking during lock hours to prevent impulsive config editing",
 		Executable:  execPath,
@@ -48,6 +63,16 @@ func New() (*Service, error) {
 			"RestartSec": "5",
 		},
 	}
+	// Without a config, nothing asks for sudo yet
+	cfg, cfgErr := config.Load()
+	if runtime.GOOS == "linux" {
+		hardened := cfgErr != nil || cfg.Elevation != locker.ElevationSudo
+		svcConfig.Option["SystemdScript"] = systemdUnit(hardened)
+	}
+	if runtime.GOOS == "windows" && cfgErr == nil && cfg.ScheduledTask {
+		task := &scheduledTask{name: name, execPath: execPath, arguments: svcConfig.Arguments}
+		return &Service{svc: task, name: name, execPath: execPath, task: true}, nil
+	}
 
 	prg := &program{}
 	svc, err := service.New(prg, svcConfig)
@@ -55,11 +80,51 @@ func New() (*Service, error) {
 		return nil, fmt.Errorf("failed to create service: %w", err)
 	}
 
-	return &Service{svc: svc}, nil
+	return &Service{svc: svc, name: name, execPath: execPath, homebrew: name != defaultName}, nil
+}
+
+// Homebrew reports whether the service uses the label 'brew services'
+// manages, because configlock was installed with Homebrew
+func (s *Service) Homebrew() bool {
+	return s.homebrew
+}
+
+// homebrewName returns the launchd label or systemd unit name 'brew services'
+// uses for the configlock formula
+func homebrewName() string {
+	if runtime.GOOS == "darwin" {
+		return "homebrew.mxcl.configlock"
+	}
+	return "homebrew.configlock"
+}
+
+// homebrewOptPath returns the version-independent opt/ path of an executable
+// inside a Homebrew Cellar, which keeps working across 'brew upgrade'
+func homebrewOptPath(execPath string) (string, bool) {
+	if realPath, err := filepath.EvalSymlinks(execPath); err == nil {
+		execPath = realPath
+	}
+	// <prefix>/Cellar/configlock/<version>/bin/configlock
+	prefix, rest, found := strings.Cut(execPath, "/Cellar/configlock/")
+	if !found {
+		return "", false
+	}
+	_, bin, found := strings.Cut(rest, "/")
+	if !found {
+		return "", false
+	}
+	return filepath.Join(prefix, "opt", "configlock", bin), true
 }
 
 // Install installs the service
 func (s *Service) Install() error {
+	// A Homebrew install replaces a service set up before under the plain
+	// name, and a scheduled task a Windows service, so two daemons don't
+	// fight over the same paths
+	if s.homebrew || s.task {
+		s.removeLegacy()
+	}
+
 	// Check if already installed
 	status, err := s.svc.Status()
 	if err == nil && status != service.StatusUnknown {
@@ -85,6 +150,19 @@ func (s *Service) Install() error {
 	return nil
 }
 
+// removeLegacy uninstalls a service installed under the default name
+func (s *Service) removeLegacy() {
+	legacy, err := service.New(&program{}, &service.Config{Name: defaultName, Option: service.KeyValue{"UserService": true}})
+	if err != nil {
+		return
+	}
+	if status, err := legacy.Status(); err != nil || status == service.StatusUnknown {
+		return
+	}
+	legacy.Stop()
+	legacy.Uninstall()
+}
+
 // Uninstall uninstalls the service
 func (s *Service) Uninstall() error {
 	// Stop first
@@ -0,0 +1,47 @@
+package service
+
+import "strings"
+
+// systemdUnit returns the unit template used on Linux in place of the
+// kardianos default, which restarts only after two minutes and is wanted by
+// multi-user.target, a target user managers never reach. The daemon reports
+// readiness and pings the watchdog (see sdnotify), so a hung daemon is
+// restarted too.
+//
+// With hardened set, the daemon and everything it runs (lock tools, hooks)
+// can't gain privileges. That breaks sudo, so it is only used when locks are
+// not elevated with sudo. Namespacing directives such as ProtectSystem are
+// left out: in a user unit they imply PrivateUsers, under which root-owned
+// files appear to belong to nobody and the lock tools are refused.
+func systemdUnit(hardened bool) string {
+	var hardening []string
+	if hardened {
+		hardening = []string{
+			"NoNewPrivileges=yes",
+			"RestrictSUIDSGID=yes",
+			"LockPersonality=yes",
+			"MemoryDenyWriteExecute=yes",
+			"RestrictRealtime=yes",
+			"SystemCallArchitectures=native",
+		}
+	}
+
+	return `[Unit]
+Description={{.Description}}
+ConditionFileIsExecutable={{.Path|cmdEscape}}
+StartLimitIntervalSec=60
+StartLimitBurst=10
+
+[Service]
+Type=notify
+NotifyAccess=main
+ExecStart={{.Path|cmdEscape}}{{range .Arguments}} {{.|cmd}}{{end}}
+ExecReload=/bin/kill -HUP $MAINPID
+Restart=always
+RestartSec=5
+WatchdogSec=2min
+` + strings.Join(append(hardening, ""), "\n") + `
+[Install]
+WantedBy=default.target
+`
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/kardianos/service"
+)
+
+// controller is the part of service.Service that Service uses, so a
+// scheduled task can stand in for the service manager
+type controller interface {
+	Install() error
+	Uninstall() error
+	Start() error
+	Stop() error
+	Restart() error
+	Status() (service.Status, error)
+}
+
+// scheduledTask runs the daemon from a Windows scheduled task started at
+// logon. A Windows service runs as LocalSystem, outside the user's session,
+// while the task runs with the user's own token.
+type scheduledTask struct {
+	name      string
+	execPath  string
+	arguments []string
+}
+
+// taskXML returns the task definition. schtasks can't set the options that
+// matter for a daemon on the command line: without them the task is killed
+// after 72 hours and not restarted when it exits.
+func (t *scheduledTask) taskXML() (string, error) {
+	current, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current user: %w", err)
+	}
+	escape := func(s string) string {
+		var b strings.Builder
+		xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+
+	return `<?xml version="1.0" encoding="UTF-16"?>
+<Task version="1.2" xmlns="http://schemas.microsoft.com/windows/2004/02/mit/task">
+  <RegistrationInfo>
+    <Description>Enforces file locking during lock hours to prevent impulsive config editing</Description>
+  </RegistrationInfo>
+  <Triggers>
+    <LogonTrigger>
+      <Enabled>true</Enabled>
+      <UserId>` + escape(current.Username) + `</UserId>
+    </LogonTrigger>
+  </Triggers>
+  <Principals>
+    <Principal id="Author">
+      <UserId>` + escape(current.Username) + `</UserId>
+      <LogonType>InteractiveToken</LogonType>
+      <RunLevel>LeastPrivilege</RunLevel>
+    </Principal>
+  </Principals>
+  <Settings>
+    <MultipleInstancesPolicy>IgnoreNew</MultipleInstancesPolicy>
+    <DisallowStartIfOnBatteries>false</DisallowStartIfOnBatteries>
+    <StopIfGoingOnBatteries>false</StopIfGoingOnBatteries>
+    <ExecutionTimeLimit>PT0S</ExecutionTimeLimit>
+    <RestartOnFailure>
+      <Interval>PT1M</Interval>
+      <Count>999</Count>
+    </RestartOnFailure>
+    <Hidden>true</Hidden>
+  </Settings>
+  <Actions Context="Author">
+    <Exec>
+      <Command>` + escape(t.execPath) + `</Command>
+      <Arguments>` + escape(strings.Join(t.arguments, " ")) + `</Arguments>
+    </Exec>
+  </Actions>
+</Task>
+`, nil
+}
+
+// schtasks runs schtasks.exe with args
+func schtasks(args ...string) error {
+	output, err := exec.Command("schtasks", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("schtasks %s failed: %v, output: %s", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Install registers the task. schtasks reads task definitions as UTF-16.
+func (t *scheduledTask) Install() error {
+	definition, err := t.taskXML()
+	if err != nil {
+		return err
+	}
+	encoded := utf16.Encode([]rune("\ufeff" + definition))
+	data := make([]byte, 0, 2*len(encoded))
+	for _, unit := range encoded {
+		data = append(data, byte(unit), byte(unit>>8))
+	}
+
+	file := filepath.Join(os.TempDir(), t.name+"-task.xml")
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write task definition: %w", err)
+	}
+	defer os.Remove(file)
+	return schtasks("/Create", "/TN", t.name, "/XML", file, "/F")
+}
+
+// Uninstall deletes the task
+func (t *scheduledTask) Uninstall() error {
+	return schtasks("/Delete", "/TN", t.name, "/F")
+}
+
+// Start runs the task now rather than at the next logon
+func (t *scheduledTask) Start() error {
+	return schtasks("/Run", "/TN", t.name)
+}
+
+// Stop ends the running task
+func (t *scheduledTask) Stop() error {
+	return schtasks("/End", "/TN", t.name)
+}
+
+// Restart ends the task, if running, and runs it again
+func (t *scheduledTask) Restart() error {
+	t.Stop()
+	return t.Start()
+}
+
+// Status returns whether the task is running. The state is read through
+// PowerShell, since schtasks /Query prints it in the system language.
+func (t *scheduledTask) Status() (service.Status, error) {
+	output, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command",
+		"(Get-ScheduledTask -TaskName '"+t.name+"' -ErrorAction Stop).State").Output()
+	if err != nil {
+		return service.StatusUnknown, service.ErrNotInstalled
+	}
+	if strings.TrimSpace(string(output)) == "Running" {
+		return service.StatusRunning, nil
+	}
+	return service.StatusStopped, nil
+}
@@ -0,0 +1,468 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+	"github.com/baggiiiie/configlock/internal/fileutil"
+	"github.com/baggiiiie/configlock/internal/locker"
+	"github.com/baggiiiie/configlock/internal/pathutil"
+)
+
+// Snapshot kinds
+const (
+	KindBtrfs = "btrfs"
+	KindZFS   = "zfs"
+	KindAPFS  = "apfs"
+	KindCopy  = "copy" // plain file copies in the backup store, works everywhere
+)
+
+// apfsDataVolume is the macOS volume holding user data behind firmlinks like /Users
+const apfsDataVolume = "/System/Volumes/Data"
+
+// Record describes a snapshot that covers a locked path
+type Record struct {
+	ID       string    `json:"id"`
+	Kind     string    `json:"kind"`
+	Path     string    `json:"path"`     // path the snapshot was taken for
+	Root     string    `json:"root"`     // root of the snapshotted subvolume/dataset/volume
+	Location string    `json:"location"` // snapshot directory, or APFS snapshot name
+	Reason   string    `json:"reason"`
+	Created  time.Time `json:"created"`
+}
+
+// indexPath returns the path to the snapshot index file
+func indexPath() string {
+	return filepath.Join(config.GetDataDir(), "snapshots.json")
+}
+
+// List returns all recorded snapshots, oldest first
+func List() ([]Record, error) {
+	data, err := os.ReadFile(indexPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshot index: %w", err)
+	}
+
+	var records []Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot index: %w", err)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Created.Before(records[j].Created)
+	})
+	return records, nil
+}
+
+// saveIndex writes the snapshot index
+func saveIndex(records []Record) error {
+	if err := os.MkdirAll(config.GetDataDir(), 0o755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot index: %w", err)
+	}
+	return os.WriteFile(indexPath(), data, 0o600)
+}
+
+// Take creates a filesystem snapshot covering path and records it in the index
+func Take(path, reason string) (*Record, error) {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		realPath = path
+	}
+
+	records, err := List()
+	if err != nil {
+		return nil, err
+	}
+	id := uniqueID(records, time.Now().Format("20060102-150405"))
+	fsType, _ := locker.DetectStrategy(realPath)
+
+	var record *Record
+	switch fsType {
+	case KindBtrfs:
+		record, err = takeBtrfs(realPath, id)
+	case KindZFS:
+		record, err = takeZFS(realPath, id)
+	case KindAPFS:
+		record, err = takeAPFS()
+	default:
+		return nil, fmt.Errorf("filesystem %s does not support snapshots", fsType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	record.ID = id
+	record.Path = realPath
+	record.Reason = reason
+	record.Created = time.Now()
+
+	if err := saveIndex(append(records, *record)); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// uniqueID appends a counter to base if a recorded snapshot already uses it
+func uniqueID(records []Record, base string) string {
+	id := base
+	for n := 2; ; n++ {
+		taken := false
+		for _, r := range records {
+			if r.ID == id {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			return id
+		}
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+// takeBtrfs creates a read-only snapshot of the subvolume containing path
+func takeBtrfs(path, id string) (*Record, error) {
+	root, err := btrfsSubvolumeRoot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotDir := filepath.Join(root, ".configlock-snapshots")
+	if err := os.MkdirAll(snapshotDir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	location := filepath.Join(snapshotDir, id)
+	if output, err := exec.Command("btrfs", "subvolume", "snapshot", "-r", root, location).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("btrfs snapshot failed: %v, output: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return &Record{Kind: KindBtrfs, Root: root, Location: location}, nil
+}
+
+// btrfsSubvolumeRoot walks up from path to the root of its btrfs subvolume
+func btrfsSubvolumeRoot(path string) (string, error) {
+	dir := path
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+
+	for {
+		if exec.Command("btrfs", "subvolume", "show", dir).Run() == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no btrfs subvolume found for %s", path)
+		}
+		dir = parent
+	}
+}
+
+// takeZFS snapshots the dataset containing path
+func takeZFS(path, id string) (*Record, error) {
+	output, err := exec.Command("zfs", "list", "-H", "-o", "name,mountpoint", path).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find zfs dataset: %w", err)
+	}
+
+	fields := strings.Split(strings.TrimSpace(string(output)), "\t")
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("unexpected zfs list output: %s", output)
+	}
+	dataset, mountpoint := fields[0], fields[1]
+
+	name := "configlock-" + id
+	if output, err := exec.Command("zfs", "snapshot", dataset+"@"+name).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("zfs snapshot failed: %v, output: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return &Record{
+		Kind:     KindZFS,
+		Root:     mountpoint,
+		Location: filepath.Join(mountpoint, ".zfs", "snapshot", name),
+	}, nil
+}
+
+// takeAPFS creates a Time Machine local snapshot of the APFS volumes
+func takeAPFS() (*Record, error) {
+	output, err := exec.Command("tmutil", "localsnapshot").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("tmutil localsnapshot failed: %v, output: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	// Output ends with "Created local snapshot with date: 2025-01-02-150405"
+	outputStr := strings.TrimSpace(string(output))
+	idx := strings.LastIndex(outputStr, ": ")
+	if idx == -1 {
+		return nil, fmt.Errorf("unexpected tmutil output: %s", outputStr)
+	}
+	date := outputStr[idx+2:]
+
+	return &Record{
+		Kind:     KindAPFS,
+		Root:     "/",
+		Location: "com.apple.TimeMachine." + date + ".local",
+	}, nil
+}
+
+// backupStoreDir returns the directory holding copy snapshots
+func backupStoreDir() string {
+	return filepath.Join(config.GetDataDir(), "backups")
+}
+
+// Capture copies each path into the backup store under a single snapshot ID.
+// Unlike Take, this works on every filesystem and platform.
+func Capture(paths []string, reason string) ([]Record, error) {
+	records, err := List()
+	if err != nil {
+		return nil, err
+	}
+
+	id := uniqueID(records, time.Now().Format("20060102-150405"))
+	location := filepath.Join(backupStoreDir(), id)
+	created := time.Now()
+
+	var captured []Record
+	for _, path := range paths {
+		realPath, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		if err := copyIntoStore(realPath, location); err != nil {
+			os.RemoveAll(location)
+			return nil, fmt.Errorf("failed to copy %s: %w", realPath, err)
+		}
+		captured = append(captured, Record{
+			ID:       id,
+			Kind:     KindCopy,
+			Path:     realPath,
+			Root:     "/",
+			Location: location,
+			Reason:   reason,
+			Created:  created,
+		})
+	}
+
+	if err := saveIndex(append(records, captured...)); err != nil {
+		return nil, err
+	}
+	return captured, nil
+}
+
+// copyIntoStore copies the managed files of path to the same absolute path below location
+func copyIntoStore(path, location string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	copyFile := func(file string) error {
+		return fileutil.CopyFile(file, filepath.Join(location, file))
+	}
+	if !info.IsDir() {
+		return copyFile(path)
+	}
+	_, err = fileutil.WalkFiles(context.Background(), path, fileutil.CollectOptions{}, copyFile)
+	return err
+}
+
+// covers reports whether a snapshot of snapshotPath contains path
+func covers(snapshotPath, path string) bool {
+	return pathutil.Within(path, snapshotPath)
+}
+
+// Find returns the newest snapshot covering path. If id is set, only that
+// snapshot is considered; if path is empty, any path in the snapshot matches.
+func Find(path, id string) (*Record, error) {
+	records, err := List()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(records) - 1; i >= 0; i-- {
+		r := records[i]
+		if id != "" && r.ID != id {
+			continue
+		}
+		if path == "" || covers(r.Path, path) {
+			return &r, nil
+		}
+	}
+
+	if id != "" {
+		return nil, fmt.Errorf("snapshot %s does not cover %s", id, path)
+	}
+	return nil, fmt.Errorf("no snapshot covers %s", path)
+}
+
+// Get returns every record belonging to the snapshot with the given ID
+func Get(id string) ([]Record, error) {
+	records, err := List()
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []Record
+	for _, r := range records {
+		if r.ID == id {
+			matched = append(matched, r)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("snapshot not found: %s", id)
+	}
+	return matched, nil
+}
+
+// Prune deletes all but the newest keep snapshots and returns the removed IDs
+func Prune(keep int) ([]string, error) {
+	records, err := List()
+	if err != nil {
+		return nil, err
+	}
+
+	// Records are oldest first; collect distinct IDs newest first
+	var ids []string
+	for i := len(records) - 1; i >= 0; i-- {
+		if !slices.Contains(ids, records[i].ID) {
+			ids = append(ids, records[i].ID)
+		}
+	}
+	if len(ids) <= keep {
+		return nil, nil
+	}
+	removeIDs := ids[keep:]
+
+	var kept []Record
+	var lastErr error
+	deleted := make(map[string]bool)
+	for _, r := range records {
+		if !slices.Contains(removeIDs, r.ID) {
+			kept = append(kept, r)
+			continue
+		}
+		// Records of one copy snapshot share a location, delete it once
+		if deleted[r.Location] {
+			continue
+		}
+		if err := deleteSnapshot(r); err != nil {
+			lastErr = fmt.Errorf("failed to delete snapshot %s: %w", r.ID, err)
+			kept = append(kept, r)
+			continue
+		}
+		deleted[r.Location] = true
+	}
+
+	if err := saveIndex(kept); err != nil {
+		return nil, err
+	}
+	return removeIDs, lastErr
+}
+
+// deleteSnapshot removes the storage behind a snapshot record
+func deleteSnapshot(r Record) error {
+	var cmd *exec.Cmd
+	switch r.Kind {
+	case KindCopy:
+		return os.RemoveAll(r.Location)
+	case KindBtrfs:
+		cmd = exec.Command("btrfs", "subvolume", "delete", r.Location)
+	case KindZFS:
+		output, err := exec.Command("zfs", "list", "-H", "-o", "name", r.Root).Output()
+		if err != nil {
+			return fmt.Errorf("failed to find zfs dataset: %w", err)
+		}
+		cmd = exec.Command("zfs", "destroy", strings.TrimSpace(string(output))+"@"+filepath.Base(r.Location))
+	case KindAPFS:
+		date := strings.TrimSuffix(strings.TrimPrefix(r.Location, "com.apple.TimeMachine."), ".local")
+		cmd = exec.Command("tmutil", "deletelocalsnapshots", date)
+	default:
+		return fmt.Errorf("unknown snapshot kind: %s", r.Kind)
+	}
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v, output: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Restore copies the snapshotted content of target (r.Path or a path inside it)
+// back into place. The caller is responsible for unlocking target first.
+func Restore(r *Record, target string) error {
+	if err := CheckLocation(r); err != nil {
+		return err
+	}
+	base := r.Location
+	if r.Kind == KindAPFS {
+		mountDir, err := os.MkdirTemp("", "configlock-snapshot-")
+		if err != nil {
+			return fmt.Errorf("failed to create mount point: %w", err)
+		}
+		defer os.Remove(mountDir)
+
+		if output, err := exec.Command("mount_apfs", "-o", "nobrowse,rdonly", "-s", r.Location, apfsDataVolume, mountDir).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to mount snapshot: %v, output: %s", err, strings.TrimSpace(string(output)))
+		}
+		defer exec.Command("umount", mountDir).Run()
+		base = mountDir
+	}
+
+	rel, err := filepath.Rel(r.Root, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s is not inside snapshot %s", target, r.ID)
+	}
+
+	source := filepath.Join(base, rel)
+	if _, err := os.Stat(source); err != nil {
+		return fmt.Errorf("%s does not exist in snapshot %s", target, r.ID)
+	}
+
+	return fileutil.CopyTree(source, target)
+}
+
+// apfsSnapshotName matches the Time Machine local snapshots takeAPFS records
+var apfsSnapshotName = regexp.MustCompile(`^com\.apple\.TimeMachine\.[0-9-]+\.local$`)
+
+// CheckLocation returns an error unless r points where a snapshot of its
+// kind is created: the index is writable by the user, and a record pointing
+// anywhere else would restore arbitrary content
+func CheckLocation(r *Record) error {
+	var want string
+	switch r.Kind {
+	case KindCopy:
+		want = filepath.Join(backupStoreDir(), r.ID)
+	case KindBtrfs:
+		want = filepath.Join(r.Root, ".configlock-snapshots", r.ID)
+	case KindZFS:
+		want = filepath.Join(r.Root, ".zfs", "snapshot", "configlock-"+r.ID)
+	case KindAPFS:
+		if r.Root == "/" && apfsSnapshotName.MatchString(r.Location) {
+			return nil
+		}
+		return fmt.Errorf("snapshot %s does not name a Time Machine local snapshot", r.ID)
+	default:
+		return fmt.Errorf("snapshot %s has unknown kind %q", r.ID, r.Kind)
+	}
+	if filepath.Base(r.ID) != r.ID || filepath.Clean(r.Location) != want {
+		return fmt.Errorf("snapshot %s is stored at %s, outside the snapshot store", r.ID, r.Location)
+	}
+	return nil
+}
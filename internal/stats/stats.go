@@ -0,0 +1,94 @@
+package stats
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/config"
+)
+
+// EventChallenge is recorded by the CLI for every typing challenge or
+// passphrase check, and EventLockHoursChange for every change made with
+// 'configlock edit time'; the other events are the daemon's lifecycle events
+const (
+	EventChallenge       = "challenge"
+	EventLockHoursChange = "lock_hours_change"
+)
+
+// Event is a single recorded event
+type Event struct {
+	Name string    `json:"event"`          // activate, deactivate, violation, temp_unlock, challenge, lock_hours_change
+	Path string    `json:"path,omitempty"` // affected path, empty for activate and deactivate
+	Time time.Time `json:"time"`
+
+	// Temp unlocks: the reason given; lock hours changes: old -> new settings
+	Reason string `json:"reason,omitempty"`
+
+	// Challenge attempts only
+	Command string `json:"command,omitempty"` // e.g. "configlock temp-unlock"
+	Kind    string `json:"kind,omitempty"`    // typing or passphrase
+	Outcome string `json:"outcome,omitempty"` // passed, failed or aborted
+	Retries int    `json:"retries,omitempty"`
+	Seconds int    `json:"seconds,omitempty"`
+}
+
+// GetStatsPath returns the file events are appended to
+func GetStatsPath() string {
+	return filepath.Join(config.GetDataDir(), "stats.jsonl")
+}
+
+// Record appends an event to the stats store
+func Record(e Event) error {
+	path := GetStatsPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open stats store: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	return nil
+}
+
+// Load returns the recorded events in [from, to), oldest first. Lines that
+// cannot be parsed, e.g. from a write cut short by a crash, are skipped.
+func Load(from, to time.Time) ([]Event, error) {
+	f, err := os.Open(GetStatsPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open stats store: %w", err)
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if !e.Time.Before(from) && e.Time.Before(to) {
+			events = append(events, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stats store: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,52 @@
+package terminal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Run opens a terminal window running the configlock CLI with args, so
+// challenge-gated commands started from the tray or a notification get an
+// interactive prompt
+func Run(args ...string) error {
+	execPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	quoted := []string{shellQuote(execPath)}
+	for _, arg := range args {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	// Keep the window open so the output can be read
+	command := strings.Join(quoted, " ") + "; echo; read -r -p 'Press Enter to close' _"
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf(`tell application "Terminal" to do script %q`, command)
+		cmd = exec.Command("osascript", "-e", script, "-e", `tell application "Terminal" to activate`)
+	case "windows":
+		cmd = exec.Command("cmd", append([]string{"/C", "start", "configlock", "cmd", "/K", execPath}, args...)...)
+	default:
+		terminal := os.Getenv("TERMINAL")
+		if terminal == "" {
+			terminal = "x-terminal-emulator"
+		}
+		cmd = exec.Command(terminal, "-e", "sh", "-c", command)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open terminal: %w", err)
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,69 @@
+package tray
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"runtime"
+)
+
+// iconSize is the edge length of the generated tray icons in pixels
+const iconSize = 22
+
+// padlockIcon draws a padlock in the given color, with the shackle open when
+// unlocked, and encodes it in the format the platform's tray expects
+func padlockIcon(c color.Color, locked bool) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, iconSize, iconSize))
+
+	// Body
+	for y := 10; y < 20; y++ {
+		for x := 4; x < 18; x++ {
+			img.Set(x, y, c)
+		}
+	}
+
+	// Shackle: two legs joined by a top bar; when open, the right leg stops
+	// short of the body
+	rightBottom := 10
+	if !locked {
+		rightBottom = 6
+	}
+	for x := 6; x < 16; x++ {
+		img.Set(x, 2, c)
+		img.Set(x, 3, c)
+	}
+	for y := 2; y < 10; y++ {
+		img.Set(6, y, c)
+		img.Set(7, y, c)
+	}
+	for y := 2; y < rightBottom; y++ {
+		img.Set(14, y, c)
+		img.Set(15, y, c)
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	if runtime.GOOS == "windows" {
+		return wrapICO(buf.Bytes())
+	}
+	return buf.Bytes()
+}
+
+// wrapICO wraps PNG data in a single-image ICO container, which Windows
+// accepts for tray icons
+func wrapICO(pngData []byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []uint16{0, 1, 1}) // reserved, type icon, one image
+	buf.Write([]byte{iconSize, iconSize, 0, 0})                // width, height, palette, reserved
+	binary.Write(&buf, binary.LittleEndian, []uint16{1, 32})   // color planes, bits per pixel
+	binary.Write(&buf, binary.LittleEndian, []uint32{uint32(len(pngData)), 22})
+	buf.Write(pngData)
+	return buf.Bytes()
+}
+
+var (
+	lockedIcon   = padlockIcon(color.RGBA{R: 0xd9, G: 0x48, B: 0x3b, A: 0xff}, true)
+	unlockedIcon = padlockIcon(color.RGBA{R: 0x3c, G: 0xa5, B: 0x5c, A: 0xff}, false)
+)
@@ -0,0 +1,149 @@
+//go:build !darwin || cgo
+
+package tray
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"fyne.io/systray"
+	"github.com/baggiiiie/configlock/internal/ipc"
+	"github.com/baggiiiie/configlock/internal/terminal"
+)
+
+// pollInterval is how often the tray asks the daemon for its status
+const pollInterval = 10 * time.Second
+
+// Run shows the tray icon and menu until the user quits
+func Run() error {
+	systray.Run(onReady, func() {})
+	return nil
+}
+
+// onReady builds the menu and keeps it in sync with the daemon
+func onReady() {
+	systray.SetTooltip("ConfigLock")
+
+	stateItem := systray.AddMenuItem("Checking daemon...", "")
+	stateItem.Disable()
+	nextItem := systray.AddMenuItem("", "")
+	nextItem.Disable()
+	nextItem.Hide()
+	systray.AddSeparator()
+	statusItem := systray.AddMenuItem("Show status", "Run 'configlock status' in a terminal")
+	unlockMenu := systray.AddMenuItem("Temporarily unlock", "Run 'configlock temp-unlock' in a terminal")
+	systray.AddSeparator()
+	quitItem := systray.AddMenuItem("Quit", "Close the tray (the daemon keeps running)")
+
+	unlockItems := &pathItems{parent: unlockMenu}
+
+	update := func() {
+		var status ipc.Status
+		if err := ipc.Query(ipc.CommandStatus, &status); err != nil {
+			systray.SetIcon(unlockedIcon)
+			systray.SetTitle("")
+			stateItem.SetTitle("Daemon not running")
+			nextItem.Hide()
+			unlockMenu.Disable()
+			return
+		}
+
+		if status.Active {
+			systray.SetIcon(lockedIcon)
+			systray.SetTitle("🔒")
+			stateItem.SetTitle(fmt.Sprintf("Locked (%d path(s), %d temporarily unlocked)", len(status.LockedPaths), len(status.TempExcludes)))
+		} else {
+			systray.SetIcon(unlockedIcon)
+			systray.SetTitle("")
+			stateItem.SetTitle("Unlocked (outside lock hours)")
+		}
+
+		if status.NextTransition.IsZero() {
+			nextItem.Hide()
+		} else {
+			verb := "Locks"
+			if status.Active {
+				verb = "Unlocks"
+			}
+			nextItem.SetTitle(fmt.Sprintf("%s in %s", verb, formatUntil(status.NextTransition)))
+			nextItem.Show()
+		}
+
+		if status.Active {
+			unlockMenu.Enable()
+		} else {
+			unlockMenu.Disable()
+		}
+		unlockItems.set(status.LockedPaths)
+	}
+
+	update()
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				update()
+			case <-statusItem.ClickedCh:
+				terminal.Run("status")
+			case <-quitItem.ClickedCh:
+				systray.Quit()
+				return
+			}
+		}
+	}()
+}
+
+// pathItems keeps one submenu item per locked path
+type pathItems struct {
+	parent *systray.MenuItem
+	paths  []string
+	items  []*systray.MenuItem
+	done   chan struct{}
+}
+
+// set replaces the submenu items if the list of paths changed
+func (p *pathItems) set(paths []string) {
+	if slices.Equal(paths, p.paths) {
+		return
+	}
+	if p.done != nil {
+		close(p.done)
+	}
+	for _, item := range p.items {
+		item.Remove()
+	}
+
+	p.paths = slices.Clone(paths)
+	p.items = nil
+	p.done = make(chan struct{})
+	for _, path := range paths {
+		item := p.parent.AddSubMenuItem(filepath.Base(path), path)
+		p.items = append(p.items, item)
+		go func(done chan struct{}) {
+			for {
+				select {
+				case <-item.ClickedCh:
+					terminal.Run("temp-unlock", path)
+				case <-done:
+					return
+				}
+			}
+		}(p.done)
+	}
+}
+
+// formatUntil formats the time left until t, e.g. "2h 15m"
+func formatUntil(t time.Time) string {
+	d := time.Until(t).Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	if d < 24*time.Hour {
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dd %dh", int(d.Hours())/24, int(d.Hours())%24)
+}
@@ -0,0 +1,10 @@
+//go:build darwin && !cgo
+
+package tray
+
+import "fmt"
+
+// Run reports that the menubar needs cgo on macOS, which this build lacks
+func Run() error {
+	return fmt.Errorf("the menubar is not available in this build (built without cgo); build configlock with CGO_ENABLED=1 to use it")
+}
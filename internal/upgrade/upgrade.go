@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -15,9 +16,19 @@ import (
 const (
 	githubReleasesURL = "https://api.github.com/repos/baggiiiie/configlock/releases/latest"
 	checkInterval     = 24 * time.Hour // Check once per day
-	requestTimeout    = 500 * time.Millisecond
+	// Long enough for a proxy round trip; a failed check is not retried
+	// until the next interval, so this delay is paid at most once a day
+	requestTimeout = 3 * time.Second
 )
 
+// httpClient honors HTTPS_PROXY, HTTP_PROXY and NO_PROXY
+var httpClient = &http.Client{
+	Transport: &http.Transport{Proxy: http.ProxyFromEnvironment},
+}
+
+// Instructions tells how to upgrade
+const Instructions = "Run 'brew upgrade configlock' or visit https://github.com/baggiiiie/configlock/releases"
+
 // ANSI color codes for muted output
 const (
 	colorDim   = "\033[2m"
@@ -30,56 +41,114 @@ type githubRelease struct {
 	HTMLURL string `json:"html_url"`
 }
 
+// cache holds the result of the last upgrade check
+type cache struct {
+	LastCheck     string `json:"last_check"`     // ISO8601 timestamp
+	LatestVersion string `json:"latest_version"` // cached latest version
+
+	// Release last announced with a desktop notification
+	NotifiedVersion string `json:"notified_version,omitempty"`
+}
+
+// cachePath returns the path to the upgrade check cache file
+func cachePath() string {
+	return filepath.Join(config.GetCacheDir(), "upgrade.json")
+}
+
+// loadCache reads the upgrade check cache; a missing or corrupt cache is empty
+func loadCache() cache {
+	var c cache
+	if data, err := os.ReadFile(cachePath()); err == nil {
+		_ = json.Unmarshal(data, &c)
+	}
+	return c
+}
+
+// saveCache writes the upgrade check cache
+func saveCache(c cache) error {
+	if err := os.MkdirAll(config.GetCacheDir(), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(cachePath(), data, 0o600)
+}
+
 // CheckForUpgrade checks if a newer version is available on GitHub
 // It caches results and only checks once per day
 // Never returns errors - silently fails if offline or on any error
 func CheckForUpgrade(currentVersion string) {
+	if latestVersion, ok := Available(currentVersion); ok {
+		printUpgradeMessage(latestVersion, currentVersion)
+	}
+}
+
+// Available returns the latest release if it is newer than currentVersion.
+// It caches results and only checks once per day, and reports no upgrade if
+// offline or on any error.
+func Available(currentVersion string) (string, bool) {
 	// Skip if version is unknown
 	if currentVersion == "" || currentVersion == "unknown" {
-		return
+		return "", false
 	}
 
-	// Try to load config for caching
+	// Load config for the releases endpoint
 	cfg, err := config.Load()
 	if err != nil {
 		// No config file, skip upgrade check
-		return
+		return "", false
 	}
 
 	// Check if we should skip based on cache
-	if !shouldCheck(cfg) {
+	cached := loadCache()
+	if !shouldCheck(cached) {
 		// Use cached result if available
-		if cfg.UpgradeLatestVersion != "" && isNewerVersion(cfg.UpgradeLatestVersion, currentVersion) {
-			printUpgradeMessage(cfg.UpgradeLatestVersion, currentVersion)
-		}
-		return
+		return cached.LatestVersion, isNewerVersion(cached.LatestVersion, currentVersion)
 	}
 
-	// Fetch latest version from GitHub
-	latestVersion, err := fetchLatestVersion()
+	// Fetch latest version from GitHub (or the configured mirror)
+	latestVersion, err := fetchLatestVersion(releasesURL(cfg))
 	if err != nil {
-		// Network error or timeout - skip silently
-		return
+		// Network error or timeout - skip silently, but don't retry until
+		// the next interval so offline commands aren't slowed down
+		cached.LastCheck = time.Now().Format(time.RFC3339)
+		_ = saveCache(cached) // Ignore save errors
+		return "", false
 	}
 
 	// Update cache
-	cfg.UpgradeLastCheck = time.Now().Format(time.RFC3339)
-	cfg.UpgradeLatestVersion = latestVersion
-	_ = cfg.Save() // Ignore save errors
+	cached.LastCheck = time.Now().Format(time.RFC3339)
+	cached.LatestVersion = latestVersion
+	_ = saveCache(cached) // Ignore save errors
 
-	// Check if upgrade is available
-	if isNewerVersion(latestVersion, currentVersion) {
-		printUpgradeMessage(latestVersion, currentVersion)
+	return latestVersion, isNewerVersion(latestVersion, currentVersion)
+}
+
+// NotifyOnce announces the latest release through notify if it is newer
+// than currentVersion and wasn't announced before, so each release is
+// announced at most once. notify reports whether it showed the announcement.
+func NotifyOnce(currentVersion string, notify func(latestVersion string) bool) {
+	latestVersion, ok := Available(currentVersion)
+	if !ok || loadCache().NotifiedVersion == latestVersion {
+		return
+	}
+	if !notify(latestVersion) {
+		return
 	}
+	cached := loadCache()
+	cached.NotifiedVersion = latestVersion
+	_ = saveCache(cached) // Ignore save errors
 }
 
 // shouldCheck returns true if enough time has passed since the last check
-func shouldCheck(cfg *config.Config) bool {
-	if cfg.UpgradeLastCheck == "" {
+func shouldCheck(c cache) bool {
+	if c.LastCheck == "" {
 		return true
 	}
 
-	lastCheck, err := time.Parse(time.RFC3339, cfg.UpgradeLastCheck)
+	lastCheck, err := time.Parse(time.RFC3339, c.LastCheck)
 	if err != nil {
 		return true
 	}
@@ -87,12 +156,22 @@ func shouldCheck(cfg *config.Config) bool {
 	return time.Since(lastCheck) >= checkInterval
 }
 
-// fetchLatestVersion fetches the latest release version from GitHub
-func fetchLatestVersion() (string, error) {
+// releasesURL returns the releases API endpoint, which can be overridden for
+// GitHub Enterprise or an internal mirror
+func releasesURL(cfg *config.Config) string {
+	if cfg.UpgradeReleasesURL != "" {
+		return cfg.UpgradeReleasesURL
+	}
+	return githubReleasesURL
+}
+
+// fetchLatestVersion fetches the latest release version from a GitHub
+// compatible "latest release" endpoint
+func fetchLatestVersion(url string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, githubReleasesURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return "", err
 	}
@@ -100,7 +179,7 @@ func fetchLatestVersion() (string, error) {
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
 	req.Header.Set("User-Agent", "configlock-upgrade-check")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -170,6 +249,5 @@ func normalizeVersion(v string) string {
 func printUpgradeMessage(latestVersion, currentVersion string) {
 	fmt.Fprintf(os.Stderr, "\n%sA new version of configlock is available: %s (current: %s)%s\n",
 		colorDim, latestVersion, currentVersion, colorReset)
-	fmt.Fprintf(os.Stderr, "%sRun 'brew upgrade configlock' or visit https://github.com/baggiiiie/configlock/releases%s\n",
-		colorDim, colorReset)
+	fmt.Fprintf(os.Stderr, "%s%s%s\n", colorDim, Instructions, colorReset)
 }
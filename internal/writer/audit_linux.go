@@ -0,0 +1,80 @@
+package writer
+
+import (
+	"bufio"
+	"bytes"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/baggiiiie/configlock/internal/locker"
+)
+
+// auditWindow is how far back an audit record may be and still count as the
+// change just seen; older records belong to earlier changes
+const auditWindow = 5 * time.Second
+
+// auditWriter returns the process of the latest audit record for a change
+// to path, from ausearch. It needs a watch rule covering path, such as
+// 'auditctl -w /home/me/.zshrc -p wa', and permission to read the audit log.
+func auditWriter(path string) (Process, bool) {
+	// A copy of ausearch in a user-writable PATH entry could name any writer
+	ausearch, err := locker.ToolPath("ausearch")
+	if err != nil {
+		return Process{}, false
+	}
+	// ausearch exits 1 when nothing matches
+	output, err := exec.Command(ausearch, "-f", path, "-ts", "recent", "-m", "SYSCALL").Output()
+	if err != nil {
+		return Process{}, false
+	}
+
+	var latest Process
+	var found bool
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "type=SYSCALL ") {
+			continue
+		}
+		at, ok := auditTime(line)
+		if !ok || time.Since(at) > auditWindow {
+			continue
+		}
+		p := Process{Name: auditField(line, "comm"), Exe: auditField(line, "exe")}
+		if p.Name != "" {
+			latest, found = p, true
+		}
+	}
+	return latest, found
+}
+
+// auditTime returns the time of a raw audit record, msg=audit(1760620201.123:456)
+func auditTime(line string) (time.Time, bool) {
+	_, rest, ok := strings.Cut(line, "msg=audit(")
+	if !ok {
+		return time.Time{}, false
+	}
+	stamp, _, ok := strings.Cut(rest, ":")
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := strconv.ParseFloat(stamp, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(int64(seconds * 1000)), true
+}
+
+// auditField returns a quoted field of a raw audit record. Values the
+// kernel hex-encodes (those with spaces or other special characters) are
+// returned empty.
+func auditField(line, name string) string {
+	_, rest, ok := strings.Cut(line, " "+name+"=\"")
+	if !ok {
+		return ""
+	}
+	value, _, _ := strings.Cut(rest, "\"")
+	return value
+}
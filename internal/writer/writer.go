@@ -0,0 +1,50 @@
+// Package writer finds which process changed a locked file, so changes made
+// by trusted tools such as 'chezmoi apply' aren't treated as violations
+package writer
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Process is a process that changed a file
+type Process struct {
+	Name string // command name, as in ps
+	Exe  string // executable path, when known
+}
+
+// commLen is the length Linux truncates command names to
+const commLen = 15
+
+// matches reports whether a process is the trusted tool name: an entry
+// containing a slash matches the executable path, others the command name
+func matches(p Process, name string) bool {
+	if strings.Contains(name, "/") {
+		return p.Exe == name || p.Name == name
+	}
+	comm := filepath.Base(p.Name)
+	if comm == name || filepath.Base(p.Exe) == name {
+		return true
+	}
+	return len(comm) == commLen && strings.HasPrefix(name, comm)
+}
+
+// Trusted returns the trusted process among names that changed path, as
+// named by the audit log (auditd on Linux, when readable and watching path).
+// Without an answer from it no change counts as trusted: a trusted tool
+// merely running says nothing about who made the change.
+func Trusted(path string, names []string) (Process, bool) {
+	if len(names) == 0 {
+		return Process{}, false
+	}
+	p, ok := auditWriter(path)
+	if !ok {
+		return Process{}, false
+	}
+	for _, name := range names {
+		if matches(p, name) {
+			return p, true
+		}
+	}
+	return Process{}, false
+}